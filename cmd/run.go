@@ -7,9 +7,11 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"frappuccino/config"
 	"frappuccino/internal/handler"
+	"frappuccino/internal/models"
 	internal "frappuccino/internal/repo"
 	"frappuccino/internal/service"
 	"frappuccino/internal/slog"
@@ -17,20 +19,57 @@ import (
 	_ "github.com/lib/pq"
 )
 
+// orderEventReplay is how many order events are kept for clients resuming
+// the event stream.
+const orderEventReplay = 1000
+
 var (
-	port  int
-	dbURL string
+	port               int
+	dbURL              string
+	orderTTL           time.Duration
+	webhookMaxAttempts int
+	channelPricing     models.ChannelPricing
+	pickupLead         time.Duration
+	shop               models.ShopInfo
 )
 
 func Run() {
 	config.LoadEnv()
 	flag.IntVar(&port, "port", 8080, "Port number")
 	flag.StringVar(&dbURL, "db", os.Getenv("DATABASE_URL"), "Database connection URL")
+	flag.DurationVar(&orderTTL, "order-ttl", defaultOrderTTL(), "How long an open order may go unchanged before it is canceled (0 keeps it)")
+	flag.IntVar(&webhookMaxAttempts, "webhook-max-attempts", 8, "Attempts at a webhook delivery before it is dead-lettered")
+	flag.Float64Var(&channelPricing.DineInTaxPct, "tax-dine-in", envFloat("TAX_DINE_IN"), "Tax on dine-in orders, in percent")
+	flag.Float64Var(&channelPricing.TakeawayTaxPct, "tax-takeaway", envFloat("TAX_TAKEAWAY"), "Tax on takeaway and delivery orders, in percent")
+	flag.Float64Var(&channelPricing.DeliveryFee, "delivery-fee", envFloat("DELIVERY_FEE"), "Fee added to delivery orders")
+	flag.Float64Var(&channelPricing.FreeDeliveryOver, "free-delivery-over", envFloat("FREE_DELIVERY_OVER"), "Subtotal from which delivery is free (0 always charges the fee)")
+	flag.DurationVar(&pickupLead, "pickup-lead-time", defaultPickupLead(), "How long before its pickup time an order placed ahead reaches the kitchen")
+	flag.StringVar(&shop.Name, "shop-name", envString("SHOP_NAME", "Frappuccino"), "Shop name printed on receipts")
+	flag.StringVar(&shop.Address, "shop-address", os.Getenv("SHOP_ADDRESS"), "Shop address printed on receipts")
+	flag.StringVar(&shop.Phone, "shop-phone", os.Getenv("SHOP_PHONE"), "Shop phone number printed on receipts")
+	flag.StringVar(&shop.TaxID, "shop-tax-id", os.Getenv("SHOP_TAX_ID"), "Tax registration number printed on receipts")
 	flag.Parse()
 
 	if port < 0 || port > 65535 {
 		log.Fatal("Invalid port number")
 	}
+	if orderTTL < 0 {
+		log.Fatal("Invalid order TTL")
+	}
+	if webhookMaxAttempts < 1 {
+		log.Fatal("Invalid number of webhook attempts")
+	}
+	for _, pct := range []float64{channelPricing.DineInTaxPct, channelPricing.TakeawayTaxPct} {
+		if pct < 0 || pct >= 100 {
+			log.Fatal("Invalid tax rate")
+		}
+	}
+	if pickupLead < 0 {
+		log.Fatal("Invalid pickup lead time")
+	}
+	if channelPricing.DeliveryFee < 0 || channelPricing.FreeDeliveryOver < 0 {
+		log.Fatal("Invalid delivery fee")
+	}
 
 	slog.Init()
 
@@ -47,14 +86,87 @@ func Run() {
 	}
 
 	container := internal.New(db)
+	transferService := service.NewTransferService(container)
+
+	if flag.NArg() > 0 {
+		if err := runCommand(ctx, transferService, flag.Args()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	invService := service.NewInventoryService(container)
 	menuService := service.NewMenuService(container)
-	orderService := service.NewOrderService(container)
+	orderEvents := service.NewOrderEventBus(orderEventReplay)
+	kitchenService := service.NewKitchenService(container, orderEvents, pickupLead)
+	orderService := service.NewOrderService(container, orderTTL, kitchenService, orderEvents, channelPricing)
 	statsService := service.NewStatsService(container)
+	stockTakeService := service.NewStockTakeService(container)
+	reportService := service.NewReportService(container)
+	pricingService := service.NewPricingService(container)
+	categoryService := service.NewCategoryService(container)
+	menuVersionService := service.NewMenuVersionService(container)
+	webhookService := service.NewWebhookService(container, webhookMaxAttempts)
+	pickupService := service.NewPickupService(container)
+	receiptService := service.NewReceiptService(container, shop)
+
+	go pricingService.RunScheduler(ctx, time.Minute)
+	go orderService.RunExpiry(ctx, time.Minute)
+	go webhookService.RunDispatcher(ctx, 5*time.Second)
+	go kitchenService.RunReleases(ctx, 30*time.Second)
+	go orderService.RunBatchWorker(ctx)
 
-	h := handler.NewHandler(invService, menuService, orderService, statsService)
+	h := handler.NewHandler(invService, menuService, orderService, statsService, stockTakeService, reportService, pricingService, categoryService, menuVersionService, transferService, kitchenService, orderEvents, webhookService, pickupService, receiptService)
 
 	srv := handler.NewServer(strconv.Itoa(port), h)
 	srv.Start()
 }
+
+// defaultOrderTTL reads ORDER_TTL (e.g. "90m"), falling back to two hours.
+func defaultOrderTTL() time.Duration {
+	v := os.Getenv("ORDER_TTL")
+	if v == "" {
+		return 2 * time.Hour
+	}
+	ttl, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid ORDER_TTL %q: %v", v, err)
+	}
+	return ttl
+}
+
+// defaultPickupLead reads PICKUP_LEAD_TIME (e.g. "10m"), falling back to
+// fifteen minutes.
+func defaultPickupLead() time.Duration {
+	v := os.Getenv("PICKUP_LEAD_TIME")
+	if v == "" {
+		return 15 * time.Minute
+	}
+	lead, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid PICKUP_LEAD_TIME %q: %v", v, err)
+	}
+	return lead
+}
+
+// envFloat reads a number from the environment, 0 when it is not set.
+func envFloat(name string) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", name, v, err)
+	}
+	return f
+}
+
+// envString reads a setting from the environment, falling back to def when it
+// is not set.
+func envString(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+)
+
+const (
+	importUsage = "usage: import [-dry-run] [-format csv|json] inventory|menu FILE"
+	exportUsage = "usage: export [-format csv|json] inventory|menu [FILE]"
+)
+
+// runCommand runs a command given after the flags instead of starting the
+// server:
+//
+//	import [-dry-run] [-format csv|json] inventory|menu FILE
+//	export [-format csv|json] inventory|menu [FILE]
+//
+// The format defaults to the file extension; export writes to stdout when no
+// file is given.
+func runCommand(ctx context.Context, svc service.TransferService, args []string) error {
+	switch args[0] {
+	case "import":
+		return runImport(ctx, svc, args[1:])
+	case "export":
+		return runExport(ctx, svc, args[1:])
+	}
+	return fmt.Errorf("unknown command %q (expected import or export)", args[0])
+}
+
+func runImport(ctx context.Context, svc service.TransferService, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Check the file without writing anything")
+	format := fs.String("format", "", "File format: csv or json")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		return errors.New(importUsage)
+	}
+	what, path := fs.Arg(0), fs.Arg(1)
+
+	var run func(context.Context, string, []byte, bool) (*models.ImportReport, error)
+	switch what {
+	case "inventory":
+		run = svc.ImportInventory
+	case "menu":
+		run = svc.ImportMenu
+	default:
+		return errors.New(importUsage)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	report, err := run(ctx, fileFormat(*format, path), data, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range report.Rows {
+		if row.Error != "" {
+			fmt.Printf("row %d\t%s\terror: %s\n", row.Row, row.Title, row.Error)
+		} else {
+			fmt.Printf("row %d\t%s\t%s\n", row.Row, row.Title, row.Action)
+		}
+	}
+	if report.DryRun {
+		fmt.Print("dry run: ")
+	}
+	fmt.Printf("%d rows, %d created, %d updated, %d failed\n", report.Total, report.Created, report.Updated, report.Failed)
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", report.Failed, report.Total)
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, svc service.TransferService, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "", "File format: csv or json")
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return errors.New(exportUsage)
+	}
+	what, path := fs.Arg(0), fs.Arg(1)
+
+	var run func(context.Context, string) ([]byte, error)
+	switch what {
+	case "inventory":
+		run = svc.ExportInventory
+	case "menu":
+		run = svc.ExportMenu
+	default:
+		return errors.New(exportUsage)
+	}
+
+	data, err := run(ctx, fileFormat(*format, path))
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func fileFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return service.FormatCSV
+	}
+	return service.FormatJSON
+}
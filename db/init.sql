@@ -1,7 +1,12 @@
-CREATE TYPE order_status AS ENUM ('canceled', 'completed', 'open');
+CREATE TYPE order_status AS ENUM ('canceled', 'completed', 'open', 'in_progress', 'ready');
+CREATE TYPE order_channel AS ENUM ('dine_in', 'takeaway', 'delivery');
+CREATE TYPE kitchen_status AS ENUM ('queued', 'in_progress', 'ready');
 CREATE TYPE size_type AS ENUM ('small', 'medium', 'large', 'extra_large');
 CREATE TYPE unit_type AS ENUM ('kg', 'l', 'pcs');
-CREATE TYPE transaction_type AS ENUM ('addition', 'consumption');
+CREATE TYPE transaction_type AS ENUM ('addition', 'consumption', 'adjustment', 'waste', 'production');
+CREATE TYPE stock_take_status AS ENUM ('open', 'committed', 'canceled');
+CREATE TYPE menu_version_status AS ENUM ('draft', 'live', 'retired');
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'delivered', 'dead');
 
 CREATE TABLE Customers (
     Customer_ID SERIAL PRIMARY KEY,
@@ -11,6 +16,16 @@ CREATE TABLE Customers (
     Preference JSONB DEFAULT '{}'::JSONB
 );
 
+-- Versions of the menu. Drafts collect changes that publishing applies all
+-- at once; exactly one version is live
+CREATE TABLE Menu_Versions (
+    Version_ID SERIAL PRIMARY KEY,
+    Name VARCHAR(100) NOT NULL,
+    Status menu_version_status NOT NULL DEFAULT 'draft',
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Published_At TIMESTAMP
+);
+
 CREATE TABLE Orders (
     Order_ID SERIAL PRIMARY KEY,
     Status order_status NOT NULL,
@@ -18,7 +33,21 @@ CREATE TABLE Orders (
     Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     Updated_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     Customer_ID INTEGER NOT NULL,
-    FOREIGN KEY (Customer_ID) REFERENCES Customers(Customer_ID) ON DELETE CASCADE
+    -- the menu version the order was priced against
+    Menu_Version_ID INTEGER,
+    -- how the order is fulfilled; Total_Amount includes the tax and the
+    -- delivery fee
+    Channel order_channel NOT NULL DEFAULT 'takeaway',
+    Table_Number INTEGER,
+    Delivery_Address TEXT,
+    Pickup_At TIMESTAMP,
+    Tax_Amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    Delivery_Fee DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    FOREIGN KEY (Customer_ID) REFERENCES Customers(Customer_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Version_ID) REFERENCES Menu_Versions(Version_ID),
+    CHECK ((Channel = 'dine_in') = (Table_Number IS NOT NULL)),
+    CHECK ((Channel = 'delivery') = (Delivery_Address IS NOT NULL)),
+    CHECK (Pickup_At IS NULL OR Channel = 'takeaway')
 );
 
 CREATE TABLE Order_Status_History (
@@ -29,15 +58,52 @@ CREATE TABLE Order_Status_History (
     FOREIGN KEY (Order_ID) REFERENCES Orders(Order_ID) ON DELETE CASCADE
 );
 
+-- Menu categories; names are unique regardless of case
+CREATE TABLE Categories (
+    Category_ID SERIAL PRIMARY KEY,
+    Name VARCHAR(50) NOT NULL,
+    Parent_ID INTEGER,
+    Display_Order INTEGER NOT NULL DEFAULT 0,
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    FOREIGN KEY (Parent_ID) REFERENCES Categories(Category_ID) ON DELETE SET NULL,
+    CHECK (Parent_ID <> Category_ID)
+);
+
 CREATE TABLE Menu_Items (
     Menu_Item_ID SERIAL PRIMARY KEY,
-    Name VARCHAR(100) NOT NULL UNIQUE,
+    Name VARCHAR(100) NOT NULL,
     Description TEXT,
     Price DECIMAL(10, 2) NOT NULL,
     Size size_type,
-    Category VARCHAR(50) NOT NULL,
+    Category_ID INTEGER NOT NULL REFERENCES Categories(Category_ID),
     Tags TEXT[],
-    Metadata JSONB DEFAULT '{}'::JSONB
+    Metadata JSONB DEFAULT '{}'::JSONB,
+    Sold_Out_Until TIMESTAMP,
+    Is_Bundle BOOLEAN NOT NULL DEFAULT FALSE,
+    -- archived items are off the menu but keep resolving for past orders
+    Archived_At TIMESTAMP
+);
+
+-- A bundle ("coffee + croissant") is sold as a set of slots, each filled
+-- with one of its options: a specific menu item or any item of a category
+CREATE TABLE Bundle_Slots (
+    Slot_ID SERIAL PRIMARY KEY,
+    Bundle_ID INTEGER NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Quantity INTEGER NOT NULL DEFAULT 1 CHECK (Quantity > 0),
+    Position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (Bundle_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE
+);
+
+CREATE TABLE Bundle_Slot_Options (
+    Option_ID SERIAL PRIMARY KEY,
+    Slot_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
+    Category_ID INTEGER,
+    FOREIGN KEY (Slot_ID) REFERENCES Bundle_Slots(Slot_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Category_ID) REFERENCES Categories(Category_ID) ON DELETE CASCADE,
+    CHECK ((Menu_Item_ID IS NULL) <> (Category_ID IS NULL))
 );
 
 CREATE TABLE Order_Items (
@@ -48,8 +114,19 @@ CREATE TABLE Order_Items (
     Price DECIMAL(10, 2) NOT NULL,
     Customization JSONB DEFAULT '{}'::JSONB,
     Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    -- Bundle lines carry the bundle price; their component lines point to
+    -- them and carry the share of that price allocated to each component
+    Is_Bundle BOOLEAN NOT NULL DEFAULT FALSE,
+    Parent_Item_ID INTEGER,
+    Bundle_Slot_ID INTEGER,
+    -- Where the kitchen is with the line; bundle lines themselves are not
+    -- made, their component lines are
+    Kitchen_Status kitchen_status NOT NULL DEFAULT 'queued',
+    Kitchen_Updated_At TIMESTAMP,
     FOREIGN KEY (Order_ID) REFERENCES Orders(Order_ID) ON DELETE CASCADE,
-    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE SET NULL
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE SET NULL,
+    FOREIGN KEY (Parent_Item_ID) REFERENCES Order_Items(Order_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Bundle_Slot_ID) REFERENCES Bundle_Slots(Slot_ID) ON DELETE SET NULL
 );
 
 CREATE TABLE Price_History (
@@ -61,14 +138,109 @@ CREATE TABLE Price_History (
     FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE
 );
 
+-- Price changes set up in advance; the scheduler applies them once
+-- Effective_At has passed and records them in Price_History
+CREATE TABLE Scheduled_Price_Changes (
+    Change_ID SERIAL PRIMARY KEY,
+    Menu_Item_ID INTEGER NOT NULL,
+    New_Price DECIMAL(10, 2) NOT NULL CHECK (New_Price > 0),
+    Effective_At TIMESTAMP NOT NULL,
+    Applied_At TIMESTAMP,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE
+);
+
+-- Recurring windows (happy hour) that override the price of an item or of
+-- every item of a category, with a fixed price or a percentage off.
+-- Days are ISO weekdays, 1 = Monday ... 7 = Sunday.
+CREATE TABLE Price_Windows (
+    Window_ID SERIAL PRIMARY KEY,
+    Name VARCHAR(100) NOT NULL,
+    Menu_Item_ID INTEGER,
+    Category_ID INTEGER,
+    Price DECIMAL(10, 2),
+    Discount_Pct DECIMAL(5, 2),
+    Days INTEGER[] NOT NULL,
+    Start_Time TIME NOT NULL,
+    End_Time TIME NOT NULL,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Category_ID) REFERENCES Categories(Category_ID) ON DELETE CASCADE,
+    CHECK ((Menu_Item_ID IS NULL) <> (Category_ID IS NULL)),
+    CHECK ((Price IS NULL) <> (Discount_Pct IS NULL)),
+    CHECK (Price IS NULL OR Price > 0),
+    CHECK (Discount_Pct IS NULL OR (Discount_Pct > 0 AND Discount_Pct < 100))
+);
+
+-- Hours of an ISO weekday during which orders placed ahead are picked up, and
+-- how many items can be picked up per 5 minute slot
+CREATE TABLE Pickup_Hours (
+    Day INTEGER PRIMARY KEY,
+    Start_Time TIME NOT NULL,
+    End_Time TIME NOT NULL,
+    Capacity INTEGER NOT NULL,
+    CHECK (Day BETWEEN 1 AND 7),
+    CHECK (Start_Time < End_Time),
+    CHECK (Capacity > 0)
+);
+
 CREATE TABLE Inventory (
     Inventory_ID SERIAL PRIMARY KEY,
     Name VARCHAR(255) NOT NULL,
     Quantity DECIMAL(12,4) NOT NULL,
     Unit unit_type NOT NULL,
-    Price NUMERIC(10, 2) NOT NULL
+    Price NUMERIC(10, 2) NOT NULL,
+    Is_Prepared BOOLEAN NOT NULL DEFAULT FALSE,
+    Yield_Quantity DECIMAL(12, 4),
+    Shelf_Life_Hours INTEGER,
+    -- allergen flags and nutrition per unit, see models.Allergens
+    Allergens TEXT[] NOT NULL DEFAULT '{}',
+    Nutrition JSONB,
+    -- stock at or below which an inventory.low_stock event is sent
+    Reorder_Level DECIMAL(12, 4),
+    Archived_At TIMESTAMP,
+    CHECK (Reorder_Level >= 0)
 );
 
+-- Recipe of one batch of a prepared (Is_Prepared) inventory item
+CREATE TABLE Prep_Recipe_Ingredients (
+    Prep_Recipe_Ingredient_ID SERIAL PRIMARY KEY,
+    Prepared_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(12, 4) NOT NULL,
+    FOREIGN KEY (Prepared_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE,
+    CHECK (Prepared_ID <> Inventory_ID)
+);
+
+CREATE TABLE Prep_Batches (
+    Batch_ID SERIAL PRIMARY KEY,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(12, 4) NOT NULL,
+    Unit_Cost NUMERIC(10, 2) NOT NULL,
+    Produced_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Expires_At TIMESTAMP,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+-- Stock on hand that can still be used. Prep stock is used oldest batch
+-- first, so what is left of a prepared item with a shelf life comes from its
+-- newest batches; stock beyond the batches not yet expired is left out.
+CREATE VIEW Usable_Stock AS
+SELECT
+    i.Inventory_ID,
+    CASE
+        WHEN i.Is_Prepared AND i.Shelf_Life_Hours IS NOT NULL
+            THEN LEAST(i.Quantity, COALESCE(fresh.Quantity, 0))
+        ELSE i.Quantity
+    END AS Quantity
+FROM Inventory i
+LEFT JOIN (
+    SELECT Inventory_ID, SUM(Quantity) AS Quantity
+    FROM Prep_Batches
+    WHERE Expires_At IS NULL OR Expires_At > NOW()
+    GROUP BY Inventory_ID
+) fresh ON fresh.Inventory_ID = i.Inventory_ID;
+
 CREATE TABLE Menu_Item_Ingredients (
     Menu_Item_Ingredients_ID SERIAL PRIMARY KEY,
     Menu_Item_ID INTEGER NOT NULL,
@@ -78,11 +250,56 @@ CREATE TABLE Menu_Item_Ingredients (
     FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
 );
 
+-- Options a customer can pick for an item ("oat milk", "extra shot"): each
+-- adds an inventory item to a portion and may take the place of a component
+CREATE TABLE Menu_Item_Modifiers (
+    Modifier_ID SERIAL PRIMARY KEY,
+    Menu_Item_ID INTEGER NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(10,3) NOT NULL CHECK (Quantity > 0),
+    Replaces_Inventory_ID INTEGER,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Replaces_Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+-- The changes of a menu version: a new item (no Menu_Item_ID), the new state
+-- of a menu item, or its removal
+CREATE TABLE Menu_Version_Items (
+    Entry_ID SERIAL PRIMARY KEY,
+    Version_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
+    Removed BOOLEAN NOT NULL DEFAULT FALSE,
+    Name VARCHAR(100),
+    Description TEXT,
+    Price DECIMAL(10, 2),
+    Size size_type,
+    Category_ID INTEGER,
+    Tags TEXT[],
+    Metadata JSONB DEFAULT '{}'::JSONB,
+    FOREIGN KEY (Version_ID) REFERENCES Menu_Versions(Version_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Category_ID) REFERENCES Categories(Category_ID),
+    CHECK (Removed OR (Name IS NOT NULL AND Price > 0 AND Category_ID IS NOT NULL)),
+    CHECK (NOT Removed OR Menu_Item_ID IS NOT NULL)
+);
+
+CREATE TABLE Menu_Version_Ingredients (
+    Entry_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(10, 3) NOT NULL CHECK (Quantity > 0),
+    PRIMARY KEY (Entry_ID, Inventory_ID),
+    FOREIGN KEY (Entry_ID) REFERENCES Menu_Version_Items(Entry_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID)
+);
+
 CREATE TABLE Inventory_Transactions (
     Transaction_ID SERIAL PRIMARY KEY,
     Inventory_ID INTEGER NOT NULL,
     Change_Amount DECIMAL(12, 4) NOT NULL,
     Transaction_Type transaction_type NOT NULL,
+    Unit_Cost NUMERIC(10, 2),
     Occurred_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
 );
@@ -90,41 +307,189 @@ CREATE TABLE Inventory_Transactions (
 CREATE TABLE Inventory_Reservations (
     Reservation_ID SERIAL PRIMARY KEY,
     Order_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
     Inventory_ID INTEGER NOT NULL,
     Reserved_Quantity DECIMAL(12, 4) NOT NULL,
     Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
     FOREIGN KEY (Order_ID) REFERENCES Orders(Order_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE SET NULL,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+-- Ingredients deducted when an order is closed, priced at the cost of the day
+CREATE TABLE Order_Consumption (
+    Consumption_ID SERIAL PRIMARY KEY,
+    Order_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(12, 4) NOT NULL,
+    Unit_Cost NUMERIC(10, 2) NOT NULL,
+    Consumed_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Order_ID) REFERENCES Orders(Order_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE SET NULL,
     FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
 );
 
-CREATE UNIQUE INDEX idx_order_items_unique ON Order_Items (Order_ID, Menu_Item_ID);
+CREATE TABLE Stock_Takes (
+    Stock_Take_ID SERIAL PRIMARY KEY,
+    Status stock_take_status NOT NULL DEFAULT 'open',
+    Note TEXT,
+    Started_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Committed_At TIMESTAMP
+);
+
+CREATE TABLE Stock_Take_Counts (
+    Count_ID SERIAL PRIMARY KEY,
+    Stock_Take_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Device VARCHAR(100) NOT NULL,
+    Counted_Quantity DECIMAL(12, 4) NOT NULL,
+    Counted_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Stock_Take_ID) REFERENCES Stock_Takes(Stock_Take_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+CREATE TABLE Stock_Take_Lines (
+    Line_ID SERIAL PRIMARY KEY,
+    Stock_Take_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Theoretical_Quantity DECIMAL(12, 4) NOT NULL,
+    Counted_Quantity DECIMAL(12, 4) NOT NULL,
+    Variance DECIMAL(12, 4) NOT NULL,
+    Unit_Price NUMERIC(10, 2) NOT NULL,
+    Variance_Value NUMERIC(12, 2) NOT NULL,
+    FOREIGN KEY (Stock_Take_ID) REFERENCES Stock_Takes(Stock_Take_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+-- Webhook subscriptions; Event_Types lists the outbox event types sent
+CREATE TABLE Webhooks (
+    Webhook_ID SERIAL PRIMARY KEY,
+    URL TEXT NOT NULL,
+    Secret TEXT NOT NULL,
+    Event_Types TEXT[] NOT NULL,
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Events written by triggers in the same transaction as the change itself.
+-- Dispatched_At is set once a delivery is queued for every webhook that
+-- subscribes to the event
+CREATE TABLE Outbox_Events (
+    Event_ID BIGSERIAL PRIMARY KEY,
+    Event_Type VARCHAR(50) NOT NULL,
+    Payload JSONB NOT NULL,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Dispatched_At TIMESTAMP
+);
+
+-- One event to one webhook. Pending deliveries are retried at
+-- Next_Attempt_At until they succeed or run out of attempts (dead)
+CREATE TABLE Webhook_Deliveries (
+    Delivery_ID BIGSERIAL PRIMARY KEY,
+    Webhook_ID INTEGER NOT NULL,
+    Event_ID BIGINT NOT NULL,
+    Status webhook_delivery_status NOT NULL DEFAULT 'pending',
+    Attempts INTEGER NOT NULL DEFAULT 0,
+    Next_Attempt_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Delivered_At TIMESTAMP,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Webhook_ID) REFERENCES Webhooks(Webhook_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Event_ID) REFERENCES Outbox_Events(Event_ID) ON DELETE CASCADE,
+    UNIQUE (Webhook_ID, Event_ID)
+);
+
+CREATE TABLE Webhook_Attempts (
+    Attempt_ID BIGSERIAL PRIMARY KEY,
+    Delivery_ID BIGINT NOT NULL,
+    Attempted_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Status_Code INTEGER,
+    Error TEXT,
+    Duration_Ms INTEGER NOT NULL,
+    FOREIGN KEY (Delivery_ID) REFERENCES Webhook_Deliveries(Delivery_ID) ON DELETE CASCADE
+);
+
+-- An item may be ordered on several lines, each with its own adjustments
+CREATE INDEX idx_order_items_order_id ON Order_Items (Order_ID);
 
 CREATE INDEX idx_orders_customer_id ON Orders(Customer_ID);
 
 CREATE INDEX idx_inventory_transactions_inventory_id ON Inventory_Transactions(Inventory_ID);
 
+CREATE INDEX idx_inventory_transactions_occurred_at ON Inventory_Transactions(Inventory_ID, Occurred_At);
+
 CREATE INDEX idx_order_status_history_order_id ON Order_Status_History(Order_ID);
 
 CREATE INDEX idx_orders_status ON Orders(Status);
 
+CREATE INDEX idx_order_consumption_order_id ON Order_Consumption(Order_ID);
+
+CREATE INDEX idx_scheduled_price_changes_pending ON Scheduled_Price_Changes(Effective_At) WHERE Applied_At IS NULL;
+
+CREATE INDEX idx_bundle_slots_bundle_id ON Bundle_Slots(Bundle_ID);
+
+CREATE INDEX idx_order_items_parent_item_id ON Order_Items(Parent_Item_ID);
+
+CREATE INDEX idx_prep_recipe_ingredients_prepared_id ON Prep_Recipe_Ingredients(Prepared_ID);
+
 CREATE INDEX idx_menu_item_ingredients_composite ON Menu_Item_Ingredients(Menu_Item_ID, Inventory_ID);
 
+CREATE UNIQUE INDEX idx_menu_item_modifiers_name ON Menu_Item_Modifiers (Menu_Item_ID, LOWER(Name));
+
+CREATE UNIQUE INDEX idx_categories_name ON Categories (LOWER(Name));
+
+CREATE INDEX idx_menu_items_category_id ON Menu_Items(Category_ID);
+
+-- names only have to be unique among the items on the menu
+CREATE UNIQUE INDEX idx_menu_items_name ON Menu_Items (Name) WHERE Archived_At IS NULL;
+
+CREATE INDEX idx_menu_items_tags ON Menu_Items USING GIN (Tags);
+
+CREATE INDEX idx_menu_items_price ON Menu_Items(Price);
+
+CREATE UNIQUE INDEX idx_menu_versions_single_live ON Menu_Versions (Status) WHERE Status = 'live';
+
+-- a draft holds at most one change per menu item
+CREATE UNIQUE INDEX idx_menu_version_items_item ON Menu_Version_Items (Version_ID, Menu_Item_ID);
+
+CREATE INDEX idx_orders_menu_version_id ON Orders(Menu_Version_ID);
+CREATE INDEX idx_orders_channel ON Orders(Channel);
+CREATE INDEX idx_orders_pickup_at ON Orders(Pickup_At) WHERE Pickup_At IS NOT NULL;
+
+-- Only one stock take may be in progress at a time
+CREATE UNIQUE INDEX idx_stock_takes_single_open ON Stock_Takes (Status) WHERE Status = 'open';
+
+CREATE UNIQUE INDEX idx_stock_take_counts_device ON Stock_Take_Counts (Stock_Take_ID, Inventory_ID, Device);
+
+CREATE INDEX idx_outbox_events_pending ON Outbox_Events (Event_ID) WHERE Dispatched_At IS NULL;
+
+CREATE INDEX idx_webhook_deliveries_due ON Webhook_Deliveries (Next_Attempt_At) WHERE Status = 'pending';
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON Webhook_Deliveries (Webhook_ID, Delivery_ID);
+
+CREATE INDEX idx_webhook_attempts_delivery_id ON Webhook_Attempts (Delivery_ID);
+
 CREATE OR REPLACE FUNCTION log_inventory_transaction()
 RETURNS TRIGGER AS $$
 BEGIN
     IF TG_OP = 'INSERT' THEN
-        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Occurred_At)
-        VALUES (NEW.Inventory_ID, NEW.Quantity, 'addition'::transaction_type, NOW());
+        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Unit_Cost, Occurred_At)
+        VALUES (NEW.Inventory_ID, NEW.Quantity, 'addition'::transaction_type, NEW.Price, NOW());
 
     ELSIF TG_OP = 'UPDATE' THEN
-        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Occurred_At)
+        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Unit_Cost, Occurred_At)
         VALUES (
             NEW.Inventory_ID,
             NEW.Quantity - OLD.Quantity,
-            CASE 
-                WHEN NEW.Quantity > OLD.Quantity THEN 'addition'::transaction_type
-                ELSE 'consumption'::transaction_type 
-            END,
+            -- callers may label the change for the current transaction, e.g. stock take corrections
+            COALESCE(
+                NULLIF(current_setting('frappuccino.transaction_type', true), '')::transaction_type,
+                CASE 
+                    WHEN NEW.Quantity > OLD.Quantity THEN 'addition'::transaction_type
+                    ELSE 'consumption'::transaction_type 
+                END
+            ),
+            NEW.Price,
             NOW()
         );
     END IF;
@@ -138,6 +503,65 @@ AFTER INSERT OR UPDATE ON Inventory
 FOR EACH ROW
 EXECUTE FUNCTION log_inventory_transaction();
 
+-- Order and stock events go to the outbox from triggers, so they commit or
+-- roll back with the change whichever code path makes it
+CREATE OR REPLACE FUNCTION outbox_order_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    kind TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        kind := 'order.created';
+    ELSIF NEW.Status IS DISTINCT FROM OLD.Status THEN
+        kind := CASE NEW.Status
+            WHEN 'completed' THEN 'order.completed'
+            WHEN 'canceled' THEN 'order.canceled'
+            ELSE 'order.status_changed'
+        END;
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO Outbox_Events (Event_Type, Payload)
+    VALUES (kind, jsonb_build_object(
+        'order_id', NEW.Order_ID::TEXT,
+        'customer_id', NEW.Customer_ID::TEXT,
+        'status', NEW.Status,
+        'amount', NEW.Total_Amount,
+        'channel', NEW.Channel,
+        'table_number', NEW.Table_Number
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER outbox_order_trigger
+AFTER INSERT OR UPDATE OF Status ON Orders
+FOR EACH ROW
+EXECUTE FUNCTION outbox_order_event();
+
+CREATE OR REPLACE FUNCTION outbox_low_stock_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO Outbox_Events (Event_Type, Payload)
+    VALUES ('inventory.low_stock', jsonb_build_object(
+        'inventory_id', NEW.Inventory_ID::TEXT,
+        'title', NEW.Name,
+        'stock', NEW.Quantity,
+        'measure', NEW.Unit,
+        'reorder_level', NEW.Reorder_Level
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- fires once when stock falls to the reorder level, not on every sale below it
+CREATE OR REPLACE TRIGGER outbox_low_stock_trigger
+AFTER UPDATE OF Quantity ON Inventory
+FOR EACH ROW
+WHEN (NEW.Reorder_Level IS NOT NULL AND NEW.Quantity <= NEW.Reorder_Level AND OLD.Quantity > NEW.Reorder_Level)
+EXECUTE FUNCTION outbox_low_stock_event();
+
 
 -- Customers
 INSERT INTO Customers (Customer_ID, Name, Email, Phone)
@@ -148,18 +572,28 @@ VALUES
 (4, 'David Brown', 'david@example.com', '456-789-0123'),
 (5, 'Eva Davis', 'eva@example.com', '567-890-1234');
 
+-- Menu Versions
+INSERT INTO Menu_Versions (Name, Status, Published_At) VALUES
+('Initial menu', 'live', CURRENT_TIMESTAMP);
+
+-- Categories
+INSERT INTO Categories (Name, Display_Order) VALUES
+('Appetizer', 1),
+('Main Course', 2),
+('Dessert', 3);
+
 -- Menu Items
-INSERT INTO Menu_Items (Name, Description, Price, Size, Category, Tags, Metadata) VALUES
-('Caesar Salad', 'Fresh romaine lettuce with grilled chicken and parmesan cheese', 8.99, 'medium', 'Appetizer', ARRAY['salad', 'chicken'], '{"spicy": false}'),
-('Bruschetta', 'Grilled bread rubbed with garlic and topped with tomatoes, basil, and olive oil', 6.50, 'small', 'Appetizer', ARRAY['bread', 'tomato'], '{"vegan": true}'),
-('Pumpkin Soup', 'Creamy pumpkin soup with a hint of nutmeg', 7.25, 'small', 'Appetizer', ARRAY['soup', 'pumpkin'], '{"gluten_free": true}'),
-('Beef Steak', 'Grilled beef steak served with seasonal vegetables', 18.50, 'large', 'Main Course', ARRAY['steak', 'beef'], '{"doneness": "medium rare"}'),
-('Pasta Carbonara', 'Classic Italian pasta with egg, cheese, and pancetta', 14.75, 'medium', 'Main Course', ARRAY['pasta', 'italian'], '{"contains_pork": true}'),
-('Grilled Salmon', 'Fresh salmon grilled to perfection with lemon butter sauce', 16.00, 'large', 'Main Course', ARRAY['fish', 'salmon'], '{"omega3": true}'),
-('Tiramisu', 'Traditional Italian dessert with layers of coffee-soaked ladyfingers and mascarpone', 6.00, 'medium', 'Dessert', ARRAY['dessert', 'coffee'], '{"contains_alcohol": false}'),
-('Cheesecake', 'New York style cheesecake with a graham cracker crust', 6.25, 'medium', 'Dessert', ARRAY['dessert', 'cheese'], '{"sweetness": "high"}'),
-('Fruit Salad', 'A mix of seasonal fruits served fresh', 5.50, 'small', 'Dessert', ARRAY['dessert', 'fruit'], '{"vegan": true}'),
-('Chocolate Lava Cake', 'Warm chocolate cake with a molten chocolate center', 7.50, 'small', 'Dessert', ARRAY['dessert', 'chocolate'], '{"temperature": "warm"}');
+INSERT INTO Menu_Items (Name, Description, Price, Size, Category_ID, Tags, Metadata) VALUES
+('Caesar Salad', 'Fresh romaine lettuce with grilled chicken and parmesan cheese', 8.99, 'medium', 1, ARRAY['salad', 'chicken'], '{"spicy": false}'),
+('Bruschetta', 'Grilled bread rubbed with garlic and topped with tomatoes, basil, and olive oil', 6.50, 'small', 1, ARRAY['bread', 'tomato'], '{"vegan": true}'),
+('Pumpkin Soup', 'Creamy pumpkin soup with a hint of nutmeg', 7.25, 'small', 1, ARRAY['soup', 'pumpkin'], '{"gluten_free": true}'),
+('Beef Steak', 'Grilled beef steak served with seasonal vegetables', 18.50, 'large', 2, ARRAY['steak', 'beef'], '{"doneness": "medium rare"}'),
+('Pasta Carbonara', 'Classic Italian pasta with egg, cheese, and pancetta', 14.75, 'medium', 2, ARRAY['pasta', 'italian'], '{"contains_pork": true}'),
+('Grilled Salmon', 'Fresh salmon grilled to perfection with lemon butter sauce', 16.00, 'large', 2, ARRAY['fish', 'salmon'], '{"omega3": true}'),
+('Tiramisu', 'Traditional Italian dessert with layers of coffee-soaked ladyfingers and mascarpone', 6.00, 'medium', 3, ARRAY['dessert', 'coffee'], '{"contains_alcohol": false}'),
+('Cheesecake', 'New York style cheesecake with a graham cracker crust', 6.25, 'medium', 3, ARRAY['dessert', 'cheese'], '{"sweetness": "high"}'),
+('Fruit Salad', 'A mix of seasonal fruits served fresh', 5.50, 'small', 3, ARRAY['dessert', 'fruit'], '{"vegan": true}'),
+('Chocolate Lava Cake', 'Warm chocolate cake with a molten chocolate center', 7.50, 'small', 3, ARRAY['dessert', 'chocolate'], '{"temperature": "warm"}');
 
 -- Price History
 INSERT INTO Price_History (Menu_Item_ID, Old_Price, New_Price, Changed_At) VALUES
@@ -406,4 +840,13 @@ VALUES (
     (SELECT Order_ID FROM Orders WHERE Created_At = '2025-02-02 12:15:00'),
     (SELECT Menu_Item_ID FROM Menu_Items WHERE Name = 'Fruit Salad'),
     1.000, 5.50, '{"extra_toppings": ["honey", "nuts"], "size": "large"}', '2025-02-02 12:16:00'
-);
\ No newline at end of file
+);
+
+-- Order_Consumption backfill for completed orders, costed with the current recipes
+INSERT INTO Order_Consumption (Order_ID, Menu_Item_ID, Inventory_ID, Quantity, Unit_Cost, Consumed_At)
+SELECT o.Order_ID, oi.Menu_Item_ID, mii.Inventory_ID, mii.Quantity * oi.Quantity, i.Price, o.Updated_At
+FROM Orders o
+JOIN Order_Items oi ON oi.Order_ID = o.Order_ID
+JOIN Menu_Item_Ingredients mii ON mii.Menu_Item_ID = oi.Menu_Item_ID
+JOIN Inventory i ON i.Inventory_ID = mii.Inventory_ID
+WHERE o.Status = 'completed';
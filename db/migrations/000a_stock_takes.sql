@@ -0,0 +1,77 @@
+-- Stock take: count sessions, the counts of each device and the variance
+-- lines written on commit. Inventory changes can be labelled for the current
+-- transaction, so that stock take corrections are logged as adjustments.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000a_stock_takes.sql
+
+BEGIN;
+
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'adjustment';
+
+CREATE TYPE stock_take_status AS ENUM ('open', 'committed', 'canceled');
+
+CREATE TABLE IF NOT EXISTS Stock_Takes (
+    Stock_Take_ID SERIAL PRIMARY KEY,
+    Status stock_take_status NOT NULL DEFAULT 'open',
+    Note TEXT,
+    Started_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Committed_At TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS Stock_Take_Counts (
+    Count_ID SERIAL PRIMARY KEY,
+    Stock_Take_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Device VARCHAR(100) NOT NULL,
+    Counted_Quantity DECIMAL(12, 4) NOT NULL,
+    Counted_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Stock_Take_ID) REFERENCES Stock_Takes(Stock_Take_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS Stock_Take_Lines (
+    Line_ID SERIAL PRIMARY KEY,
+    Stock_Take_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Theoretical_Quantity DECIMAL(12, 4) NOT NULL,
+    Counted_Quantity DECIMAL(12, 4) NOT NULL,
+    Variance DECIMAL(12, 4) NOT NULL,
+    Unit_Price NUMERIC(10, 2) NOT NULL,
+    Variance_Value NUMERIC(12, 2) NOT NULL,
+    FOREIGN KEY (Stock_Take_ID) REFERENCES Stock_Takes(Stock_Take_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+-- Only one stock take may be in progress at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_takes_single_open ON Stock_Takes (Status) WHERE Status = 'open';
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_take_counts_device ON Stock_Take_Counts (Stock_Take_ID, Inventory_ID, Device);
+
+CREATE OR REPLACE FUNCTION log_inventory_transaction()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Occurred_At)
+        VALUES (NEW.Inventory_ID, NEW.Quantity, 'addition'::transaction_type, NOW());
+
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Occurred_At)
+        VALUES (
+            NEW.Inventory_ID,
+            NEW.Quantity - OLD.Quantity,
+            -- callers may label the change for the current transaction, e.g. stock take corrections
+            COALESCE(
+                NULLIF(current_setting('frappuccino.transaction_type', true), '')::transaction_type,
+                CASE
+                    WHEN NEW.Quantity > OLD.Quantity THEN 'addition'::transaction_type
+                    ELSE 'consumption'::transaction_type
+                END
+            ),
+            NOW()
+        );
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,41 @@
+-- Cost history: every stock movement records the unit cost of the item at
+-- the time. Earlier movements keep no cost and are valued at the current
+-- price.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000b_inventory_cost_history.sql
+
+BEGIN;
+
+ALTER TABLE Inventory_Transactions ADD COLUMN IF NOT EXISTS Unit_Cost NUMERIC(10, 2);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_occurred_at ON Inventory_Transactions(Inventory_ID, Occurred_At);
+
+CREATE OR REPLACE FUNCTION log_inventory_transaction()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Unit_Cost, Occurred_At)
+        VALUES (NEW.Inventory_ID, NEW.Quantity, 'addition'::transaction_type, NEW.Price, NOW());
+
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO Inventory_Transactions (Inventory_ID, Change_Amount, Transaction_Type, Unit_Cost, Occurred_At)
+        VALUES (
+            NEW.Inventory_ID,
+            NEW.Quantity - OLD.Quantity,
+            -- callers may label the change for the current transaction, e.g. stock take corrections
+            COALESCE(
+                NULLIF(current_setting('frappuccino.transaction_type', true), '')::transaction_type,
+                CASE
+                    WHEN NEW.Quantity > OLD.Quantity THEN 'addition'::transaction_type
+                    ELSE 'consumption'::transaction_type
+                END
+            ),
+            NEW.Price,
+            NOW()
+        );
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,39 @@
+-- Profit report: the ingredients deducted for each closed order are kept
+-- with the cost of the day, reservations remember the menu item they were
+-- made for, and spoiled stock is written off as waste. Orders completed
+-- before this migration are costed with the current recipes and prices.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000c_order_consumption.sql
+
+BEGIN;
+
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'waste';
+
+ALTER TABLE Inventory_Reservations
+    ADD COLUMN IF NOT EXISTS Menu_Item_ID INTEGER REFERENCES Menu_Items(Menu_Item_ID) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS Order_Consumption (
+    Consumption_ID SERIAL PRIMARY KEY,
+    Order_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(12, 4) NOT NULL,
+    Unit_Cost NUMERIC(10, 2) NOT NULL,
+    Consumed_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Order_ID) REFERENCES Orders(Order_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE SET NULL,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_consumption_order_id ON Order_Consumption(Order_ID);
+
+INSERT INTO Order_Consumption (Order_ID, Menu_Item_ID, Inventory_ID, Quantity, Unit_Cost, Consumed_At)
+SELECT o.Order_ID, oi.Menu_Item_ID, mii.Inventory_ID, mii.Quantity * oi.Quantity, i.Price, o.Updated_At
+FROM Orders o
+JOIN Order_Items oi ON oi.Order_ID = o.Order_ID
+JOIN Menu_Item_Ingredients mii ON mii.Menu_Item_ID = oi.Menu_Item_ID
+JOIN Inventory i ON i.Inventory_ID = mii.Inventory_ID
+WHERE o.Status = 'completed'
+    AND NOT EXISTS (SELECT 1 FROM Order_Consumption c WHERE c.Order_ID = o.Order_ID);
+
+COMMIT;
@@ -0,0 +1,5 @@
+-- Sold-out marks: a menu item can be taken off sale until a given time.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000d_sold_out.sql
+
+ALTER TABLE Menu_Items ADD COLUMN IF NOT EXISTS Sold_Out_Until TIMESTAMP;
@@ -0,0 +1,37 @@
+-- Prepared items: inventory items made in house from a recipe of other
+-- inventory items, produced in batches with a shelf life.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000e_prepared_items.sql
+
+BEGIN;
+
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'production';
+
+ALTER TABLE Inventory
+    ADD COLUMN IF NOT EXISTS Is_Prepared BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS Yield_Quantity DECIMAL(12, 4),
+    ADD COLUMN IF NOT EXISTS Shelf_Life_Hours INTEGER;
+
+CREATE TABLE IF NOT EXISTS Prep_Recipe_Ingredients (
+    Prep_Recipe_Ingredient_ID SERIAL PRIMARY KEY,
+    Prepared_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(12, 4) NOT NULL,
+    FOREIGN KEY (Prepared_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE,
+    CHECK (Prepared_ID <> Inventory_ID)
+);
+
+CREATE TABLE IF NOT EXISTS Prep_Batches (
+    Batch_ID SERIAL PRIMARY KEY,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(12, 4) NOT NULL,
+    Unit_Cost NUMERIC(10, 2) NOT NULL,
+    Produced_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Expires_At TIMESTAMP,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_prep_recipe_ingredients_prepared_id ON Prep_Recipe_Ingredients(Prepared_ID);
+
+COMMIT;
@@ -0,0 +1,44 @@
+-- Bundles: a menu item sold as a set of slots, each filled with one of its
+-- options. Order lines of a bundle carry its price and point to the lines of
+-- its components, which may repeat an item ordered on its own.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000f_bundles.sql
+
+BEGIN;
+
+ALTER TABLE Menu_Items ADD COLUMN IF NOT EXISTS Is_Bundle BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS Bundle_Slots (
+    Slot_ID SERIAL PRIMARY KEY,
+    Bundle_ID INTEGER NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Quantity INTEGER NOT NULL DEFAULT 1 CHECK (Quantity > 0),
+    Position INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY (Bundle_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE
+);
+
+-- Category holds the free-text category; 001_menu_categories.sql turns it
+-- into a Category_ID
+CREATE TABLE IF NOT EXISTS Bundle_Slot_Options (
+    Option_ID SERIAL PRIMARY KEY,
+    Slot_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
+    Category VARCHAR(50),
+    FOREIGN KEY (Slot_ID) REFERENCES Bundle_Slots(Slot_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    CHECK ((Menu_Item_ID IS NULL) <> (Category IS NULL))
+);
+
+ALTER TABLE Order_Items
+    ADD COLUMN IF NOT EXISTS Is_Bundle BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS Parent_Item_ID INTEGER REFERENCES Order_Items(Order_Item_ID) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS Bundle_Slot_ID INTEGER REFERENCES Bundle_Slots(Slot_ID) ON DELETE SET NULL;
+
+DROP INDEX IF EXISTS idx_order_items_unique;
+CREATE UNIQUE INDEX idx_order_items_unique ON Order_Items (Order_ID, Menu_Item_ID) WHERE Parent_Item_ID IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_bundle_slots_bundle_id ON Bundle_Slots(Bundle_ID);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_parent_item_id ON Order_Items(Parent_Item_ID);
+
+COMMIT;
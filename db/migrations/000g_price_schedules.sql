@@ -0,0 +1,39 @@
+-- Pricing: price changes scheduled in advance and recurring price windows
+-- (happy hour) for an item or a category.
+--
+--   psql "$DATABASE_URL" -f db/migrations/000g_price_schedules.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS Scheduled_Price_Changes (
+    Change_ID SERIAL PRIMARY KEY,
+    Menu_Item_ID INTEGER NOT NULL,
+    New_Price DECIMAL(10, 2) NOT NULL CHECK (New_Price > 0),
+    Effective_At TIMESTAMP NOT NULL,
+    Applied_At TIMESTAMP,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE
+);
+
+-- Category holds the free-text category; 001_menu_categories.sql turns it
+-- into a Category_ID. Days are ISO weekdays, 1 = Monday ... 7 = Sunday.
+CREATE TABLE IF NOT EXISTS Price_Windows (
+    Window_ID SERIAL PRIMARY KEY,
+    Name VARCHAR(100) NOT NULL,
+    Menu_Item_ID INTEGER,
+    Category VARCHAR(50),
+    Price DECIMAL(10, 2),
+    Discount_Pct DECIMAL(5, 2),
+    Days INTEGER[] NOT NULL,
+    Start_Time TIME NOT NULL,
+    End_Time TIME NOT NULL,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    CHECK ((Menu_Item_ID IS NULL) <> (Category IS NULL)),
+    CHECK ((Price IS NULL) <> (Discount_Pct IS NULL)),
+    CHECK (Price IS NULL OR Price > 0),
+    CHECK (Discount_Pct IS NULL OR (Discount_Pct > 0 AND Discount_Pct < 100))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_price_changes_pending ON Scheduled_Price_Changes(Effective_At) WHERE Applied_At IS NULL;
+
+COMMIT;
@@ -0,0 +1,89 @@
+-- Moves a database created before categories were managed onto the
+-- Categories table. Fresh databases get the final schema from init.sql.
+--
+-- Free-text category values are folded together before they become
+-- categories: surrounding spaces and case are ignored, and a plural is merged
+-- into its singular when both are in use ("Coffee", "coffee " and "Coffees"
+-- all end up in "Coffee").
+--
+--   psql "$DATABASE_URL" -f db/migrations/001_menu_categories.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS Categories (
+    Category_ID SERIAL PRIMARY KEY,
+    Name VARCHAR(50) NOT NULL,
+    Parent_ID INTEGER,
+    Display_Order INTEGER NOT NULL DEFAULT 0,
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    FOREIGN KEY (Parent_ID) REFERENCES Categories(Category_ID) ON DELETE SET NULL,
+    CHECK (Parent_ID <> Category_ID)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON Categories (LOWER(Name));
+
+CREATE TEMP TABLE Category_Map ON COMMIT DROP AS
+WITH Spellings AS (
+    SELECT Category AS Original, LOWER(BTRIM(Category)) AS Folded FROM Menu_Items
+    UNION
+    SELECT Category, LOWER(BTRIM(Category)) FROM Bundle_Slot_Options WHERE Category IS NOT NULL
+    UNION
+    SELECT Category, LOWER(BTRIM(Category)) FROM Price_Windows WHERE Category IS NOT NULL
+)
+SELECT
+    s.Original,
+    CASE
+        WHEN s.Folded = '' THEN 'uncategorized'
+        WHEN s.Folded LIKE '%s' AND EXISTS (
+            SELECT 1 FROM Spellings o WHERE o.Folded = LEFT(s.Folded, -1)
+        ) THEN LEFT(s.Folded, -1)
+        ELSE s.Folded
+    END AS Key
+FROM Spellings s;
+
+INSERT INTO Categories (Name, Display_Order)
+SELECT INITCAP(Key), ROW_NUMBER() OVER (ORDER BY Key)
+FROM (SELECT DISTINCT Key FROM Category_Map) k
+ON CONFLICT ((LOWER(Name))) DO NOTHING;
+
+-- Menu items
+ALTER TABLE Menu_Items ADD COLUMN Category_ID INTEGER REFERENCES Categories(Category_ID);
+
+UPDATE Menu_Items mi
+SET Category_ID = c.Category_ID
+FROM Category_Map m
+JOIN Categories c ON LOWER(c.Name) = m.Key
+WHERE m.Original = mi.Category;
+
+ALTER TABLE Menu_Items ALTER COLUMN Category_ID SET NOT NULL;
+ALTER TABLE Menu_Items DROP COLUMN Category;
+
+CREATE INDEX idx_menu_items_category_id ON Menu_Items(Category_ID);
+
+-- Bundle slot options; dropping Category also drops the old CHECK on it
+ALTER TABLE Bundle_Slot_Options
+    ADD COLUMN Category_ID INTEGER REFERENCES Categories(Category_ID) ON DELETE CASCADE;
+
+UPDATE Bundle_Slot_Options bo
+SET Category_ID = c.Category_ID
+FROM Category_Map m
+JOIN Categories c ON LOWER(c.Name) = m.Key
+WHERE m.Original = bo.Category;
+
+ALTER TABLE Bundle_Slot_Options DROP COLUMN Category;
+ALTER TABLE Bundle_Slot_Options ADD CHECK ((Menu_Item_ID IS NULL) <> (Category_ID IS NULL));
+
+-- Price windows
+ALTER TABLE Price_Windows
+    ADD COLUMN Category_ID INTEGER REFERENCES Categories(Category_ID) ON DELETE CASCADE;
+
+UPDATE Price_Windows pw
+SET Category_ID = c.Category_ID
+FROM Category_Map m
+JOIN Categories c ON LOWER(c.Name) = m.Key
+WHERE m.Original = pw.Category;
+
+ALTER TABLE Price_Windows DROP COLUMN Category;
+ALTER TABLE Price_Windows ADD CHECK ((Menu_Item_ID IS NULL) <> (Category_ID IS NULL));
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Indexes behind the tag and price filters of GET /menu.
+--
+--   psql "$DATABASE_URL" -f db/migrations/002_menu_filter_indexes.sql
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_tags ON Menu_Items USING GIN (Tags);
+
+CREATE INDEX IF NOT EXISTS idx_menu_items_price ON Menu_Items(Price);
@@ -0,0 +1,7 @@
+-- Allergen flags and nutrition per unit on inventory items.
+--
+--   psql "$DATABASE_URL" -f db/migrations/003_inventory_allergens.sql
+
+ALTER TABLE Inventory ADD COLUMN IF NOT EXISTS Allergens TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE Inventory ADD COLUMN IF NOT EXISTS Nutrition JSONB;
@@ -0,0 +1,16 @@
+-- Archiving replaces deleting for menu and inventory items, so past orders,
+-- recipes and reports keep their references.
+--
+--   psql "$DATABASE_URL" -f db/migrations/004_archive_menu_and_inventory.sql
+
+BEGIN;
+
+ALTER TABLE Menu_Items ADD COLUMN IF NOT EXISTS Archived_At TIMESTAMP;
+
+ALTER TABLE Inventory ADD COLUMN IF NOT EXISTS Archived_At TIMESTAMP;
+
+ALTER TABLE Menu_Items DROP CONSTRAINT IF EXISTS menu_items_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_menu_items_name ON Menu_Items (Name) WHERE Archived_At IS NULL;
+
+COMMIT;
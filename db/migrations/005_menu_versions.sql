@@ -0,0 +1,57 @@
+-- Menu versions: drafts of menu changes published all at once, and the
+-- version every order was priced against. The current menu becomes the live
+-- version; orders placed before it keep no version.
+--
+--   psql "$DATABASE_URL" -f db/migrations/005_menu_versions.sql
+
+BEGIN;
+
+CREATE TYPE menu_version_status AS ENUM ('draft', 'live', 'retired');
+
+CREATE TABLE Menu_Versions (
+    Version_ID SERIAL PRIMARY KEY,
+    Name VARCHAR(100) NOT NULL,
+    Status menu_version_status NOT NULL DEFAULT 'draft',
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Published_At TIMESTAMP
+);
+
+CREATE TABLE Menu_Version_Items (
+    Entry_ID SERIAL PRIMARY KEY,
+    Version_ID INTEGER NOT NULL,
+    Menu_Item_ID INTEGER,
+    Removed BOOLEAN NOT NULL DEFAULT FALSE,
+    Name VARCHAR(100),
+    Description TEXT,
+    Price DECIMAL(10, 2),
+    Size size_type,
+    Category_ID INTEGER,
+    Tags TEXT[],
+    Metadata JSONB DEFAULT '{}'::JSONB,
+    FOREIGN KEY (Version_ID) REFERENCES Menu_Versions(Version_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Category_ID) REFERENCES Categories(Category_ID),
+    CHECK (Removed OR (Name IS NOT NULL AND Price > 0 AND Category_ID IS NOT NULL)),
+    CHECK (NOT Removed OR Menu_Item_ID IS NOT NULL)
+);
+
+CREATE TABLE Menu_Version_Ingredients (
+    Entry_ID INTEGER NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(10, 3) NOT NULL CHECK (Quantity > 0),
+    PRIMARY KEY (Entry_ID, Inventory_ID),
+    FOREIGN KEY (Entry_ID) REFERENCES Menu_Version_Items(Entry_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID)
+);
+
+ALTER TABLE Orders ADD COLUMN Menu_Version_ID INTEGER REFERENCES Menu_Versions(Version_ID);
+
+CREATE UNIQUE INDEX idx_menu_versions_single_live ON Menu_Versions (Status) WHERE Status = 'live';
+
+CREATE UNIQUE INDEX idx_menu_version_items_item ON Menu_Version_Items (Version_ID, Menu_Item_ID);
+
+CREATE INDEX idx_orders_menu_version_id ON Orders(Menu_Version_ID);
+
+INSERT INTO Menu_Versions (Name, Status, Published_At) VALUES ('Initial menu', 'live', CURRENT_TIMESTAMP);
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Kitchen display: orders move from open through in_progress and ready to
+-- completed, and every order line tracks how far the kitchen got with it.
+-- Existing lines start out queued.
+--
+--   psql "$DATABASE_URL" -f db/migrations/006_kitchen_display.sql
+
+BEGIN;
+
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'in_progress';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'ready';
+
+CREATE TYPE kitchen_status AS ENUM ('queued', 'in_progress', 'ready');
+
+ALTER TABLE Order_Items
+    ADD COLUMN Kitchen_Status kitchen_status NOT NULL DEFAULT 'queued',
+    ADD COLUMN Kitchen_Updated_At TIMESTAMP;
+
+COMMIT;
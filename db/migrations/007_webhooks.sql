@@ -0,0 +1,127 @@
+-- Outgoing webhooks: subscriptions, the transactional outbox filled by
+-- triggers on orders and stock, and the delivery log. Also adds the reorder
+-- level that raises inventory.low_stock.
+--
+--   psql "$DATABASE_URL" -f db/migrations/007_webhooks.sql
+
+BEGIN;
+
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'delivered', 'dead');
+
+ALTER TABLE Inventory
+    ADD COLUMN Reorder_Level DECIMAL(12, 4),
+    ADD CHECK (Reorder_Level >= 0);
+
+-- Webhook subscriptions; Event_Types lists the outbox event types sent
+CREATE TABLE Webhooks (
+    Webhook_ID SERIAL PRIMARY KEY,
+    URL TEXT NOT NULL,
+    Secret TEXT NOT NULL,
+    Event_Types TEXT[] NOT NULL,
+    Active BOOLEAN NOT NULL DEFAULT TRUE,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+-- Events written by triggers in the same transaction as the change itself.
+-- Dispatched_At is set once a delivery is queued for every webhook that
+-- subscribes to the event
+CREATE TABLE Outbox_Events (
+    Event_ID BIGSERIAL PRIMARY KEY,
+    Event_Type VARCHAR(50) NOT NULL,
+    Payload JSONB NOT NULL,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Dispatched_At TIMESTAMP
+);
+
+-- One event to one webhook. Pending deliveries are retried at
+-- Next_Attempt_At until they succeed or run out of attempts (dead)
+CREATE TABLE Webhook_Deliveries (
+    Delivery_ID BIGSERIAL PRIMARY KEY,
+    Webhook_ID INTEGER NOT NULL,
+    Event_ID BIGINT NOT NULL,
+    Status webhook_delivery_status NOT NULL DEFAULT 'pending',
+    Attempts INTEGER NOT NULL DEFAULT 0,
+    Next_Attempt_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Delivered_At TIMESTAMP,
+    Created_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    FOREIGN KEY (Webhook_ID) REFERENCES Webhooks(Webhook_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Event_ID) REFERENCES Outbox_Events(Event_ID) ON DELETE CASCADE,
+    UNIQUE (Webhook_ID, Event_ID)
+);
+
+CREATE TABLE Webhook_Attempts (
+    Attempt_ID BIGSERIAL PRIMARY KEY,
+    Delivery_ID BIGINT NOT NULL,
+    Attempted_At TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    Status_Code INTEGER,
+    Error TEXT,
+    Duration_Ms INTEGER NOT NULL,
+    FOREIGN KEY (Delivery_ID) REFERENCES Webhook_Deliveries(Delivery_ID) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_outbox_events_pending ON Outbox_Events (Event_ID) WHERE Dispatched_At IS NULL;
+
+CREATE INDEX idx_webhook_deliveries_due ON Webhook_Deliveries (Next_Attempt_At) WHERE Status = 'pending';
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON Webhook_Deliveries (Webhook_ID, Delivery_ID);
+
+CREATE INDEX idx_webhook_attempts_delivery_id ON Webhook_Attempts (Delivery_ID);
+
+-- Order and stock events go to the outbox from triggers, so they commit or
+-- roll back with the change whichever code path makes it
+CREATE OR REPLACE FUNCTION outbox_order_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    kind TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        kind := 'order.created';
+    ELSIF NEW.Status IS DISTINCT FROM OLD.Status THEN
+        kind := CASE NEW.Status
+            WHEN 'completed' THEN 'order.completed'
+            WHEN 'canceled' THEN 'order.canceled'
+            ELSE 'order.status_changed'
+        END;
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO Outbox_Events (Event_Type, Payload)
+    VALUES (kind, jsonb_build_object(
+        'order_id', NEW.Order_ID::TEXT,
+        'customer_id', NEW.Customer_ID::TEXT,
+        'status', NEW.Status,
+        'amount', NEW.Total_Amount
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER outbox_order_trigger
+AFTER INSERT OR UPDATE OF Status ON Orders
+FOR EACH ROW
+EXECUTE FUNCTION outbox_order_event();
+
+CREATE OR REPLACE FUNCTION outbox_low_stock_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO Outbox_Events (Event_Type, Payload)
+    VALUES ('inventory.low_stock', jsonb_build_object(
+        'inventory_id', NEW.Inventory_ID::TEXT,
+        'title', NEW.Name,
+        'stock', NEW.Quantity,
+        'measure', NEW.Unit,
+        'reorder_level', NEW.Reorder_Level
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- fires once when stock falls to the reorder level, not on every sale below it
+CREATE OR REPLACE TRIGGER outbox_low_stock_trigger
+AFTER UPDATE OF Quantity ON Inventory
+FOR EACH ROW
+WHEN (NEW.Reorder_Level IS NOT NULL AND NEW.Quantity <= NEW.Reorder_Level AND OLD.Quantity > NEW.Reorder_Level)
+EXECUTE FUNCTION outbox_low_stock_event();
+
+COMMIT;
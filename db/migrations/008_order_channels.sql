@@ -0,0 +1,56 @@
+-- Order channels: an order is dine-in at a table, takeaway, possibly
+-- pre-ordered for a pickup time, or delivered to an address for a fee. The
+-- tax and the fee are kept apart from the total they are part of. Existing
+-- orders become takeaway orders without tax.
+--
+--   psql "$DATABASE_URL" -f db/migrations/008_order_channels.sql
+
+BEGIN;
+
+CREATE TYPE order_channel AS ENUM ('dine_in', 'takeaway', 'delivery');
+
+ALTER TABLE Orders
+    ADD COLUMN Channel order_channel NOT NULL DEFAULT 'takeaway',
+    ADD COLUMN Table_Number INTEGER,
+    ADD COLUMN Delivery_Address TEXT,
+    ADD COLUMN Pickup_At TIMESTAMP,
+    ADD COLUMN Tax_Amount DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN Delivery_Fee DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    ADD CHECK ((Channel = 'dine_in') = (Table_Number IS NOT NULL)),
+    ADD CHECK ((Channel = 'delivery') = (Delivery_Address IS NOT NULL)),
+    ADD CHECK (Pickup_At IS NULL OR Channel = 'takeaway');
+
+CREATE INDEX idx_orders_channel ON Orders(Channel);
+
+-- order webhooks tell the channel and the table
+CREATE OR REPLACE FUNCTION outbox_order_event()
+RETURNS TRIGGER AS $$
+DECLARE
+    kind TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        kind := 'order.created';
+    ELSIF NEW.Status IS DISTINCT FROM OLD.Status THEN
+        kind := CASE NEW.Status
+            WHEN 'completed' THEN 'order.completed'
+            WHEN 'canceled' THEN 'order.canceled'
+            ELSE 'order.status_changed'
+        END;
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO Outbox_Events (Event_Type, Payload)
+    VALUES (kind, jsonb_build_object(
+        'order_id', NEW.Order_ID::TEXT,
+        'customer_id', NEW.Customer_ID::TEXT,
+        'status', NEW.Status,
+        'amount', NEW.Total_Amount,
+        'channel', NEW.Channel,
+        'table_number', NEW.Table_Number
+    ));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
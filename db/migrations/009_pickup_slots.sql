@@ -0,0 +1,21 @@
+-- Pickup slots: orders placed ahead are picked up within the pickup hours of
+-- the weekday, in 5 minute slots of limited capacity. No day has pickup
+-- hours until they are set.
+--
+--   psql "$DATABASE_URL" -f db/migrations/009_pickup_slots.sql
+
+BEGIN;
+
+CREATE TABLE Pickup_Hours (
+    Day INTEGER PRIMARY KEY,
+    Start_Time TIME NOT NULL,
+    End_Time TIME NOT NULL,
+    Capacity INTEGER NOT NULL,
+    CHECK (Day BETWEEN 1 AND 7),
+    CHECK (Start_Time < End_Time),
+    CHECK (Capacity > 0)
+);
+
+CREATE INDEX idx_orders_pickup_at ON Orders(Pickup_At) WHERE Pickup_At IS NOT NULL;
+
+COMMIT;
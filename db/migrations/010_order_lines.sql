@@ -0,0 +1,12 @@
+-- Order lines: an item may be ordered on several lines of an order, each
+-- with its own adjustments, so lines are no longer unique per item.
+--
+--   psql "$DATABASE_URL" -f db/migrations/010_order_lines.sql
+
+BEGIN;
+
+DROP INDEX IF EXISTS idx_order_items_unique;
+
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON Order_Items (Order_ID);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Usable stock: prep stock past its shelf life no longer counts towards
+-- availability, order checks or production.
+--
+--   psql "$DATABASE_URL" -f db/migrations/011_usable_stock.sql
+
+BEGIN;
+
+CREATE OR REPLACE VIEW Usable_Stock AS
+SELECT
+    i.Inventory_ID,
+    CASE
+        WHEN i.Is_Prepared AND i.Shelf_Life_Hours IS NOT NULL
+            THEN LEAST(i.Quantity, COALESCE(fresh.Quantity, 0))
+        ELSE i.Quantity
+    END AS Quantity
+FROM Inventory i
+LEFT JOIN (
+    SELECT Inventory_ID, SUM(Quantity) AS Quantity
+    FROM Prep_Batches
+    WHERE Expires_At IS NULL OR Expires_At > NOW()
+    GROUP BY Inventory_ID
+) fresh ON fresh.Inventory_ID = i.Inventory_ID;
+
+COMMIT;
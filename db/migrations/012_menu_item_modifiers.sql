@@ -0,0 +1,22 @@
+-- Menu item modifiers: options such as oat milk or an extra shot, whose
+-- ingredients count towards the allergens and nutrition of the item.
+--
+--   psql "$DATABASE_URL" -f db/migrations/012_menu_item_modifiers.sql
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS Menu_Item_Modifiers (
+    Modifier_ID SERIAL PRIMARY KEY,
+    Menu_Item_ID INTEGER NOT NULL,
+    Name VARCHAR(100) NOT NULL,
+    Inventory_ID INTEGER NOT NULL,
+    Quantity DECIMAL(10,3) NOT NULL CHECK (Quantity > 0),
+    Replaces_Inventory_ID INTEGER,
+    FOREIGN KEY (Menu_Item_ID) REFERENCES Menu_Items(Menu_Item_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE,
+    FOREIGN KEY (Replaces_Inventory_ID) REFERENCES Inventory(Inventory_ID) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_menu_item_modifiers_name ON Menu_Item_Modifiers (Menu_Item_ID, LOWER(Name));
+
+COMMIT;
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+	"frappuccino/pkg/json"
+)
+
+type CategoryHandler struct {
+	CategorySvc service.CategoryService
+}
+
+func NewCategoryHandler(svc service.CategoryService) *CategoryHandler {
+	return &CategoryHandler{
+		CategorySvc: svc,
+	}
+}
+
+func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	categories, err := h.CategorySvc.GetAllCategories(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting categories: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, categories)
+}
+
+func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.CategorySvc.GetCategoryByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting category: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, category)
+}
+
+func (h *CategoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	category, err := json.UnmarshalJson[*models.Category](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.CategorySvc.CreateCategory(ctx, category); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error creating category: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusCreated, category)
+}
+
+func (h *CategoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	category, err := json.UnmarshalJson[*models.Category](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.CategorySvc.UpdateCategory(ctx, r.PathValue("id"), category); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error updating category: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, category)
+}
+
+func (h *CategoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.CategorySvc.DeleteCategory(ctx, r.PathValue("id")); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error deleting category: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Category deleted")
+}
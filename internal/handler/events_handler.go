@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+)
+
+var orderStatuses = []string{"open", "in_progress", "ready", "completed", "canceled"}
+
+type EventsHandler struct {
+	Events *service.OrderEventBus
+}
+
+func NewEventsHandler(events *service.OrderEventBus) *EventsHandler {
+	return &EventsHandler{
+		Events: events,
+	}
+}
+
+// StreamOrders pushes order events as Server-Sent Events, optionally only
+// those of some statuses (?status=ready,completed) or of one customer
+// (?customer=3). A client resumes with the Last-Event-ID header, or
+// ?last_event_id= where it cannot set headers. When events it missed are no
+// longer kept, a "reset" event comes first and the client should reload the
+// orders it shows.
+func (h *EventsHandler) StreamOrders(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		Respond(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := models.OrderEventFilter{CustomerID: query.Get("customer")}
+	if v := query.Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			status = strings.TrimSpace(status)
+			if !slices.Contains(orderStatuses, status) {
+				Respond(w, http.StatusBadRequest, "invalid status "+strconv.Quote(status))
+				return
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	var lastID *int64
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastID = &id
+	}
+
+	replay, complete, events, unsubscribe := h.Events.Subscribe(filter, lastID)
+	defer unsubscribe()
+
+	startEventStream(w)
+	if !complete {
+		if err := writeEvent(w, "", "reset", map[string]string{"reason": "missed events are no longer available"}); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := writeOrderEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// fell too far behind; the client resumes from its last event
+				return
+			}
+			if err := writeOrderEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+func writeOrderEvent(w io.Writer, event *models.OrderEvent) error {
+	return writeEvent(w, strconv.FormatInt(event.ID, 10), event.Type, event)
+}
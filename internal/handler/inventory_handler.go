@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"frappuccino/internal/models"
 	"frappuccino/internal/service"
@@ -23,7 +26,8 @@ func NewInventoryHandler(svc service.InventoryService) *InventoryHandler {
 
 func (h *InventoryHandler) GetInventoryItems(w http.ResponseWriter, r *http.Request) {
 	slog.Info("Handling GetInventoryItems request: path=%v", r.URL.Path)
-	items, err := h.InvService.GetAllInventory(r.Context())
+	includeArchived := strings.Contains(r.URL.Query().Get("include"), "archived")
+	items, err := h.InvService.GetAllInventory(r.Context(), includeArchived)
 	if err != nil {
 		slog.Error("Error getting inventory items: %v", err)
 		Respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
@@ -118,18 +122,26 @@ func (h *InventoryHandler) UpdateInventoryItem(w http.ResponseWriter, r *http.Re
 
 func (h *InventoryHandler) DeleteInventoryItem(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
-	if err := h.InvService.DeleteInventoryByID(r.Context(), id); err != nil {
-		if errors.Is(err, cerrors.ErrNotExist) {
-			slog.Error("Inventory item not found for deletion, id=%v", id)
-			Respond(w, http.StatusNotFound, map[string]string{"error": cerrors.ErrNotExist.Error()})
-			return
-		}
-		slog.Error("Error deleting inventory item: id=%v, error=%v", id, err)
-		Respond(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	if err := h.InvService.ArchiveInventory(r.Context(), id); err != nil {
+		slog.Error("Error archiving inventory item: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, map[string]string{"error": Err.Message})
+		return
+	}
+	slog.Info("Inventory item archived successfully: id=%v", id)
+	Respond(w, http.StatusOK, map[string]string{"message": "Inventory item archived successfully"})
+}
+
+func (h *InventoryHandler) RestoreInventoryItem(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.InvService.RestoreInventory(r.Context(), id); err != nil {
+		slog.Error("Error restoring inventory item: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, map[string]string{"error": Err.Message})
 		return
 	}
-	slog.Info("Inventory item deleted successfully: id=%v", id)
-	Respond(w, http.StatusOK, map[string]string{"message": "Inventory item deleted successfully"})
+	slog.Info("Inventory item restored successfully: id=%v", id)
+	Respond(w, http.StatusOK, map[string]string{"message": "Inventory item restored successfully"})
 }
 
 func (h *InventoryHandler) GetInventoryList(w http.ResponseWriter, r *http.Request) {
@@ -150,3 +162,152 @@ func (h *InventoryHandler) GetInventoryList(w http.ResponseWriter, r *http.Reque
 	}
 	Respond(w, http.StatusOK, response)
 }
+
+func (h *InventoryHandler) ReceiveStock(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		Respond(w, http.StatusUnsupportedMediaType, "content type is not application/json")
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	var receipt models.StockReceipt
+	if err := json.Unmarshal(data, &receipt); err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	if err := h.InvService.ReceiveStock(ctx, id, &receipt); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error receiving stock: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+	slog.Info("Stock received: id=%v, quantity=%v", id, receipt.Quantity)
+	Respond(w, http.StatusOK, "Stock received")
+}
+
+func (h *InventoryHandler) GetCostHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	history, err := h.InvService.GetCostHistory(ctx, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting cost history: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+	Respond(w, http.StatusOK, history)
+}
+
+func (h *InventoryHandler) RecordWaste(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		Respond(w, http.StatusUnsupportedMediaType, "content type is not application/json")
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	var waste models.WasteRecord
+	if err := json.Unmarshal(data, &waste); err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	if err := h.InvService.RecordWaste(ctx, id, &waste); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error recording waste: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+	slog.Info("Waste recorded: id=%v, quantity=%v", id, waste.Quantity)
+	Respond(w, http.StatusOK, "Waste recorded")
+}
+
+func (h *InventoryHandler) Produce(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		Respond(w, http.StatusUnsupportedMediaType, "content type is not application/json")
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	var req models.ProductionRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	batch, err := h.InvService.Produce(ctx, id, &req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error producing prepared item: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+	slog.Info("Prepared item produced: id=%v, quantity=%v", id, batch.Quantity)
+	Respond(w, http.StatusCreated, batch)
+}
+
+func (h *InventoryHandler) GetPrepBatches(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	batches, err := h.InvService.GetPrepBatches(ctx, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting batches: id=%v, error=%v", id, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+	Respond(w, http.StatusOK, batches)
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+	"frappuccino/pkg/json"
+)
+
+type KitchenHandler struct {
+	KitchenSvc service.KitchenService
+}
+
+func NewKitchenHandler(svc service.KitchenService) *KitchenHandler {
+	return &KitchenHandler{
+		KitchenSvc: svc,
+	}
+}
+
+func (h *KitchenHandler) GetQueue(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tickets, err := h.KitchenSvc.GetQueue(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting kitchen queue: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, tickets)
+}
+
+func (h *KitchenHandler) SetOrderStatus(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, func(ctx context.Context, update *models.KitchenUpdate) (*models.KitchenTicket, error) {
+		return h.KitchenSvc.SetOrderStatus(ctx, r.PathValue("id"), update)
+	})
+}
+
+func (h *KitchenHandler) SetLineStatus(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, func(ctx context.Context, update *models.KitchenUpdate) (*models.KitchenTicket, error) {
+		return h.KitchenSvc.SetLineStatus(ctx, r.PathValue("id"), r.PathValue("lineId"), update)
+	})
+}
+
+func (h *KitchenHandler) setStatus(w http.ResponseWriter, r *http.Request, set func(context.Context, *models.KitchenUpdate) (*models.KitchenTicket, error)) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	update, err := json.UnmarshalJson[*models.KitchenUpdate](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	ticket, err := set(ctx, update)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error updating kitchen status: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, ticket)
+}
+
+// Stream pushes the kitchen queue as Server-Sent Events: the whole queue as
+// a "queue" event on connect, then a "ticket" or "removed" event per change.
+func (h *KitchenHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		Respond(w, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+
+	// subscribe first so no change slips in between the queue and the events
+	events, unsubscribe := h.KitchenSvc.Subscribe()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	tickets, err := h.KitchenSvc.GetQueue(ctx)
+	cancel()
+	if err != nil {
+		slog.Error("Error getting kitchen queue: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	startEventStream(w)
+	if err := writeEvent(w, "", "queue", tickets); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// fell too far behind; the client reconnects for a fresh queue
+				return
+			}
+			if err := writeEvent(w, "", event.Type, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -2,8 +2,11 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"frappuccino/internal/models"
@@ -27,20 +30,58 @@ func (h *MenuHandler) GetAllMenus(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	listMenu, err := h.MenuSvc.GetAllMenus(ctx)
+	q := parseMenuQuery(r)
+	filter, err := parseMenuFilter(r)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			Respond(w, http.StatusRequestTimeout, "Request timed out")
+		Respond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q.MenuFilter = filter
+
+	if q.GroupBy != "" {
+		sections, err := h.MenuSvc.GetMenuByCategory(ctx, q)
+		if err != nil {
+			h.respondListError(ctx, w, err)
 			return
 		}
+		Respond(w, http.StatusOK, sections)
+		return
+	}
 
-		slog.Error("Failed to get all menu items: %s", err.Error())
-		Err := FromError(err)
-		Respond(w, Err.Status, "Failed to get all menu items")
+	listMenu, total, err := h.MenuSvc.GetAllMenus(ctx, q)
+	if err != nil {
+		h.respondListError(ctx, w, err)
 		return
 	}
 
-	Respond(w, http.StatusOK, listMenu)
+	if !q.Paged() {
+		Respond(w, http.StatusOK, listMenu)
+		return
+	}
+
+	totalPages := (total + q.PageSize - 1) / q.PageSize
+	Respond(w, http.StatusOK, &models.MenuPage{
+		Items:       listMenu,
+		CurrentPage: q.Page,
+		HasNextPage: q.Page < totalPages,
+		TotalPages:  totalPages,
+		TotalItems:  total,
+	})
+}
+
+func (h *MenuHandler) respondListError(ctx context.Context, w http.ResponseWriter, err error) {
+	if ctx.Err() == context.DeadlineExceeded {
+		Respond(w, http.StatusRequestTimeout, "Request timed out")
+		return
+	}
+
+	slog.Error("Failed to get all menu items: %s", err.Error())
+	Err := FromError(err)
+	if Err.Status == http.StatusBadRequest {
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+	Respond(w, Err.Status, "Failed to get all menu items")
 }
 
 func (h *MenuHandler) CreateMenu(w http.ResponseWriter, r *http.Request) {
@@ -86,7 +127,7 @@ func (h *MenuHandler) GetMenuByID(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	id := r.PathValue("id")
-	item, err := h.MenuSvc.GetMenuByID(ctx, id)
+	item, err := h.MenuSvc.GetMenuByID(ctx, id, parseMenuQuery(r))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusGatewayTimeout, "Request timed out")
@@ -146,18 +187,185 @@ func (h *MenuHandler) DeleteMenu(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	id := r.PathValue("id")
-	err := h.MenuSvc.DeleteMenu(ctx, id)
+	err := h.MenuSvc.ArchiveMenu(ctx, id)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusGatewayTimeout, "Request timed out")
 			return
 		}
 
-		slog.Error("Failed to delete menu item: %s", err.Error())
+		slog.Error("Failed to archive menu item: %s", err.Error())
 		Err := FromError(err)
-		Respond(w, Err.Status, "Failed to delete menu item")
+		Respond(w, Err.Status, Err.Message)
 		return
 	}
 
-	Respond(w, http.StatusOK, "Menu item deleted successfully")
+	Respond(w, http.StatusOK, "Menu item archived successfully")
+}
+
+func (h *MenuHandler) RestoreMenu(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	err := h.MenuSvc.RestoreMenu(ctx, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusGatewayTimeout, "Request timed out")
+			return
+		}
+
+		slog.Error("Failed to restore menu item: %s", err.Error())
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Menu item restored successfully")
+}
+
+func (h *MenuHandler) MarkSoldOut(w http.ResponseWriter, r *http.Request) {
+	req := &models.SoldOutRequest{}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	if len(data) > 0 {
+		req, err = json.UnmarshalJson[*models.SoldOutRequest](data)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "Failed to unmarshal JSON data")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	err = h.MenuSvc.MarkSoldOut(ctx, id, req.Until)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusGatewayTimeout, "Request timed out")
+			return
+		}
+
+		slog.Error("Failed to mark menu item as sold out: %s", err.Error())
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Menu item marked as sold out")
+}
+
+func (h *MenuHandler) ClearSoldOut(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	err := h.MenuSvc.ClearSoldOut(ctx, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusGatewayTimeout, "Request timed out")
+			return
+		}
+
+		slog.Error("Failed to clear sold out mark: %s", err.Error())
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Menu item is back on sale")
+}
+
+func (h *MenuHandler) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := r.PathValue("id")
+	history, err := h.MenuSvc.GetPriceHistory(ctx, id)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusGatewayTimeout, "Request timed out")
+			return
+		}
+
+		slog.Error("Failed to get price history: %s", err.Error())
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, history)
+}
+
+func parseMenuQuery(r *http.Request) *models.MenuQuery {
+	q := &models.MenuQuery{}
+	for _, part := range strings.Split(r.URL.Query().Get("include"), ",") {
+		switch strings.TrimSpace(part) {
+		case "cost":
+			q.IncludeCost = true
+		case "archived":
+			q.IncludeArchived = true
+		}
+	}
+	q.AvailableOnly = r.URL.Query().Get("available") == "true"
+	q.GroupBy = r.URL.Query().Get("group_by")
+	q.ExcludeAllergens = splitList(r.URL.Query().Get("exclude_allergens"))
+	q.Modifiers = splitList(r.URL.Query().Get("modifiers"))
+	return q
+}
+
+// parseMenuFilter reads the filters, sorting and paging of a menu listing:
+// category, tags (comma separated, tagMode=all to require every tag), size,
+// minPrice, maxPrice, q, sortBy, order=desc, page and pageSize.
+func parseMenuFilter(r *http.Request) (models.MenuFilter, error) {
+	query := r.URL.Query()
+	f := models.MenuFilter{
+		CategoryID: query.Get("category"),
+		AllTags:    query.Get("tagMode") == "all",
+		Size:       query.Get("size"),
+		Search:     strings.TrimSpace(query.Get("q")),
+		SortBy:     query.Get("sortBy"),
+		Desc:       query.Get("order") == "desc",
+	}
+
+	f.Tags = splitList(query.Get("tags"))
+
+	for name, dst := range map[string]**float64{"minPrice": &f.MinPrice, "maxPrice": &f.MaxPrice} {
+		if v := query.Get(name); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &price
+		}
+	}
+
+	for name, dst := range map[string]*int{"page": &f.Page, "pageSize": &f.PageSize} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				return f, fmt.Errorf("invalid %s", name)
+			}
+			*dst = n
+		}
+	}
+
+	return f, nil
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
 }
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+	"frappuccino/pkg/json"
+)
+
+type MenuVersionHandler struct {
+	MenuVersionSvc service.MenuVersionService
+}
+
+func NewMenuVersionHandler(svc service.MenuVersionService) *MenuVersionHandler {
+	return &MenuVersionHandler{
+		MenuVersionSvc: svc,
+	}
+}
+
+func (h *MenuVersionHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	version, err := json.UnmarshalJson[*models.MenuVersion](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.MenuVersionSvc.CreateVersion(ctx, version); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error creating menu version: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusCreated, version)
+}
+
+func (h *MenuVersionHandler) GetAllVersions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	versions, err := h.MenuVersionSvc.GetAllVersions(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting menu versions: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, versions)
+}
+
+func (h *MenuVersionHandler) GetVersionByID(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	version, err := h.MenuVersionSvc.GetVersionByID(ctx, r.PathValue("id"))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting menu version: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, version)
+}
+
+func (h *MenuVersionHandler) DeleteVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.MenuVersionSvc.DeleteVersion(ctx, r.PathValue("id")); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error deleting menu version: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Menu version deleted")
+}
+
+func (h *MenuVersionHandler) SaveEntry(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	entry, err := json.UnmarshalJson[*models.MenuVersionEntry](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.MenuVersionSvc.SaveEntry(ctx, r.PathValue("id"), entry); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error saving menu version entry: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusCreated, entry)
+}
+
+func (h *MenuVersionHandler) DeleteEntry(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.MenuVersionSvc.DeleteEntry(ctx, r.PathValue("id"), r.PathValue("entryId")); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error deleting menu version entry: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Entry deleted")
+}
+
+func (h *MenuVersionHandler) DiffVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	diff, err := h.MenuVersionSvc.DiffVersion(ctx, r.PathValue("id"))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error comparing menu version: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, diff)
+}
+
+func (h *MenuVersionHandler) PublishVersion(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	version, err := h.MenuVersionSvc.PublishVersion(ctx, r.PathValue("id"))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error publishing menu version: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, version)
+}
@@ -26,7 +26,8 @@ func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	listOrders, err := h.OrderSvc.GetAllOrders(ctx)
+	filter := models.OrderFilter{Channel: r.URL.Query().Get("channel")}
+	listOrders, err := h.OrderSvc.GetAllOrders(ctx, filter)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusRequestTimeout, "Request timeout")
@@ -264,7 +265,21 @@ func (h *OrderHandler) BatchProcessOrders(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	res, err := h.OrderSvc.BatchProcessOrders(ctx, batch.Purchases)
+	if batch.Async {
+		job, err := h.OrderSvc.SubmitBatch(batch)
+		if err != nil {
+			slog.Error("Error queuing batch: %v", err)
+			Err := FromError(err)
+			Respond(w, Err.Status, Err.Message)
+			return
+		}
+
+		slog.Info("Batch queued: job_id=%v, orders=%v", job.JobID, job.Total)
+		Respond(w, http.StatusAccepted, job)
+		return
+	}
+
+	res, err := h.OrderSvc.BatchProcessOrders(ctx, batch)
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusRequestTimeout, "Request timeout")
@@ -279,3 +294,147 @@ func (h *OrderHandler) BatchProcessOrders(w http.ResponseWriter, r *http.Request
 
 	Respond(w, http.StatusOK, res)
 }
+
+// AddLine adds a line to an open order; ?merge=true adds to the count of the
+// same line if the order has one.
+func (h *OrderHandler) AddLine(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		Respond(w, http.StatusBadRequest, "content type is not application/json")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	line, err := json.UnmarshalJson[*models.LineItem](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+	merge := r.URL.Query().Get("merge") == "true"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	order, err := h.OrderSvc.AddLine(ctx, r.PathValue("id"), line, merge)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error adding order line: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, order)
+}
+
+func (h *OrderHandler) UpdateLine(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		Respond(w, http.StatusBadRequest, "content type is not application/json")
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	patch, err := json.UnmarshalJson[*models.LinePatch](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	order, err := h.OrderSvc.UpdateLine(ctx, r.PathValue("id"), r.PathValue("line_id"), patch)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error updating order line: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, order)
+}
+
+func (h *OrderHandler) DeleteLine(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	order, err := h.OrderSvc.DeleteLine(ctx, r.PathValue("id"), r.PathValue("line_id"))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error deleting order line: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, order)
+}
+
+// GetBatchJob reports the progress of a batch queued with "async": true,
+// and its result once it is done.
+func (h *OrderHandler) GetBatchJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.OrderSvc.GetBatchJob(r.PathValue("job_id"))
+	if err != nil {
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, job)
+}
+
+// GetReservations lists the stock held by orders, oldest first. older_than
+// (a duration such as 30m) leaves out younger reservations.
+func (h *OrderHandler) GetReservations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var olderThan time.Duration
+	if v := r.URL.Query().Get("older_than"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "invalid older_than, must be a duration such as 30m")
+			return
+		}
+		olderThan = d
+	}
+
+	reservations, err := h.OrderSvc.GetReservations(ctx, olderThan)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting reservations: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, reservations)
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+	"frappuccino/pkg/json"
+)
+
+type PickupHandler struct {
+	PickupSvc service.PickupService
+}
+
+func NewPickupHandler(svc service.PickupService) *PickupHandler {
+	return &PickupHandler{
+		PickupSvc: svc,
+	}
+}
+
+func (h *PickupHandler) GetHours(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	hours, err := h.PickupSvc.GetHours(ctx)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting pickup hours: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, hours)
+}
+
+func (h *PickupHandler) SaveHours(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	hours, err := json.UnmarshalJson[*models.PickupHours](data)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, "Failed to unmarshal request body")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.PickupSvc.SaveHours(ctx, r.PathValue("day"), hours); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error saving pickup hours: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, hours)
+}
+
+func (h *PickupHandler) DeleteHours(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.PickupSvc.DeleteHours(ctx, r.PathValue("day")); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error deleting pickup hours: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, "Pickup hours deleted")
+}
+
+// GetSlots lists the pickup slots of ?date=2006-01-02, today by default.
+func (h *PickupHandler) GetSlots(w http.ResponseWriter, r *http.Request) {
+	date := time.Now()
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+			return
+		}
+		date = d
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	slots, err := h.PickupSvc.GetSlots(ctx, date)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting pickup slots: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, slots)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+)
+
+type ReceiptHandler struct {
+	ReceiptSvc service.ReceiptService
+}
+
+func NewReceiptHandler(svc service.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{
+		ReceiptSvc: svc,
+	}
+}
+
+var receiptContentTypes = map[string]string{
+	service.FormatText:   "text/plain; charset=utf-8",
+	service.FormatHTML:   "text/html; charset=utf-8",
+	service.FormatESCPOS: "application/octet-stream",
+	service.FormatJSON:   "application/json",
+}
+
+// GetReceipt prints an order: ?kind=receipt|kitchen, ?format=text|html|escpos|json
+// and ?paper=58|80, by default a text receipt for 80 mm paper.
+func (h *ReceiptHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	kind := query.Get("kind")
+	if kind == "" {
+		kind = models.ReceiptCustomer
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = service.FormatText
+	}
+	paper := models.Paper80mm
+	if v := query.Get("paper"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "paper must be 58 or 80")
+			return
+		}
+		paper = p
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	data, err := h.ReceiptSvc.GetReceipt(ctx, r.PathValue("id"), kind, format, paper)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error printing receipt: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	w.Header().Set("Content-Type", receiptContentTypes[format])
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+)
+
+type ReportHandler struct {
+	ReportSvc service.ReportService
+}
+
+func NewReportHandler(svc service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		ReportSvc: svc,
+	}
+}
+
+func (h *ReportHandler) GetInventoryValuation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	asOf := time.Now()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		parsed, err := time.Parse("02.01.2006", dateStr)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "invalid date format, must be DD.MM.YYYY")
+			return
+		}
+		// value the stock at the end of the requested day
+		asOf = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	method := r.URL.Query().Get("method")
+
+	valuation, err := h.ReportSvc.GetInventoryValuation(ctx, asOf, method)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting inventory valuation: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, valuation)
+}
+
+func (h *ReportHandler) GetMenuEngineering(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.ReportSvc.GetMenuEngineering(ctx, from, to)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting menu engineering report: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, report)
+}
+
+func (h *ReportHandler) GetProfitReport(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	from, to, err := parseDateRange(r, 30)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := h.ReportSvc.GetProfitReport(ctx, from, to, r.URL.Query().Get("group_by"))
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting profit report: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, report)
+}
+
+func (h *ReportHandler) GetPriceImpact(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	from, to, err := parseDateRange(r, 90)
+	if err != nil {
+		Respond(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var windowDays int
+	if windowStr := r.URL.Query().Get("window_days"); windowStr != "" {
+		windowDays, err = strconv.Atoi(windowStr)
+		if err != nil {
+			Respond(w, http.StatusBadRequest, "window_days must be a number")
+			return
+		}
+	}
+
+	report, err := h.ReportSvc.GetPriceImpact(ctx, from, to, windowDays)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error getting price impact report: %v", err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	Respond(w, http.StatusOK, report)
+}
+
+// parseDateRange reads the startDate and endDate query parameters
+// (DD.MM.YYYY). Both days are inclusive; when omitted the range covers the
+// last defaultDays days.
+func parseDateRange(r *http.Request, defaultDays int) (time.Time, time.Time, error) {
+	now := time.Now()
+	from := now.AddDate(0, 0, -defaultDays)
+	to := now
+
+	if startDateStr := r.URL.Query().Get("startDate"); startDateStr != "" {
+		parsed, err := time.Parse("02.01.2006", startDateStr)
+		if err != nil {
+			return from, to, errors.New("invalid startDate format, must be DD.MM.YYYY")
+		}
+		from = parsed
+	}
+
+	if endDateStr := r.URL.Query().Get("endDate"); endDateStr != "" {
+		parsed, err := time.Parse("02.01.2006", endDateStr)
+		if err != nil {
+			return from, to, errors.New("invalid endDate format, must be DD.MM.YYYY")
+		}
+		to = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return from, to, nil
+}
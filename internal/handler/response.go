@@ -3,7 +3,10 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"frappuccino/internal/models"
 )
@@ -40,8 +43,36 @@ func FromError(err error) Error {
 			apiError.Status = http.StatusBadRequest
 		case models.ErrNotFound:
 			apiError.Status = http.StatusNotFound
+		case models.ErrBusy:
+			apiError.Status = http.StatusServiceUnavailable
 		}
 	}
 
 	return apiError
 }
+
+// streamHeartbeat keeps idle event streams from being closed by proxies.
+const streamHeartbeat = 15 * time.Second
+
+func startEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeEvent writes one Server-Sent Event; the id line is left out when id
+// is empty.
+func writeEvent(w io.Writer, id, name string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
@@ -7,18 +7,40 @@ import (
 )
 
 type Handler struct {
-	InvHandler   *InventoryHandler
-	MenuHandler  *MenuHandler
-	OrderHandler *OrderHandler
-	StatsHandler *StatsHandler
+	InvHandler         *InventoryHandler
+	MenuHandler        *MenuHandler
+	OrderHandler       *OrderHandler
+	StatsHandler       *StatsHandler
+	StockTakeHandler   *StockTakeHandler
+	ReportHandler      *ReportHandler
+	PricingHandler     *PricingHandler
+	CategoryHandler    *CategoryHandler
+	MenuVersionHandler *MenuVersionHandler
+	TransferHandler    *TransferHandler
+	KitchenHandler     *KitchenHandler
+	EventsHandler      *EventsHandler
+	WebhookHandler     *WebhookHandler
+	PickupHandler      *PickupHandler
+	ReceiptHandler     *ReceiptHandler
 }
 
-func NewHandler(invSvc service.InventoryService, menuSvc service.MenuService, orderSvc service.OrderService, statsSvc service.StatsService) *Handler {
+func NewHandler(invSvc service.InventoryService, menuSvc service.MenuService, orderSvc service.OrderService, statsSvc service.StatsService, stockTakeSvc service.StockTakeService, reportSvc service.ReportService, pricingSvc service.PricingService, categorySvc service.CategoryService, menuVersionSvc service.MenuVersionService, transferSvc service.TransferService, kitchenSvc service.KitchenService, orderEvents *service.OrderEventBus, webhookSvc service.WebhookService, pickupSvc service.PickupService, receiptSvc service.ReceiptService) *Handler {
 	return &Handler{
-		InvHandler:   NewInventoryHandler(invSvc),
-		MenuHandler:  NewMenuHandler(menuSvc),
-		OrderHandler: NewOrderHandler(orderSvc),
-		StatsHandler: NewStatsHandler(statsSvc),
+		InvHandler:         NewInventoryHandler(invSvc),
+		MenuHandler:        NewMenuHandler(menuSvc),
+		OrderHandler:       NewOrderHandler(orderSvc),
+		StatsHandler:       NewStatsHandler(statsSvc),
+		StockTakeHandler:   NewStockTakeHandler(stockTakeSvc),
+		ReportHandler:      NewReportHandler(reportSvc),
+		PricingHandler:     NewPricingHandler(pricingSvc),
+		CategoryHandler:    NewCategoryHandler(categorySvc),
+		MenuVersionHandler: NewMenuVersionHandler(menuVersionSvc),
+		TransferHandler:    NewTransferHandler(transferSvc),
+		KitchenHandler:     NewKitchenHandler(kitchenSvc),
+		EventsHandler:      NewEventsHandler(orderEvents),
+		WebhookHandler:     NewWebhookHandler(webhookSvc),
+		PickupHandler:      NewPickupHandler(pickupSvc),
+		ReceiptHandler:     NewReceiptHandler(receiptSvc),
 	}
 }
 
@@ -30,13 +52,56 @@ func (h *Handler) newRouter() *http.ServeMux {
 	router.HandleFunc("GET /inventory/{id}", h.InvHandler.GetInventoryItemId)
 	router.HandleFunc("PUT /inventory/{id}", h.InvHandler.UpdateInventoryItem)
 	router.HandleFunc("DELETE /inventory/{id}", h.InvHandler.DeleteInventoryItem)
+	router.HandleFunc("POST /inventory/{id}/restore", h.InvHandler.RestoreInventoryItem)
 	router.HandleFunc("GET /inventory/list", h.InvHandler.GetInventoryList)
+	router.HandleFunc("POST /inventory/import", h.TransferHandler.ImportInventory)
+	router.HandleFunc("GET /inventory/export", h.TransferHandler.ExportInventory)
+	router.HandleFunc("POST /inventory/{id}/receipts", h.InvHandler.ReceiveStock)
+	router.HandleFunc("GET /inventory/{id}/cost-history", h.InvHandler.GetCostHistory)
+	router.HandleFunc("POST /inventory/{id}/waste", h.InvHandler.RecordWaste)
+	router.HandleFunc("POST /inventory/{id}/produce", h.InvHandler.Produce)
+	router.HandleFunc("GET /inventory/{id}/batches", h.InvHandler.GetPrepBatches)
+
+	router.HandleFunc("POST /stocktakes", h.StockTakeHandler.StartStockTake)
+	router.HandleFunc("GET /stocktakes/{id}", h.StockTakeHandler.GetStockTake)
+	router.HandleFunc("POST /stocktakes/{id}/counts", h.StockTakeHandler.SubmitCounts)
+	router.HandleFunc("POST /stocktakes/{id}/commit", h.StockTakeHandler.CommitStockTake)
+	router.HandleFunc("POST /stocktakes/{id}/cancel", h.StockTakeHandler.CancelStockTake)
+	router.HandleFunc("GET /stocktakes/{id}/report", h.StockTakeHandler.GetVarianceReport)
 
 	router.HandleFunc("GET /menu", h.MenuHandler.GetAllMenus)
 	router.HandleFunc("POST /menu", h.MenuHandler.CreateMenu)
 	router.HandleFunc("GET /menu/{id}", h.MenuHandler.GetMenuByID)
+	router.HandleFunc("POST /menu/import", h.TransferHandler.ImportMenu)
+	router.HandleFunc("GET /menu/export", h.TransferHandler.ExportMenu)
 	router.HandleFunc("PUT /menu/{id}", h.MenuHandler.UpdateMenu)
 	router.HandleFunc("DELETE /menu/{id}", h.MenuHandler.DeleteMenu)
+	router.HandleFunc("POST /menu/{id}/restore", h.MenuHandler.RestoreMenu)
+	router.HandleFunc("POST /menu/{id}/sold-out", h.MenuHandler.MarkSoldOut)
+	router.HandleFunc("DELETE /menu/{id}/sold-out", h.MenuHandler.ClearSoldOut)
+	router.HandleFunc("GET /menu/{id}/price-history", h.MenuHandler.GetPriceHistory)
+	router.HandleFunc("POST /menu/{id}/price-changes", h.PricingHandler.SchedulePriceChange)
+	router.HandleFunc("GET /menu/{id}/price-changes", h.PricingHandler.GetScheduledChanges)
+	router.HandleFunc("DELETE /menu/{id}/price-changes/{changeId}", h.PricingHandler.CancelScheduledChange)
+
+	router.HandleFunc("GET /categories", h.CategoryHandler.GetAllCategories)
+	router.HandleFunc("POST /categories", h.CategoryHandler.CreateCategory)
+	router.HandleFunc("GET /categories/{id}", h.CategoryHandler.GetCategoryByID)
+	router.HandleFunc("PUT /categories/{id}", h.CategoryHandler.UpdateCategory)
+	router.HandleFunc("DELETE /categories/{id}", h.CategoryHandler.DeleteCategory)
+
+	router.HandleFunc("GET /menu-versions", h.MenuVersionHandler.GetAllVersions)
+	router.HandleFunc("POST /menu-versions", h.MenuVersionHandler.CreateVersion)
+	router.HandleFunc("GET /menu-versions/{id}", h.MenuVersionHandler.GetVersionByID)
+	router.HandleFunc("DELETE /menu-versions/{id}", h.MenuVersionHandler.DeleteVersion)
+	router.HandleFunc("POST /menu-versions/{id}/items", h.MenuVersionHandler.SaveEntry)
+	router.HandleFunc("DELETE /menu-versions/{id}/items/{entryId}", h.MenuVersionHandler.DeleteEntry)
+	router.HandleFunc("GET /menu-versions/{id}/diff", h.MenuVersionHandler.DiffVersion)
+	router.HandleFunc("POST /menu-versions/{id}/publish", h.MenuVersionHandler.PublishVersion)
+
+	router.HandleFunc("GET /price-windows", h.PricingHandler.GetWindows)
+	router.HandleFunc("POST /price-windows", h.PricingHandler.CreateWindow)
+	router.HandleFunc("DELETE /price-windows/{id}", h.PricingHandler.DeleteWindow)
 
 	router.HandleFunc("GET /orders", h.OrderHandler.GetAllOrders)
 	router.HandleFunc("POST /orders", h.OrderHandler.CreateOrder)
@@ -45,12 +110,57 @@ func (h *Handler) newRouter() *http.ServeMux {
 	router.HandleFunc("PUT /orders/{id}", h.OrderHandler.UpdateOrder)
 	router.HandleFunc("DELETE /orders/{id}", h.OrderHandler.DeleteOrder)
 	router.HandleFunc("POST /orders/{id}/close", h.OrderHandler.CloseOrder)
+	router.HandleFunc("GET /orders/{id}/{resource}", h.orderResource)
+	router.HandleFunc("POST /orders/{id}/items", h.OrderHandler.AddLine)
+	router.HandleFunc("PATCH /orders/{id}/items/{line_id}", h.OrderHandler.UpdateLine)
+	router.HandleFunc("DELETE /orders/{id}/items/{line_id}", h.OrderHandler.DeleteLine)
 	router.HandleFunc("GET /orders/number", h.OrderHandler.GetNumberOfOrderedItems)
+	router.HandleFunc("GET /reservations", h.OrderHandler.GetReservations)
+
+	router.HandleFunc("GET /pickup-hours", h.PickupHandler.GetHours)
+	router.HandleFunc("PUT /pickup-hours/{day}", h.PickupHandler.SaveHours)
+	router.HandleFunc("DELETE /pickup-hours/{day}", h.PickupHandler.DeleteHours)
+	router.HandleFunc("GET /pickup-slots", h.PickupHandler.GetSlots)
+
+	router.HandleFunc("GET /kds/queue", h.KitchenHandler.GetQueue)
+	router.HandleFunc("GET /kds/stream", h.KitchenHandler.Stream)
+	router.HandleFunc("POST /kds/orders/{id}/status", h.KitchenHandler.SetOrderStatus)
+	router.HandleFunc("POST /kds/orders/{id}/lines/{lineId}/status", h.KitchenHandler.SetLineStatus)
+
+	router.HandleFunc("GET /events/orders", h.EventsHandler.StreamOrders)
+
+	router.HandleFunc("GET /webhooks", h.WebhookHandler.GetAllWebhooks)
+	router.HandleFunc("POST /webhooks", h.WebhookHandler.CreateWebhook)
+	router.HandleFunc("GET /webhooks/{id}", h.WebhookHandler.GetWebhookByID)
+	router.HandleFunc("PUT /webhooks/{id}", h.WebhookHandler.UpdateWebhook)
+	router.HandleFunc("DELETE /webhooks/{id}", h.WebhookHandler.DeleteWebhook)
+	router.HandleFunc("GET /webhooks/{id}/deliveries", h.WebhookHandler.GetDeliveries)
+	router.HandleFunc("POST /webhooks/{id}/deliveries/{deliveryId}/retry", h.WebhookHandler.RetryDelivery)
 
 	router.HandleFunc("GET /stats/total-sales", h.StatsHandler.GetTotalSum)
 	router.HandleFunc("GET /stats/popular-items", h.StatsHandler.GetPopularItem)
 	router.HandleFunc("GET /stats/search", h.StatsHandler.GetSearch)
 	router.HandleFunc("GET /stats/orderedItemsByPeriod", h.StatsHandler.GetItemByPeriod)
 
+	router.HandleFunc("GET /reports/inventory-valuation", h.ReportHandler.GetInventoryValuation)
+	router.HandleFunc("GET /reports/menu-engineering", h.ReportHandler.GetMenuEngineering)
+	router.HandleFunc("GET /reports/profit", h.ReportHandler.GetProfitReport)
+	router.HandleFunc("GET /reports/price-impact", h.ReportHandler.GetPriceImpact)
+
 	return router
 }
+
+// orderResource serves GET /orders/batch/{job_id} and GET /orders/{id}/receipt.
+// ServeMux refuses the two as separate patterns, since both would match
+// /orders/batch/receipt.
+func (h *Handler) orderResource(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.PathValue("id") == "batch":
+		r.SetPathValue("job_id", r.PathValue("resource"))
+		h.OrderHandler.GetBatchJob(w, r)
+	case r.PathValue("resource") == "receipt":
+		h.ReceiptHandler.GetReceipt(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -23,7 +23,7 @@ func (t *StatsHandler) GetPopularItem(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	data, err := t.StatsSvc.GetPopularItem(ctx)
+	data, err := t.StatsSvc.GetPopularItem(ctx, r.URL.Query().Get("channel"))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusRequestTimeout, "Request timeout")
@@ -41,7 +41,7 @@ func (t *StatsHandler) GetTotalSum(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	data, err := t.StatsSvc.GetTotalSum(ctx)
+	data, err := t.StatsSvc.GetTotalSum(ctx, r.URL.Query().Get("channel"))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusRequestTimeout, "Request timeout")
@@ -80,7 +80,7 @@ func (t *StatsHandler) GetSearch(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	results, err := t.StatsSvc.GetSearch(ctx, query, filter, minPrice, maxPrice)
+	results, err := t.StatsSvc.GetSearch(ctx, query, filter, minPrice, maxPrice, r.URL.Query().Get("channel"))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusRequestTimeout, "Request timeout")
@@ -112,7 +112,7 @@ func (t *StatsHandler) GetItemByPeriod(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	list, err := t.StatsSvc.GetItemByPeriod(ctx, period, month, year)
+	list, err := t.StatsSvc.GetItemByPeriod(ctx, period, month, year, r.URL.Query().Get("channel"))
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			Respond(w, http.StatusRequestTimeout, "Request timeout")
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/service"
+	"frappuccino/internal/slog"
+)
+
+type TransferHandler struct {
+	TransferSvc service.TransferService
+}
+
+func NewTransferHandler(svc service.TransferService) *TransferHandler {
+	return &TransferHandler{
+		TransferSvc: svc,
+	}
+}
+
+type importFunc func(ctx context.Context, format string, data []byte, dryRun bool) (*models.ImportReport, error)
+
+type exportFunc func(ctx context.Context, format string) ([]byte, error)
+
+func (h *TransferHandler) ImportInventory(w http.ResponseWriter, r *http.Request) {
+	h.importFile(w, r, "inventory", h.TransferSvc.ImportInventory)
+}
+
+func (h *TransferHandler) ExportInventory(w http.ResponseWriter, r *http.Request) {
+	h.exportFile(w, r, "inventory", h.TransferSvc.ExportInventory)
+}
+
+func (h *TransferHandler) ImportMenu(w http.ResponseWriter, r *http.Request) {
+	h.importFile(w, r, "menu", h.TransferSvc.ImportMenu)
+}
+
+func (h *TransferHandler) ExportMenu(w http.ResponseWriter, r *http.Request) {
+	h.exportFile(w, r, "menu", h.TransferSvc.ExportMenu)
+}
+
+// importFile reads a CSV or JSON body; the format comes from ?format= or the
+// Content-Type. A report with failed rows is answered with 400.
+func (h *TransferHandler) importFile(w http.ResponseWriter, r *http.Request, what string, run importFunc) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		Respond(w, http.StatusInternalServerError, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.FormatJSON
+		if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+			format = service.FormatCSV
+		}
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	// large files are written row by row
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	report, err := run(ctx, format, data, dryRun)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error importing %s: %v", what, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	if report.Failed > 0 {
+		Respond(w, http.StatusBadRequest, report)
+		return
+	}
+	Respond(w, http.StatusOK, report)
+}
+
+func (h *TransferHandler) exportFile(w http.ResponseWriter, r *http.Request, what string, run exportFunc) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = service.FormatJSON
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	data, err := run(ctx, format)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			Respond(w, http.StatusRequestTimeout, "Request timeout")
+			return
+		}
+
+		slog.Error("Error exporting %s: %v", what, err)
+		Err := FromError(err)
+		Respond(w, Err.Status, Err.Message)
+		return
+	}
+
+	contentType := "application/json"
+	if format == service.FormatCSV {
+		contentType = "text/csv"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+what+`.`+format+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"strings"
+)
+
+type Category struct {
+	CategoryID   string  `json:"category_id"`
+	Name         string  `json:"name"`
+	ParentID     *string `json:"parent_id,omitempty"`
+	DisplayOrder int     `json:"display_order"`
+	Active       *bool   `json:"active"`
+}
+
+// MenuSection is a category of the menu with its items and subcategories,
+// both in display order.
+type MenuSection struct {
+	CategoryID   string         `json:"category_id"`
+	Name         string         `json:"name"`
+	DisplayOrder int            `json:"display_order"`
+	Items        []*Product     `json:"items"`
+	Sections     []*MenuSection `json:"sections,omitempty"`
+}
+
+// Validate checks the category and defaults it to active.
+func (c *Category) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("category name is required")
+	}
+	if len(c.Name) > 50 {
+		return errors.New("category name is too long")
+	}
+	if c.ParentID != nil && *c.ParentID == c.CategoryID {
+		return errors.New("category cannot be its own parent")
+	}
+	if c.Active == nil {
+		active := true
+		c.Active = &active
+	}
+	return nil
+}
+
+// IsActive reports whether items of the category are shown on the menu.
+func (c *Category) IsActive() bool {
+	return c.Active == nil || *c.Active
+}
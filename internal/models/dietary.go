@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Allergens lists the allergen flags an inventory item can carry: the
+// fourteen allergens menus have to declare.
+var Allergens = []string{
+	"celery", "crustaceans", "dairy", "eggs", "fish", "gluten", "lupin",
+	"molluscs", "mustard", "nuts", "peanuts", "sesame", "soy", "sulphites",
+}
+
+// Nutrition holds energy (kcal) and macronutrients (g). On an inventory item
+// the values are per unit of its measure, on a menu item per portion.
+type Nutrition struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+}
+
+func (n *Nutrition) Validate() error {
+	if n.Calories < 0 || n.Protein < 0 || n.Fat < 0 || n.Carbs < 0 {
+		return errors.New("nutrition values must not be negative")
+	}
+	return nil
+}
+
+// Add adds qty units of other to n.
+func (n *Nutrition) Add(other *Nutrition, qty float64) {
+	n.Calories += other.Calories * qty
+	n.Protein += other.Protein * qty
+	n.Fat += other.Fat * qty
+	n.Carbs += other.Carbs * qty
+}
+
+func (n Nutrition) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *Nutrition) Scan(src interface{}) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return errors.New("failed to scan Nutrition: expected []byte")
+	}
+	return json.Unmarshal(data, n)
+}
+
+// NormalizeAllergens lowercases, sorts and deduplicates the flags and rejects
+// unknown ones.
+func NormalizeAllergens(flags []string) ([]string, error) {
+	normalized := []string{}
+	for _, flag := range flags {
+		flag = strings.ToLower(strings.TrimSpace(flag))
+		if !slices.Contains(Allergens, flag) {
+			return nil, errors.New("unknown allergen " + flag + " (expected: " + strings.Join(Allergens, ", ") + ")")
+		}
+		if !slices.Contains(normalized, flag) {
+			normalized = append(normalized, flag)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
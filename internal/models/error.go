@@ -7,7 +7,9 @@ var (
 	ErrElemExist             = errors.New("element already exists")
 	ErrInvalidInput          = errors.New("invalid input")
 	ErrInternal              = errors.New("internal error")
+	ErrBusy                  = errors.New("busy")
 	ErrInventoryNotAvailable = errors.New("inventory not available")
+	ErrCountsSpanMovement    = errors.New("counts of the item were taken before and after a stock movement")
 )
 
 type Error struct {
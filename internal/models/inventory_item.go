@@ -3,6 +3,9 @@ package models
 import (
 	"errors"
 	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type InventoryItem struct {
@@ -11,31 +14,162 @@ type InventoryItem struct {
 	Stock        float64 `json:"stock"`
 	Measure      string  `json:"measure"`
 	UnitCost     float64 `json:"unit_cost"`
+
+	// Allergens and nutrition per unit of Measure. A prepared item inherits
+	// both from its recipe unless set on the item itself.
+	Allergens pq.StringArray `json:"allergens"`
+	Nutrition *Nutrition     `json:"nutrition,omitempty"`
+
+	// Prepared items (cold brew, syrups) are made in house from a recipe
+	// of other inventory items and yield a batch of Yield units.
+	Prepared       bool                `json:"prepared"`
+	Yield          *float64            `json:"yield,omitempty"`
+	ShelfLifeHours *int                `json:"shelf_life_hours,omitempty"`
+	Recipe         []*ProductComponent `json:"recipe,omitempty"`
+	MaxProducible  *float64            `json:"max_producible,omitempty"`
+
+	// ReorderLevel is the stock at or below which an inventory.low_stock
+	// event is sent.
+	ReorderLevel *float64 `json:"reorder_level,omitempty"`
+
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+type ProductionRequest struct {
+	Batches float64 `json:"batches"`
+}
+
+type PrepBatch struct {
+	BatchID   string     `json:"batch_id"`
+	ItemRef   string     `json:"inventory_id"`
+	Quantity  float64    `json:"quantity"`
+	UnitCost  float64    `json:"unit_cost"`
+	Produced  time.Time  `json:"produced"`
+	Expires   *time.Time `json:"expires,omitempty"`
+	IsExpired bool       `json:"expired"`
+}
+
+// RecipeNode is one vertex of the recipe graph: a raw ingredient, or a
+// prepared item together with the ingredients of one batch.
+type RecipeNode struct {
+	ItemRef   string
+	Title     string
+	Prepared  bool
+	Yield     float64
+	UnitCost  float64
+	Available float64
+	Allergens []string
+	Nutrition *Nutrition
+	Archived  bool
+	Recipe    []*ProductComponent
 }
 
 type InventoryTransaction struct {
-	ID         string  `json:"transaction_id"`
-	ItemRef    string  `json:"inventory_ref"`
-	Delta      float64 `json:"delta"`
-	Type       string  `json:"type"`
-	OccurredAt string  `json:"occurred_at"`
+	ID         string   `json:"transaction_id"`
+	ItemRef    string   `json:"inventory_ref"`
+	Delta      float64  `json:"delta"`
+	Type       string   `json:"type"`
+	UnitCost   *float64 `json:"unit_cost,omitempty"`
+	OccurredAt string   `json:"occurred_at"`
+}
+
+type WasteRecord struct {
+	Quantity float64 `json:"quantity"`
+}
+
+type StockReceipt struct {
+	Quantity float64 `json:"quantity"`
+	UnitCost float64 `json:"unit_cost"`
 }
 
 func (inv *InventoryItem) Validate() error {
 	if strings.TrimSpace(inv.Title) == "" {
 		return errors.New("inventory item title is required")
 	}
-	if inv.Stock <= 0 {
-		return errors.New("stock must be greater than zero")
-	}
-	if inv.UnitCost <= 0 {
-		return errors.New("unit cost must be greater than zero")
+	if inv.Prepared {
+		if err := inv.validateRecipe(); err != nil {
+			return err
+		}
+	} else {
+		if inv.Stock <= 0 {
+			return errors.New("stock must be greater than zero")
+		}
+		if inv.UnitCost <= 0 {
+			return errors.New("unit cost must be greater than zero")
+		}
 	}
 
 	validMeasures := map[string]bool{"kg": true, "l": true, "pcs": true}
 	if !validMeasures[inv.Measure] {
 		return errors.New("invalid measurement unit (expected: kg, l, pcs)")
 	}
+	if inv.ReorderLevel != nil && *inv.ReorderLevel < 0 {
+		return errors.New("reorder level cannot be negative")
+	}
 
+	allergens, err := NormalizeAllergens(inv.Allergens)
+	if err != nil {
+		return err
+	}
+	inv.Allergens = allergens
+	if inv.Nutrition != nil {
+		if err := inv.Nutrition.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (rec *StockReceipt) Validate() error {
+	if rec.Quantity <= 0 {
+		return errors.New("received quantity must be greater than zero")
+	}
+	if rec.UnitCost <= 0 {
+		return errors.New("unit cost must be greater than zero")
+	}
+	return nil
+}
+
+func (w *WasteRecord) Validate() error {
+	if w.Quantity <= 0 {
+		return errors.New("wasted quantity must be greater than zero")
+	}
+	return nil
+}
+
+// validateRecipe checks a prepared item. Its stock may start at zero and its
+// cost is derived from the recipe, so neither is required.
+func (inv *InventoryItem) validateRecipe() error {
+	if inv.Stock < 0 {
+		return errors.New("stock cannot be negative")
+	}
+	if inv.Yield == nil || *inv.Yield <= 0 {
+		return errors.New("yield must be greater than zero")
+	}
+	if inv.ShelfLifeHours != nil && *inv.ShelfLifeHours <= 0 {
+		return errors.New("shelf life must be greater than zero")
+	}
+	if len(inv.Recipe) == 0 {
+		return errors.New("recipe cannot be empty")
+	}
+	for _, line := range inv.Recipe {
+		if strings.TrimSpace(line.ComponentID) == "" {
+			return errors.New("recipe component id is required")
+		}
+		if line.ComponentID == inv.IngredientID {
+			return errors.New("prepared item cannot be part of its own recipe")
+		}
+		if line.RequiredQty <= 0 {
+			return errors.New("recipe quantity must be greater than 0")
+		}
+	}
+	return nil
+}
+
+func (p *ProductionRequest) Validate() error {
+	if p.Batches <= 0 {
+		return errors.New("batches must be greater than zero")
+	}
 	return nil
 }
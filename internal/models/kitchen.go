@@ -0,0 +1,80 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Kitchen states of an order line. An order is in_progress once any of its
+// lines is started and ready once all of them are.
+const (
+	KitchenQueued     = "queued"
+	KitchenInProgress = "in_progress"
+	KitchenReady      = "ready"
+)
+
+// KitchenOrderStatus derives the status of an order from the kitchen status
+// of its lines: the counts of all lines, of started lines (in progress or
+// ready) and of ready lines.
+func KitchenOrderStatus(total, started, ready int) string {
+	switch {
+	case total > 0 && ready == total:
+		return "ready"
+	case started > 0:
+		return "in_progress"
+	default:
+		return "open"
+	}
+}
+
+// KitchenTicket is an order on the kitchen display, with the lines to make.
+// Bundles are expanded into the items chosen for their slots. WaitSeconds
+// counts from when the order joined the queue, which for an order placed
+// ahead is some time before its pickup.
+type KitchenTicket struct {
+	OrderID     string         `json:"order_id"`
+	CustomerID  string         `json:"customer_id"`
+	Status      string         `json:"status"`
+	Channel     string         `json:"channel"`
+	TableNumber *int           `json:"table_number,omitempty"`
+	PickupAt    *time.Time     `json:"pickup_at,omitempty"`
+	PlacedAt    time.Time      `json:"placed_at"`
+	WaitSeconds int            `json:"wait_seconds"`
+	Lines       []*KitchenLine `json:"lines"`
+}
+
+type KitchenLine struct {
+	LineID      string     `json:"line_id"`
+	ItemID      string     `json:"item_id"`
+	Title       string     `json:"title"`
+	Count       int        `json:"count"`
+	Adjustments ConfigMap  `json:"adjustments,omitempty"`
+	Bundle      string     `json:"bundle,omitempty"`
+	Status      string     `json:"status"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+type KitchenUpdate struct {
+	Status string `json:"status"`
+}
+
+func (u *KitchenUpdate) Validate() error {
+	switch u.Status {
+	case KitchenQueued, KitchenInProgress, KitchenReady:
+		return nil
+	}
+	return errors.New("status must be queued, in_progress or ready")
+}
+
+// KitchenEvent is pushed to kitchen displays when a ticket changes. A ticket
+// that left the queue (completed, canceled or deleted) is sent as removed.
+type KitchenEvent struct {
+	Type    string         `json:"type"`
+	OrderID string         `json:"order_id"`
+	Ticket  *KitchenTicket `json:"ticket,omitempty"`
+}
+
+const (
+	KitchenEventTicket  = "ticket"
+	KitchenEventRemoved = "removed"
+)
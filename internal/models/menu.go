@@ -4,7 +4,9 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/lib/pq"
 )
@@ -15,10 +17,45 @@ type Product struct {
 	Details    string              `json:"details"`
 	UnitPrice  float64             `json:"unit_price"`
 	SizeLabel  string              `json:"size_label"`
+	CategoryID string              `json:"category_id"`
 	Group      string              `json:"group"`
 	Labels     pq.StringArray      `json:"labels"`
 	Extras     ExtrasMap           `json:"extras"`
 	Components []*ProductComponent `json:"components"`
+	Modifiers  []*Modifier         `json:"modifiers,omitempty"`
+	Cost       *float64            `json:"cost,omitempty"`
+	Margin     *float64            `json:"margin,omitempty"`
+	MarginPct  *float64            `json:"margin_pct,omitempty"`
+
+	// Derived from the components and the selected modifiers; a bundle
+	// declares the allergens of every item its slots offer and has no
+	// nutrition until it is filled.
+	Allergens []string   `json:"allergens"`
+	Nutrition *Nutrition `json:"nutrition,omitempty"`
+
+	MaxMakeable  int        `json:"max_makeable"`
+	Available    bool       `json:"available"`
+	SoldOutUntil *time.Time `json:"sold_out_until,omitempty"`
+
+	// A bundle has no recipe of its own: it is sold at UnitPrice as a set of
+	// slots, each filled with one of the slot options.
+	Bundle bool          `json:"bundle"`
+	Slots  []*BundleSlot `json:"slots,omitempty"`
+
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+type BundleSlot struct {
+	SlotID   string          `json:"slot_id"`
+	Name     string          `json:"name"`
+	Quantity int             `json:"quantity"`
+	Options  []*BundleOption `json:"options"`
+}
+
+// BundleOption offers either a single menu item or every item of a category.
+type BundleOption struct {
+	ItemID     *string `json:"item_id,omitempty"`
+	CategoryID *string `json:"category_id,omitempty"`
 }
 
 type ProductComponent struct {
@@ -26,6 +63,145 @@ type ProductComponent struct {
 	ComponentName string   `json:"component_name"`
 	RequiredQty   float64  `json:"required_qty"`
 	InStock       *float64 `json:"in_stock,omitempty"`
+	UnitCost      float64  `json:"-"`
+	Available     float64  `json:"-"`
+}
+
+// Modifier is an option a customer can pick for a menu item ("oat milk",
+// "extra shot"). It adds Quantity of an inventory item to a portion and may
+// take the place of one of the components. Allergens and Nutrition are those
+// of what it adds.
+type Modifier struct {
+	Name          string     `json:"name"`
+	ComponentID   string     `json:"component_id"`
+	ComponentName string     `json:"component_name"`
+	Quantity      float64    `json:"quantity"`
+	ReplacesID    *string    `json:"replaces_id,omitempty"`
+	Allergens     []string   `json:"allergens"`
+	Nutrition     *Nutrition `json:"nutrition,omitempty"`
+	Selected      bool       `json:"selected,omitempty"`
+}
+
+// Modifier returns the modifier of the item with the given name, ignoring
+// case, or nil.
+func (p *Product) Modifier(name string) *Modifier {
+	for _, m := range p.Modifiers {
+		if strings.EqualFold(m.Name, strings.TrimSpace(name)) {
+			return m
+		}
+	}
+	return nil
+}
+
+// MenuQuery holds the optional parts of a menu listing requested by the client.
+type MenuQuery struct {
+	MenuFilter
+	IncludeCost      bool
+	AvailableOnly    bool
+	ExcludeAllergens []string
+	GroupBy          string
+	// Modifiers names the modifiers whose allergens and nutrition are added
+	// to the items offering them.
+	Modifiers []string
+}
+
+// Validate checks the query and normalizes the excluded allergens.
+func (q *MenuQuery) Validate() error {
+	if err := q.MenuFilter.Validate(); err != nil {
+		return err
+	}
+	allergens, err := NormalizeAllergens(q.ExcludeAllergens)
+	if err != nil {
+		return err
+	}
+	q.ExcludeAllergens = allergens
+	return nil
+}
+
+// FiltersComputed reports whether the query filters on values computed after
+// loading (availability, allergens), so paging has to follow the filtering.
+func (q *MenuQuery) FiltersComputed() bool {
+	return q.AvailableOnly || len(q.ExcludeAllergens) > 0
+}
+
+// MenuFilter narrows, orders and pages a menu listing. Zero values leave the
+// listing untouched; paging starts once a page or a page size is set.
+type MenuFilter struct {
+	IncludeArchived bool
+	CategoryID      string
+	Tags            []string
+	AllTags         bool
+	Size            string
+	MinPrice        *float64
+	MaxPrice        *float64
+	Search          string
+	SortBy          string
+	Desc            bool
+	Page            int
+	PageSize        int
+}
+
+// MenuPage is one page of a paged menu listing.
+type MenuPage struct {
+	Items       []*Product `json:"items"`
+	CurrentPage int        `json:"currentPage"`
+	HasNextPage bool       `json:"hasNextPage"`
+	TotalPages  int        `json:"totalPages"`
+	TotalItems  int        `json:"totalItems"`
+}
+
+// MenuSortKeys lists the fields a menu listing can be sorted by.
+var MenuSortKeys = []string{"id", "title", "price", "category"}
+
+var menuSizes = []string{"small", "medium", "large", "extra_large"}
+
+// Validate checks the filter and fills in the paging defaults.
+func (f *MenuFilter) Validate() error {
+	if f.Size != "" && !slices.Contains(menuSizes, f.Size) {
+		return errors.New("size must be one of " + strings.Join(menuSizes, ", "))
+	}
+	if f.MinPrice != nil && *f.MinPrice < 0 || f.MaxPrice != nil && *f.MaxPrice < 0 {
+		return errors.New("price bounds must not be negative")
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return errors.New("minPrice must not exceed maxPrice")
+	}
+	if f.SortBy != "" && !slices.Contains(MenuSortKeys, f.SortBy) {
+		return errors.New("sortBy must be one of " + strings.Join(MenuSortKeys, ", "))
+	}
+	if f.Page < 0 {
+		return errors.New("page must be greater than 0")
+	}
+	if f.PageSize < 0 || f.PageSize > 100 {
+		return errors.New("pageSize must be between 1 and 100")
+	}
+	if f.Page > 0 && f.PageSize == 0 {
+		f.PageSize = 10
+	}
+	if f.PageSize > 0 && f.Page == 0 {
+		f.Page = 1
+	}
+	return nil
+}
+
+// Paged reports whether the listing is split into pages.
+func (f *MenuFilter) Paged() bool {
+	return f.PageSize > 0
+}
+
+// Offset is the number of items before the requested page.
+func (f *MenuFilter) Offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+type SoldOutRequest struct {
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// IsSoldOut reports whether the item was manually marked as sold out and the
+// mark has not expired yet.
+func (p *Product) IsSoldOut(now time.Time) bool {
+	return p.SoldOutUntil != nil && now.Before(*p.SoldOutUntil)
 }
 
 func (p *Product) CheckRequiredFields() error {
@@ -35,8 +211,14 @@ func (p *Product) CheckRequiredFields() error {
 	if p.UnitPrice <= 0 {
 		return errors.New("unit price must be greater than 0")
 	}
-	if strings.TrimSpace(p.Group) == "" {
-		return errors.New("product group is required")
+	if strings.TrimSpace(p.CategoryID) == "" && strings.TrimSpace(p.Group) == "" {
+		return errors.New("product category is required")
+	}
+	if p.SizeLabel != "" && !slices.Contains(menuSizes, p.SizeLabel) {
+		return errors.New("size must be one of " + strings.Join(menuSizes, ", "))
+	}
+	if p.Bundle {
+		return p.checkSlots()
 	}
 	if len(p.Components) == 0 {
 		return errors.New("components list cannot be empty")
@@ -49,9 +231,87 @@ func (p *Product) CheckRequiredFields() error {
 			return errors.New("component quantity must be greater than 0")
 		}
 	}
+	return p.checkModifiers()
+}
+
+func (p *Product) checkModifiers() error {
+	seen := map[string]bool{}
+	for _, m := range p.Modifiers {
+		m.Name = strings.TrimSpace(m.Name)
+		if m.Name == "" {
+			return errors.New("modifier name is required")
+		}
+		if strings.Contains(m.Name, ",") {
+			return errors.New("modifier name cannot contain a comma")
+		}
+		key := strings.ToLower(m.Name)
+		if seen[key] {
+			return errors.New("modifier " + m.Name + " appears twice")
+		}
+		seen[key] = true
+		if strings.TrimSpace(m.ComponentID) == "" {
+			return errors.New("modifier component id is required")
+		}
+		if m.Quantity <= 0 {
+			return errors.New("modifier quantity must be greater than 0")
+		}
+		if m.ReplacesID != nil && !slices.ContainsFunc(p.Components, func(c *ProductComponent) bool {
+			return c.ComponentID == *m.ReplacesID
+		}) {
+			return errors.New("modifier " + m.Name + " replaces a component the item does not have")
+		}
+	}
 	return nil
 }
 
+func (p *Product) checkSlots() error {
+	if len(p.Components) > 0 {
+		return errors.New("bundle cannot have components of its own")
+	}
+	if len(p.Modifiers) > 0 {
+		return errors.New("bundle cannot have modifiers of its own")
+	}
+	if len(p.Slots) == 0 {
+		return errors.New("bundle must have at least one slot")
+	}
+	for _, slot := range p.Slots {
+		if strings.TrimSpace(slot.Name) == "" {
+			return errors.New("slot name is required")
+		}
+		if slot.Quantity == 0 {
+			slot.Quantity = 1
+		}
+		if slot.Quantity < 0 {
+			return errors.New("slot quantity must be greater than 0")
+		}
+		if len(slot.Options) == 0 {
+			return errors.New("slot must offer at least one option")
+		}
+		for _, opt := range slot.Options {
+			if (opt.ItemID == nil) == (opt.CategoryID == nil) {
+				return errors.New("slot option must name either an item or a category")
+			}
+			if opt.ItemID != nil && *opt.ItemID == p.ProductID {
+				return errors.New("bundle cannot contain itself")
+			}
+		}
+	}
+	return nil
+}
+
+// Offers reports whether the slot accepts the given menu item.
+func (s *BundleSlot) Offers(item *Product) bool {
+	for _, opt := range s.Options {
+		if opt.ItemID != nil && *opt.ItemID == item.ProductID {
+			return true
+		}
+		if opt.CategoryID != nil && *opt.CategoryID == item.CategoryID {
+			return true
+		}
+	}
+	return false
+}
+
 type ExtrasMap map[string]interface{}
 
 func (e ExtrasMap) Value() (driver.Value, error) {
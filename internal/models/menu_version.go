@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	MenuVersionDraft   = "draft"
+	MenuVersionLive    = "live"
+	MenuVersionRetired = "retired"
+)
+
+// MenuVersion is a state of the menu. A draft collects changes that leave the
+// live menu untouched until it is published; publishing applies them all at
+// once and makes the draft the live version.
+type MenuVersion struct {
+	VersionID   string              `json:"version_id"`
+	Name        string              `json:"name"`
+	Status      string              `json:"status"`
+	CreatedAt   *time.Time          `json:"created_at,omitempty"`
+	PublishedAt *time.Time          `json:"published_at,omitempty"`
+	Entries     []*MenuVersionEntry `json:"entries,omitempty"`
+}
+
+// MenuVersionEntry is one change of a draft: a new item (no ProductID), the
+// new state of a menu item, or its removal.
+type MenuVersionEntry struct {
+	EntryID   string   `json:"entry_id"`
+	ProductID string   `json:"product_id,omitempty"`
+	Remove    bool     `json:"remove,omitempty"`
+	Item      *Product `json:"item,omitempty"`
+}
+
+// MenuDiffLine describes how a draft changes one menu item. Fields maps the
+// json names of the changed fields to their live and draft values; an added
+// item lists every field it sets.
+type MenuDiffLine struct {
+	ProductID string                 `json:"product_id,omitempty"`
+	Title     string                 `json:"title"`
+	Change    string                 `json:"change"`
+	Fields    map[string]FieldChange `json:"fields,omitempty"`
+}
+
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+func (v *MenuVersion) Validate() error {
+	if strings.TrimSpace(v.Name) == "" {
+		return errors.New("version name is required")
+	}
+	return nil
+}
+
+func (e *MenuVersionEntry) Validate() error {
+	if e.Remove {
+		if strings.TrimSpace(e.ProductID) == "" {
+			return errors.New("product id is required to remove an item")
+		}
+		e.Item = nil
+		return nil
+	}
+	if e.Item == nil {
+		return errors.New("item is required")
+	}
+	if e.Item.Bundle {
+		return errors.New("bundles cannot be changed in a menu version")
+	}
+	return e.Item.CheckRequiredFields()
+}
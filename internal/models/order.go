@@ -17,13 +17,54 @@ type Purchase struct {
 	Note       *string     `json:"note,omitempty"`
 	Created    *time.Time  `json:"created,omitempty"`
 	Updated    *time.Time  `json:"updated,omitempty"`
+
+	// MenuVersionID is the menu version the order was priced against.
+	MenuVersionID *string `json:"menu_version_id,omitempty"`
+
+	// Channel is how the order is fulfilled, with what it needs: a table
+	// for dine-in, an address for delivery and, for takeaway pre-orders,
+	// when it is picked up.
+	Channel         string     `json:"channel"`
+	TableNumber     *int       `json:"table_number,omitempty"`
+	DeliveryAddress *string    `json:"delivery_address,omitempty"`
+	PickupAt        *time.Time `json:"pickup_at,omitempty"`
+
+	// Subtotal, Tax and DeliveryFee make up Amount. They are set when the
+	// order is priced.
+	Subtotal    *float64 `json:"subtotal,omitempty"`
+	Tax         *float64 `json:"tax,omitempty"`
+	DeliveryFee *float64 `json:"delivery_fee,omitempty"`
+
+	// MergeLines folds identical lines into one when the order is saved.
+	MergeLines bool `json:"merge_lines,omitempty"`
 }
 
+// OrderFilter narrows an order listing; empty fields match every order.
+type OrderFilter struct {
+	Channel string
+}
+
+// LineItem is one line of an order. The same item may be ordered on several
+// lines, each with its own adjustments; LineID tells them apart.
 type LineItem struct {
+	LineID      string    `json:"line_id,omitempty"`
 	ItemID      string    `json:"item_id"`
 	Count       int       `json:"count"`
 	UnitPrice   float64   `json:"unit_price"`
 	Adjustments ConfigMap `json:"adjustments"`
+
+	// Selections fill the slots of a bundle line.
+	Selections []*BundleSelection `json:"selections,omitempty"`
+}
+
+// BundleSelection is the item chosen for one bundle slot. Count and UnitPrice
+// are filled in when the order is priced: Count is the number of units per
+// bundle and UnitPrice the share of the bundle price allocated to one unit.
+type BundleSelection struct {
+	SlotID    string  `json:"slot_id"`
+	ItemID    string  `json:"item_id"`
+	Count     int     `json:"count"`
+	UnitPrice float64 `json:"unit_price"`
 }
 
 type PurchaseHistory struct {
@@ -35,6 +76,10 @@ type PurchaseHistory struct {
 
 type PurchaseBatch struct {
 	Purchases []*Purchase `json:"purchases"`
+	// Mode is best_effort (the default) or all_or_nothing.
+	Mode string `json:"mode,omitempty"`
+	// Async queues the batch as a job instead of answering with the result.
+	Async bool `json:"async,omitempty"`
 }
 
 type PurchaseResult struct {
@@ -43,11 +88,17 @@ type PurchaseResult struct {
 }
 
 type ResultReport struct {
-	Total       int                 `json:"total"`
-	Confirmed   int                 `json:"confirmed"`
-	Declined    int                 `json:"declined"`
-	Revenue     float64             `json:"revenue"`
-	StockEvents []*ProductComponent `json:"stock_events"`
+	Mode string `json:"mode"`
+	// RolledBack is set when an all_or_nothing batch was undone.
+	RolledBack  bool          `json:"rolled_back,omitempty"`
+	Total       int           `json:"total"`
+	Confirmed   int           `json:"confirmed"`
+	Declined    int           `json:"declined"`
+	Revenue     float64       `json:"revenue"`
+	StockEvents []*StockEvent `json:"stock_events"`
+	// StockEventsError says why StockEvents could not be read for a batch
+	// whose orders were kept.
+	StockEventsError string `json:"stock_events_error,omitempty"`
 }
 
 type ConfigMap map[string]interface{}
@@ -86,21 +137,82 @@ func (p *Purchase) Validate() error {
 	if p.Status != "open" {
 		return errors.New("purchase state must be 'open'")
 	}
+	if err := p.validateChannel(time.Now()); err != nil {
+		return err
+	}
 	for _, pos := range p.Positions {
-		if strings.TrimSpace(pos.ItemID) == "" {
-			return errors.New("missing item id")
+		if err := pos.Validate(); err != nil {
+			return err
 		}
-		if pos.Count <= 0 {
-			return errors.New("invalid item count")
+	}
+	return nil
+}
+
+func (l *LineItem) Validate() error {
+	if strings.TrimSpace(l.ItemID) == "" {
+		return errors.New("missing item id")
+	}
+	if l.Count <= 0 {
+		return errors.New("invalid item count")
+	}
+	for _, sel := range l.Selections {
+		if strings.TrimSpace(sel.SlotID) == "" || strings.TrimSpace(sel.ItemID) == "" {
+			return errors.New("bundle selection needs a slot id and an item id")
 		}
 	}
 	return nil
 }
 
+// GetItemIDs lists the items ordered, each once however many lines it is on.
 func (p *Purchase) GetItemIDs() []string {
 	var ids []string
+	seen := make(map[string]bool)
 	for _, item := range p.Positions {
-		ids = append(ids, item.ItemID)
+		if !seen[item.ItemID] {
+			seen[item.ItemID] = true
+			ids = append(ids, item.ItemID)
+		}
 	}
 	return ids
 }
+
+// ComponentLines returns the lines that consume stock: plain lines as they
+// are and, for bundle lines, one line per selected item.
+func (p *Purchase) ComponentLines() []*LineItem {
+	var lines []*LineItem
+	for _, item := range p.Positions {
+		if len(item.Selections) == 0 {
+			lines = append(lines, item)
+			continue
+		}
+		for _, sel := range item.Selections {
+			lines = append(lines, &LineItem{
+				ItemID:    sel.ItemID,
+				Count:     item.Count * sel.Count,
+				UnitPrice: sel.UnitPrice,
+			})
+		}
+	}
+	return lines
+}
+
+// OrderReservation is the stock held for an order, with how long it has been
+// held. An open order expires TTL after its last change, or after its pickup
+// time when placed ahead.
+type OrderReservation struct {
+	OrderID      string           `json:"order_id"`
+	CustomerID   string           `json:"customer_id"`
+	Status       string           `json:"status"`
+	ReservedAt   time.Time        `json:"reserved_at"`
+	LastActivity time.Time        `json:"last_activity"`
+	PickupAt     *time.Time       `json:"pickup_at,omitempty"`
+	AgeMinutes   int              `json:"age_minutes"`
+	ExpiresAt    *time.Time       `json:"expires_at,omitempty"`
+	Items        []*ReservedStock `json:"items"`
+}
+
+type ReservedStock struct {
+	InventoryID string  `json:"inventory_id"`
+	Title       string  `json:"title"`
+	Quantity    float64 `json:"quantity"`
+}
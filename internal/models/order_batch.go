@@ -0,0 +1,45 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Batch modes: best_effort keeps every order that goes through on its own,
+// all_or_nothing keeps the batch only if every order goes through.
+const (
+	BatchBestEffort   = "best_effort"
+	BatchAllOrNothing = "all_or_nothing"
+)
+
+// Batch job statuses.
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// ValidateBatchMode accepts the batch modes, and "" for the default.
+func ValidateBatchMode(mode string) error {
+	switch mode {
+	case "", BatchBestEffort, BatchAllOrNothing:
+		return nil
+	}
+	return fmt.Errorf("mode must be %s or %s", BatchBestEffort, BatchAllOrNothing)
+}
+
+// BatchJob is a batch processed in the background. Processed counts the
+// orders handled so far; Result is set once the job is done.
+type BatchJob struct {
+	JobID     string          `json:"job_id"`
+	Status    string          `json:"status"`
+	Mode      string          `json:"mode"`
+	Total     int             `json:"total"`
+	Processed int             `json:"processed"`
+	Error     string          `json:"error,omitempty"`
+	Result    *PurchaseResult `json:"result,omitempty"`
+	Created   time.Time       `json:"created"`
+	Finished  *time.Time      `json:"finished,omitempty"`
+	Purchases []*Purchase     `json:"-"`
+}
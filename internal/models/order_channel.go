@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Order channels: how an order reaches the customer. Orders placed without
+// a channel are takeaway.
+const (
+	ChannelDineIn   = "dine_in"
+	ChannelTakeaway = "takeaway"
+	ChannelDelivery = "delivery"
+)
+
+var OrderChannels = []string{ChannelDineIn, ChannelTakeaway, ChannelDelivery}
+
+// ValidateChannel accepts an order channel, or "" for any channel where it
+// filters a listing.
+func ValidateChannel(channel string) error {
+	if channel != "" && !slices.Contains(OrderChannels, channel) {
+		return fmt.Errorf("unknown channel %q (expected one of %s)", channel, strings.Join(OrderChannels, ", "))
+	}
+	return nil
+}
+
+// validateChannel checks that an order carries what its channel needs: a
+// table for dine-in and an address for delivery. Only takeaway orders can
+// be pre-ordered for a pickup time.
+func (p *Purchase) validateChannel(now time.Time) error {
+	if p.Channel == "" {
+		return errors.New("channel is required")
+	}
+	if err := ValidateChannel(p.Channel); err != nil {
+		return err
+	}
+
+	if p.Channel == ChannelDineIn {
+		if p.TableNumber == nil || *p.TableNumber <= 0 {
+			return errors.New("dine-in orders need a table number")
+		}
+	} else if p.TableNumber != nil {
+		return errors.New("only dine-in orders have a table number")
+	}
+
+	if p.Channel == ChannelDelivery {
+		if p.DeliveryAddress == nil || strings.TrimSpace(*p.DeliveryAddress) == "" {
+			return errors.New("delivery orders need an address")
+		}
+	} else if p.DeliveryAddress != nil {
+		return errors.New("only delivery orders have an address")
+	}
+
+	if p.PickupAt != nil {
+		if p.Channel != ChannelTakeaway {
+			return errors.New("only takeaway orders can be picked up later")
+		}
+		if !p.PickupAt.After(now) {
+			return errors.New("pickup_at must be in the future")
+		}
+	}
+	return nil
+}
+
+// ChannelPricing is what the channel adds to the price of the items: tax,
+// at a lower rate for food taken away in many places, and a delivery fee
+// waived from a set subtotal.
+type ChannelPricing struct {
+	DineInTaxPct   float64
+	TakeawayTaxPct float64
+	DeliveryFee    float64
+	// FreeDeliveryOver is the subtotal from which delivery is free; 0
+	// always charges the fee.
+	FreeDeliveryOver float64
+}
+
+// Charges returns the tax and the delivery fee of an order of the channel
+// with the given subtotal. Delivered food is taxed as takeaway and the fee
+// is not taxed.
+func (c ChannelPricing) Charges(channel string, subtotal float64) (tax, fee float64) {
+	rate := c.TakeawayTaxPct
+	if channel == ChannelDineIn {
+		rate = c.DineInTaxPct
+	}
+	tax = math.Round(subtotal*rate) / 100
+
+	if channel == ChannelDelivery && (c.FreeDeliveryOver <= 0 || subtotal < c.FreeDeliveryOver) {
+		fee = c.DeliveryFee
+	}
+	return tax, fee
+}
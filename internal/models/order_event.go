@@ -0,0 +1,44 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Order event types, published whenever an order is placed or changes.
+// order.status_changed covers the kitchen moving an order to in_progress or
+// ready.
+const (
+	OrderCreated       = "order.created"
+	OrderUpdated       = "order.updated"
+	OrderStatusChanged = "order.status_changed"
+	OrderCompleted     = "order.completed"
+	OrderCanceled      = "order.canceled"
+	OrderDeleted       = "order.deleted"
+)
+
+// OrderEvent is one change of an order. IDs grow by one per event, so a
+// client can resume after the last event it saw.
+type OrderEvent struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	OrderID    string    `json:"order_id"`
+	CustomerID string    `json:"customer_id,omitempty"`
+	Status     string    `json:"status"`
+	At         time.Time `json:"at"`
+	Order      *Purchase `json:"order,omitempty"`
+}
+
+// OrderEventFilter picks the events a subscriber gets; empty fields match
+// every event.
+type OrderEventFilter struct {
+	Statuses   []string
+	CustomerID string
+}
+
+func (f *OrderEventFilter) Match(e *OrderEvent) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, e.Status) {
+		return false
+	}
+	return f.CustomerID == "" || f.CustomerID == e.CustomerID
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// LinePatch changes a line of an open order. Fields left out keep their
+// value; the item itself cannot change, a line for another item is added
+// instead.
+type LinePatch struct {
+	Count       *int               `json:"count,omitempty"`
+	Adjustments ConfigMap          `json:"adjustments,omitempty"`
+	Selections  []*BundleSelection `json:"selections,omitempty"`
+}
+
+// Apply changes the line as the patch says.
+func (p *LinePatch) Apply(line *LineItem) {
+	if p.Count != nil {
+		line.Count = *p.Count
+	}
+	if p.Adjustments != nil {
+		line.Adjustments = p.Adjustments
+	}
+	if p.Selections != nil {
+		line.Selections = p.Selections
+	}
+}
+
+// SameAs reports whether two lines order the same thing: the same item with
+// the same adjustments and, for a bundle, the same item in every slot.
+func (l *LineItem) SameAs(other *LineItem) bool {
+	return l.ItemID == other.ItemID &&
+		adjustmentsKey(l.Adjustments) == adjustmentsKey(other.Adjustments) &&
+		selectionsKey(l.Selections) == selectionsKey(other.Selections)
+}
+
+// MergeLines folds lines that are the same into the first of them, adding up
+// their counts. The order of the lines is kept.
+func MergeLines(lines []*LineItem) []*LineItem {
+	merged := make([]*LineItem, 0, len(lines))
+	for _, line := range lines {
+		i := slices.IndexFunc(merged, line.SameAs)
+		if i < 0 {
+			merged = append(merged, line)
+			continue
+		}
+		merged[i].Count += line.Count
+	}
+	return merged
+}
+
+// adjustmentsKey writes adjustments out with sorted keys, so equal
+// adjustments give equal keys. No adjustments and {} are the same.
+func adjustmentsKey(adjustments ConfigMap) string {
+	if len(adjustments) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(map[string]interface{}(adjustments))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func selectionsKey(selections []*BundleSelection) string {
+	keys := make([]string, 0, len(selections))
+	for _, sel := range selections {
+		keys = append(keys, sel.SlotID+"="+sel.ItemID)
+	}
+	slices.Sort(keys)
+	return strings.Join(keys, ",")
+}
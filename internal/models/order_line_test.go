@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLineItemSameAs(t *testing.T) {
+	oat := ConfigMap{"milk": "oat", "shots": 2}
+	bundle := func(selections ...string) *LineItem {
+		line := &LineItem{ItemID: "9"}
+		for i := 0; i < len(selections); i += 2 {
+			line.Selections = append(line.Selections, &BundleSelection{SlotID: selections[i], ItemID: selections[i+1]})
+		}
+		return line
+	}
+
+	tests := []struct {
+		name string
+		a, b *LineItem
+		want bool
+	}{
+		{"same item", &LineItem{ItemID: "1"}, &LineItem{ItemID: "1"}, true},
+		{"other item", &LineItem{ItemID: "1"}, &LineItem{ItemID: "2"}, false},
+		{"count and price do not matter", &LineItem{ItemID: "1", Count: 1, UnitPrice: 4}, &LineItem{ItemID: "1", Count: 3, UnitPrice: 5}, true},
+		{"no adjustments and empty adjustments", &LineItem{ItemID: "1"}, &LineItem{ItemID: "1", Adjustments: ConfigMap{}}, true},
+		{"adjustments in another order", &LineItem{ItemID: "1", Adjustments: oat}, &LineItem{ItemID: "1", Adjustments: ConfigMap{"shots": 2, "milk": "oat"}}, true},
+		{"other adjustments", &LineItem{ItemID: "1", Adjustments: oat}, &LineItem{ItemID: "1", Adjustments: ConfigMap{"milk": "soy", "shots": 2}}, false},
+		{"adjustments and none", &LineItem{ItemID: "1", Adjustments: oat}, &LineItem{ItemID: "1"}, false},
+		{"slots in another order", bundle("1", "4", "2", "5"), bundle("2", "5", "1", "4"), true},
+		{"other item in a slot", bundle("1", "4", "2", "5"), bundle("1", "4", "2", "6"), false},
+		{"items swapped between slots", bundle("1", "4", "2", "5"), bundle("1", "5", "2", "4"), false},
+		{"slot left out", bundle("1", "4", "2", "5"), bundle("1", "4"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.SameAs(tt.b); got != tt.want {
+				t.Errorf("a.SameAs(b) = %v, want %v", got, tt.want)
+			}
+			if got := tt.b.SameAs(tt.a); got != tt.want {
+				t.Errorf("b.SameAs(a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	line := func(item string, count int, milk string) *LineItem {
+		l := &LineItem{ItemID: item, Count: count}
+		if milk != "" {
+			l.Adjustments = ConfigMap{"milk": milk}
+		}
+		return l
+	}
+
+	tests := []struct {
+		name  string
+		lines []*LineItem
+		want  string
+	}{
+		{
+			name:  "nothing to merge",
+			lines: []*LineItem{line("1", 1, ""), line("2", 2, "")},
+			want:  "[1x1 2x2]",
+		},
+		{
+			name:  "same lines add up in the first",
+			lines: []*LineItem{line("1", 1, ""), line("2", 1, ""), line("1", 2, "")},
+			want:  "[1x3 2x1]",
+		},
+		{
+			name:  "adjustments keep lines apart",
+			lines: []*LineItem{line("1", 1, "oat"), line("1", 1, ""), line("1", 2, "oat"), line("1", 1, "soy")},
+			want:  "[1x3 1x1 1x1]",
+		},
+		{
+			name:  "no lines",
+			lines: nil,
+			want:  "[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeLines(tt.lines)
+			got := make([]string, len(merged))
+			for i, l := range merged {
+				got[i] = fmt.Sprintf("%sx%d", l.ItemID, l.Count)
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Errorf("MergeLines() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// PickupSlotLength is how finely pickup hours are divided: the kitchen hands
+// out at most the capacity of the hours in every slot.
+const PickupSlotLength = 5 * time.Minute
+
+// PickupHours are the hours of an ISO weekday during which pre-orders can be
+// picked up, and how many items can be picked up per slot.
+type PickupHours struct {
+	Day      int    `json:"day"`
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Capacity int    `json:"capacity"`
+}
+
+// PickupSlot is one slot of the pickup hours, with the items already booked
+// into it.
+type PickupSlot struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Capacity  int       `json:"capacity"`
+	Booked    int       `json:"booked"`
+	Available int       `json:"available"`
+}
+
+// PickupBooking is the number of items of the orders to be picked up at a
+// moment.
+type PickupBooking struct {
+	PickupAt time.Time
+	Items    int
+}
+
+func (h *PickupHours) Validate() error {
+	if h.Day < 1 || h.Day > 7 {
+		return errors.New("day must be an ISO weekday from 1 (Monday) to 7 (Sunday)")
+	}
+	start, err := clockMinutes(h.Start)
+	if err != nil {
+		return err
+	}
+	end, err := clockMinutes(h.End)
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return errors.New("pickup hours must end after they start")
+	}
+	if (end-start)%int(PickupSlotLength/time.Minute) != 0 {
+		return errors.New("pickup hours must be a whole number of 5 minute slots")
+	}
+	if h.Capacity <= 0 {
+		return errors.New("capacity must be greater than 0")
+	}
+	return nil
+}
+
+// Slots lays the hours out over the day of date, in the location of date.
+func (h *PickupHours) Slots(date time.Time) []*PickupSlot {
+	start, err := clockMinutes(h.Start)
+	if err != nil {
+		return nil
+	}
+	end, err := clockMinutes(h.End)
+	if err != nil {
+		return nil
+	}
+
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	var slots []*PickupSlot
+	for m := start; m < end; m += int(PickupSlotLength / time.Minute) {
+		from := day.Add(time.Duration(m) * time.Minute)
+		slots = append(slots, &PickupSlot{
+			Start:     from,
+			End:       from.Add(PickupSlotLength),
+			Capacity:  h.Capacity,
+			Available: h.Capacity,
+		})
+	}
+	return slots
+}
+
+// SlotFor finds the slot a pickup at t falls in, or nil when t is outside the
+// hours. t is taken in its own location.
+func SlotFor(slots []*PickupSlot, t time.Time) *PickupSlot {
+	for _, slot := range slots {
+		if !t.Before(slot.Start) && t.Before(slot.End) {
+			return slot
+		}
+	}
+	return nil
+}
+
+// Book counts the bookings into the slots they fall in.
+func Book(slots []*PickupSlot, bookings []*PickupBooking) {
+	for _, b := range bookings {
+		if slot := SlotFor(slots, b.PickupAt); slot != nil {
+			slot.Booked += b.Items
+			slot.Available = max(slot.Capacity-slot.Booked, 0)
+		}
+	}
+}
+
+// Items is the number of items the kitchen makes for the order, counting
+// bundles by the items chosen for their slots.
+func (p *Purchase) Items() int {
+	n := 0
+	for _, line := range p.ComponentLines() {
+		n += line.Count
+	}
+	return n
+}
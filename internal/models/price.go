@@ -1,5 +1,14 @@
 package models
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
 type PriceHistory struct {
 	ID         string  `json:"price_id"`
 	MenuItemID string  `json:"product_id"`
@@ -7,3 +16,149 @@ type PriceHistory struct {
 	NewPrice   float64 `json:"new_price"`
 	ChangeDate string  `json:"change_date"`
 }
+
+type ScheduledPriceChange struct {
+	ChangeID    string     `json:"change_id"`
+	MenuItemID  string     `json:"product_id"`
+	NewPrice    float64    `json:"new_price"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+	Created     time.Time  `json:"created"`
+}
+
+// PriceWindow overrides the price of an item, or of every item of a category,
+// on the given weekdays between Start and End ("15:00"). A window whose end is
+// before its start runs past midnight.
+type PriceWindow struct {
+	WindowID    string        `json:"window_id"`
+	Name        string        `json:"name"`
+	ItemID      *string       `json:"item_id,omitempty"`
+	CategoryID  *string       `json:"category_id,omitempty"`
+	Price       *float64      `json:"price,omitempty"`
+	DiscountPct *float64      `json:"discount_pct,omitempty"`
+	Days        pq.Int64Array `json:"days"`
+	Start       string        `json:"start"`
+	End         string        `json:"end"`
+}
+
+func (c *ScheduledPriceChange) Validate(now time.Time) error {
+	if c.NewPrice <= 0 {
+		return errors.New("new price must be greater than 0")
+	}
+	if !c.EffectiveAt.After(now) {
+		return errors.New("effective_at must be in the future")
+	}
+	return nil
+}
+
+func (w *PriceWindow) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return errors.New("window name is required")
+	}
+	if (w.ItemID == nil) == (w.CategoryID == nil) {
+		return errors.New("window must target either an item or a category")
+	}
+	if (w.Price == nil) == (w.DiscountPct == nil) {
+		return errors.New("window must set either a price or a discount")
+	}
+	if w.Price != nil && *w.Price <= 0 {
+		return errors.New("price must be greater than 0")
+	}
+	if w.DiscountPct != nil && (*w.DiscountPct <= 0 || *w.DiscountPct >= 100) {
+		return errors.New("discount must be between 0 and 100")
+	}
+	if len(w.Days) == 0 {
+		return errors.New("at least one day is required")
+	}
+	for _, d := range w.Days {
+		if d < 1 || d > 7 {
+			return errors.New("days must be ISO weekdays from 1 (Monday) to 7 (Sunday)")
+		}
+	}
+	start, err := clockMinutes(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := clockMinutes(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return errors.New("window start and end must differ")
+	}
+	return nil
+}
+
+// Targets reports whether the window applies to the product.
+func (w *PriceWindow) Targets(p *Product) bool {
+	if w.ItemID != nil {
+		return *w.ItemID == p.ProductID
+	}
+	return w.CategoryID != nil && *w.CategoryID == p.CategoryID
+}
+
+// Covers reports whether t falls inside the window. For a window running past
+// midnight the part after midnight belongs to the day the window started on.
+func (w *PriceWindow) Covers(t time.Time) bool {
+	start, err := clockMinutes(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := clockMinutes(w.End)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	day := isoWeekday(t)
+
+	if start < end {
+		return now >= start && now < end && w.onDay(day)
+	}
+	if now >= start {
+		return w.onDay(day)
+	}
+	yesterday := day - 1
+	if yesterday == 0 {
+		yesterday = 7
+	}
+	return now < end && w.onDay(yesterday)
+}
+
+// PriceFor returns the window price of an item listed at the given price.
+func (w *PriceWindow) PriceFor(listPrice float64) float64 {
+	if w.Price != nil {
+		return *w.Price
+	}
+	return listPrice * (100 - *w.DiscountPct) / 100
+}
+
+func (w *PriceWindow) onDay(day int) bool {
+	for _, d := range w.Days {
+		if int(d) == day {
+			return true
+		}
+	}
+	return false
+}
+
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// clockMinutes parses "HH:MM" (or "HH:MM:SS" as read back from the database)
+// into minutes after midnight.
+func clockMinutes(s string) (int, error) {
+	layout := "15:04"
+	if strings.Count(s, ":") == 2 {
+		layout = "15:04:05"
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriceWindowCovers(t *testing.T) {
+	// 5 January 2026 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	afternoon := &PriceWindow{Days: []int64{1}, Start: "15:00", End: "17:00"}
+	lateFriday := &PriceWindow{Days: []int64{5}, Start: "22:00", End: "02:00"}
+	lateSunday := &PriceWindow{Days: []int64{7}, Start: "22:00:00", End: "02:00:00"}
+
+	tests := []struct {
+		name   string
+		window *PriceWindow
+		at     time.Time
+		want   bool
+	}{
+		{"start is inside", afternoon, at(5, 15, 0), true},
+		{"end is outside", afternoon, at(5, 17, 0), false},
+		{"other day", afternoon, at(6, 16, 0), false},
+		{"before midnight on its day", lateFriday, at(9, 23, 0), true},
+		{"after midnight belongs to the day before", lateFriday, at(10, 1, 30), true},
+		{"end after midnight is outside", lateFriday, at(10, 2, 0), false},
+		{"after midnight of a day it does not start on", lateFriday, at(9, 1, 30), false},
+		{"evening of the next day", lateFriday, at(10, 23, 0), false},
+		{"sunday window runs into monday", lateSunday, at(12, 1, 0), true},
+		{"sunday window is not on monday evening", lateSunday, at(12, 23, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Covers(tt.at); got != tt.want {
+				t.Errorf("Covers(%s) = %v, want %v", tt.at.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+}
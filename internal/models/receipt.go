@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Receipt variants: the customer receipt with prices, and the kitchen ticket
+// listing what to make without them.
+const (
+	ReceiptCustomer = "receipt"
+	ReceiptKitchen  = "kitchen"
+)
+
+// Paper widths of the thermal printers, in millimetres.
+const (
+	Paper58mm = 58
+	Paper80mm = 80
+)
+
+// ShopInfo heads every receipt.
+type ShopInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	Phone   string `json:"phone,omitempty"`
+	TaxID   string `json:"tax_id,omitempty"`
+}
+
+// Receipt is an order laid out for printing. Orders are paid when they are
+// completed, so a completed order shows its total as paid and any other as
+// due.
+type Receipt struct {
+	Kind            string         `json:"kind"`
+	Shop            ShopInfo       `json:"shop"`
+	OrderID         string         `json:"order_id"`
+	Customer        string         `json:"customer"`
+	Status          string         `json:"status"`
+	Channel         string         `json:"channel"`
+	TableNumber     *int           `json:"table_number,omitempty"`
+	DeliveryAddress *string        `json:"delivery_address,omitempty"`
+	PickupAt        *time.Time     `json:"pickup_at,omitempty"`
+	PlacedAt        time.Time      `json:"placed_at"`
+	Lines           []*ReceiptLine `json:"lines"`
+	Subtotal        float64        `json:"subtotal"`
+	Tax             float64        `json:"tax"`
+	TaxPct          float64        `json:"tax_pct"`
+	DeliveryFee     float64        `json:"delivery_fee"`
+	Total           float64        `json:"total"`
+	Paid            bool           `json:"paid"`
+}
+
+// ReceiptLine is an order line with its adjustments ("oat milk", "size:
+// large") and, for a bundle, the items chosen for it.
+type ReceiptLine struct {
+	Title       string   `json:"title"`
+	Count       int      `json:"count"`
+	UnitPrice   float64  `json:"unit_price"`
+	Total       float64  `json:"total"`
+	Adjustments []string `json:"adjustments,omitempty"`
+	Components  []string `json:"components,omitempty"`
+}
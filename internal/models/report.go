@@ -0,0 +1,112 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	ClassStar      = "star"
+	ClassPlowhorse = "plowhorse"
+	ClassPuzzle    = "puzzle"
+	ClassDog       = "dog"
+)
+
+type MenuItemSales struct {
+	ItemRef string
+	Title   string
+	Group   string
+	Price   float64
+	Sold    float64
+	Revenue float64
+}
+
+type MenuEngineeringLine struct {
+	ItemRef     string  `json:"product_id"`
+	Title       string  `json:"title"`
+	Group       string  `json:"group"`
+	Sold        float64 `json:"sold"`
+	Popularity  float64 `json:"popularity_pct"`
+	Revenue     float64 `json:"revenue"`
+	UnitCost    float64 `json:"unit_cost"`
+	UnitMargin  float64 `json:"unit_margin"`
+	TotalMargin float64 `json:"total_margin"`
+	Class       string  `json:"class"`
+}
+
+type MenuEngineeringReport struct {
+	From                time.Time              `json:"from"`
+	To                  time.Time              `json:"to"`
+	TotalSold           float64                `json:"total_sold"`
+	PopularityThreshold float64                `json:"popularity_threshold_pct"`
+	MarginThreshold     float64                `json:"margin_threshold"`
+	Items               []*MenuEngineeringLine `json:"items"`
+}
+
+type ProfitLine struct {
+	Period         *time.Time `json:"period,omitempty"`
+	Category       string     `json:"category,omitempty"`
+	Orders         int        `json:"orders"`
+	Revenue        float64    `json:"revenue"`
+	COGS           float64    `json:"cogs"`
+	GrossProfit    float64    `json:"gross_profit"`
+	GrossMarginPct float64    `json:"gross_margin_pct"`
+	WasteCost      float64    `json:"waste_cost"`
+}
+
+type ProfitReport struct {
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	GroupBy    string        `json:"group_by"`
+	Periods    []*ProfitLine `json:"periods"`
+	Categories []*ProfitLine `json:"categories"`
+	Total      ProfitLine    `json:"total"`
+}
+
+// Finalize derives gross profit and margin from revenue and COGS.
+func (l *ProfitLine) Finalize() {
+	l.Revenue = math.Round(l.Revenue*100) / 100
+	l.COGS = math.Round(l.COGS*100) / 100
+	l.WasteCost = math.Round(l.WasteCost*100) / 100
+	l.GrossProfit = math.Round((l.Revenue-l.COGS)*100) / 100
+	l.GrossMarginPct = 0
+	if l.Revenue != 0 {
+		l.GrossMarginPct = math.Round(l.GrossProfit/l.Revenue*10000) / 100
+	}
+}
+
+// PriceImpactPeriod sums the sales of an item over the window on one side of
+// a price change. Days is shorter than the window when the change is recent.
+type PriceImpactPeriod struct {
+	Days          float64 `json:"days"`
+	Units         float64 `json:"units"`
+	Revenue       float64 `json:"revenue"`
+	UnitsPerDay   float64 `json:"units_per_day"`
+	RevenuePerDay float64 `json:"revenue_per_day"`
+}
+
+type PriceImpactLine struct {
+	PriceID          string            `json:"price_id"`
+	ItemRef          string            `json:"product_id"`
+	Title            string            `json:"title"`
+	OldPrice         float64           `json:"old_price"`
+	NewPrice         float64           `json:"new_price"`
+	ChangedAt        time.Time         `json:"changed_at"`
+	Before           PriceImpactPeriod `json:"before"`
+	After            PriceImpactPeriod `json:"after"`
+	PriceChangePct   float64           `json:"price_change_pct"`
+	UnitsChangePct   *float64          `json:"units_change_pct,omitempty"`
+	RevenueChangePct *float64          `json:"revenue_change_pct,omitempty"`
+	// Elasticity is the midpoint (arc) elasticity of daily unit sales.
+	Elasticity *float64 `json:"elasticity,omitempty"`
+	// OverlappingChange flags another price change of the item inside the
+	// window, which muddies the comparison.
+	OverlappingChange bool `json:"overlapping_change"`
+}
+
+type PriceImpactReport struct {
+	From       time.Time          `json:"from"`
+	To         time.Time          `json:"to"`
+	WindowDays int                `json:"window_days"`
+	Changes    []*PriceImpactLine `json:"changes"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "math"
+
+// StockEvent is what a batch did to one ingredient: the stock it started
+// from, what it consumed and the stock it left, all as of one snapshot.
+type StockEvent struct {
+	InventoryID  string   `json:"inventory_id"`
+	Title        string   `json:"title"`
+	Measure      string   `json:"measure"`
+	Opening      float64  `json:"opening_stock"`
+	Consumed     float64  `json:"consumed"`
+	Closing      float64  `json:"closing_stock"`
+	ReorderLevel *float64 `json:"reorder_level,omitempty"`
+	// LowStock is set when the closing stock is at or below the reorder
+	// level.
+	LowStock bool `json:"low_stock"`
+}
+
+// NewStockEvent works out a stock event from the stock read after the batch
+// and what the batch consumed, both taken in the same snapshot. Quantities
+// keep the four decimals inventory is stored with.
+func NewStockEvent(id, title, measure string, closing, consumed float64, reorderLevel *float64) *StockEvent {
+	round := func(f float64) float64 { return math.Round(f*1e4) / 1e4 }
+
+	event := &StockEvent{
+		InventoryID:  id,
+		Title:        title,
+		Measure:      measure,
+		Opening:      round(closing + consumed),
+		Consumed:     round(consumed),
+		Closing:      round(closing),
+		ReorderLevel: reorderLevel,
+	}
+	if reorderLevel != nil {
+		event.LowStock = event.Closing <= *reorderLevel
+	}
+	return event
+}
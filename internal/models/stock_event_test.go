@@ -0,0 +1,98 @@
+package models
+
+import "testing"
+
+func TestNewStockEvent(t *testing.T) {
+	level := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name         string
+		closing      float64
+		consumed     float64
+		reorderLevel *float64
+		wantOpening  float64
+		wantConsumed float64
+		wantClosing  float64
+		wantLow      bool
+	}{
+		{
+			name:         "opening is closing plus consumed",
+			closing:      7.5,
+			consumed:     2.5,
+			wantOpening:  10,
+			wantConsumed: 2.5,
+			wantClosing:  7.5,
+		},
+		{
+			name:         "rounded to four decimals",
+			closing:      0.1 + 0.2,
+			consumed:     0.00004,
+			wantOpening:  0.3,
+			wantConsumed: 0,
+			wantClosing:  0.3,
+		},
+		{
+			name:         "below reorder level",
+			closing:      4,
+			consumed:     6,
+			reorderLevel: level(5),
+			wantOpening:  10,
+			wantConsumed: 6,
+			wantClosing:  4,
+			wantLow:      true,
+		},
+		{
+			name:         "at reorder level",
+			closing:      5,
+			consumed:     1,
+			reorderLevel: level(5),
+			wantOpening:  6,
+			wantConsumed: 1,
+			wantClosing:  5,
+			wantLow:      true,
+		},
+		{
+			name:         "at reorder level after rounding",
+			closing:      5.00001,
+			consumed:     1,
+			reorderLevel: level(5),
+			wantOpening:  6,
+			wantConsumed: 1,
+			wantClosing:  5,
+			wantLow:      true,
+		},
+		{
+			name:         "above reorder level",
+			closing:      5.5,
+			consumed:     1,
+			reorderLevel: level(5),
+			wantOpening:  6.5,
+			wantConsumed: 1,
+			wantClosing:  5.5,
+		},
+		{
+			name:         "no reorder level",
+			closing:      0,
+			consumed:     3,
+			wantOpening:  3,
+			wantConsumed: 3,
+			wantClosing:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewStockEvent("1", "Milk", "l", tt.closing, tt.consumed, tt.reorderLevel)
+			if e.Opening != tt.wantOpening || e.Consumed != tt.wantConsumed || e.Closing != tt.wantClosing {
+				t.Errorf("NewStockEvent() = opening %g, consumed %g, closing %g; want %g, %g, %g",
+					e.Opening, e.Consumed, e.Closing, tt.wantOpening, tt.wantConsumed, tt.wantClosing)
+			}
+			if e.LowStock != tt.wantLow {
+				t.Errorf("NewStockEvent().LowStock = %v, want %v", e.LowStock, tt.wantLow)
+			}
+			if e.ReorderLevel != tt.reorderLevel {
+				t.Errorf("NewStockEvent().ReorderLevel = %v, want %v", e.ReorderLevel, tt.reorderLevel)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type StockTake struct {
+	StockTakeID string        `json:"stock_take_id"`
+	Status      string        `json:"status"`
+	Note        *string       `json:"note,omitempty"`
+	Started     *time.Time    `json:"started,omitempty"`
+	Committed   *time.Time    `json:"committed,omitempty"`
+	Counts      []*StockCount `json:"counts"`
+}
+
+type StockCount struct {
+	ItemRef    string     `json:"inventory_id"`
+	Device     string     `json:"device"`
+	CountedQty float64    `json:"counted_qty"`
+	CountedAt  *time.Time `json:"counted_at,omitempty"`
+}
+
+type CountSubmission struct {
+	Device string        `json:"device"`
+	Counts []*StockCount `json:"counts"`
+}
+
+type VarianceLine struct {
+	ItemRef        string  `json:"inventory_id"`
+	Title          string  `json:"title"`
+	Measure        string  `json:"measure"`
+	TheoreticalQty float64 `json:"theoretical_qty"`
+	CountedQty     float64 `json:"counted_qty"`
+	Variance       float64 `json:"variance"`
+	UnitCost       float64 `json:"unit_cost"`
+	VarianceValue  float64 `json:"variance_value"`
+}
+
+type VarianceReport struct {
+	StockTakeID   string          `json:"stock_take_id"`
+	Committed     *time.Time      `json:"committed,omitempty"`
+	Lines         []*VarianceLine `json:"lines"`
+	ItemsCounted  int             `json:"items_counted"`
+	ItemsAdjusted int             `json:"items_adjusted"`
+	TotalValue    float64         `json:"total_variance_value"`
+}
+
+func (c *CountSubmission) Validate() error {
+	if strings.TrimSpace(c.Device) == "" {
+		return errors.New("device is required")
+	}
+	if len(c.Counts) == 0 {
+		return errors.New("at least one count is required")
+	}
+	seen := make(map[string]bool, len(c.Counts))
+	for _, count := range c.Counts {
+		if strings.TrimSpace(count.ItemRef) == "" {
+			return errors.New("inventory id is required")
+		}
+		if count.CountedQty < 0 {
+			return errors.New("counted quantity cannot be negative")
+		}
+		if seen[count.ItemRef] {
+			return errors.New("duplicate inventory id in counts")
+		}
+		seen[count.ItemRef] = true
+	}
+	return nil
+}
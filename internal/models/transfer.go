@@ -0,0 +1,244 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Import and export records refer to categories and ingredients by name, so
+// a file exported by one shop can be imported by another.
+
+type InventoryRecord struct {
+	Title          string             `json:"title"`
+	Stock          float64            `json:"stock"`
+	Measure        string             `json:"measure"`
+	UnitCost       float64            `json:"unit_cost"`
+	Allergens      []string           `json:"allergens"`
+	Nutrition      *Nutrition         `json:"nutrition,omitempty"`
+	Prepared       bool               `json:"prepared"`
+	Yield          *float64           `json:"yield,omitempty"`
+	ShelfLifeHours *int               `json:"shelf_life_hours,omitempty"`
+	Recipe         []*ComponentRecord `json:"recipe,omitempty"`
+}
+
+type MenuRecord struct {
+	Title      string             `json:"title"`
+	Details    string             `json:"details"`
+	UnitPrice  float64            `json:"unit_price"`
+	SizeLabel  string             `json:"size_label"`
+	Category   string             `json:"category"`
+	Labels     []string           `json:"labels"`
+	Extras     ExtrasMap          `json:"extras,omitempty"`
+	Components []*ComponentRecord `json:"components"`
+}
+
+// ComponentRecord is one line of a recipe, naming the inventory item.
+type ComponentRecord struct {
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+}
+
+// ImportReport tells what an import did, or would do on a dry run, with
+// each row. Rows are numbered from 1; in a CSV file the header is row 1.
+type ImportReport struct {
+	DryRun  bool         `json:"dry_run"`
+	Total   int          `json:"total"`
+	Created int          `json:"created"`
+	Updated int          `json:"updated"`
+	Failed  int          `json:"failed"`
+	Rows    []*ImportRow `json:"rows"`
+}
+
+type ImportRow struct {
+	Row    int    `json:"row"`
+	Title  string `json:"title"`
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+var (
+	InventoryCSVHeader = []string{"title", "stock", "measure", "unit_cost", "allergens", "calories", "protein", "fat", "carbs", "prepared", "yield", "shelf_life_hours", "recipe"}
+	MenuCSVHeader      = []string{"title", "details", "unit_price", "size_label", "category", "labels", "extras", "components"}
+)
+
+// In CSV, lists are separated by ";" and components are written as
+// "name:quantity", e.g. "Espresso beans:0.018;Milk:0.2".
+
+func (r *InventoryRecord) CSV() []string {
+	var calories, protein, fat, carbs string
+	if n := r.Nutrition; n != nil {
+		calories, protein, fat, carbs = formatFloat(n.Calories), formatFloat(n.Protein), formatFloat(n.Fat), formatFloat(n.Carbs)
+	}
+	var yield, shelfLife string
+	if r.Yield != nil {
+		yield = formatFloat(*r.Yield)
+	}
+	if r.ShelfLifeHours != nil {
+		shelfLife = strconv.Itoa(*r.ShelfLifeHours)
+	}
+	return []string{
+		r.Title,
+		formatFloat(r.Stock),
+		r.Measure,
+		formatFloat(r.UnitCost),
+		strings.Join(r.Allergens, ";"),
+		calories, protein, fat, carbs,
+		strconv.FormatBool(r.Prepared),
+		yield,
+		shelfLife,
+		formatComponents(r.Recipe),
+	}
+}
+
+// ParseInventoryCSV reads a row keyed by the header. Missing columns are
+// left empty.
+func ParseInventoryCSV(row map[string]string) (*InventoryRecord, error) {
+	var (
+		r   = &InventoryRecord{Title: row["title"], Measure: row["measure"]}
+		err error
+	)
+	if r.Stock, err = parseFloat(row, "stock"); err != nil {
+		return nil, err
+	}
+	if r.UnitCost, err = parseFloat(row, "unit_cost"); err != nil {
+		return nil, err
+	}
+	r.Allergens = splitList(row["allergens"])
+
+	if row["calories"] != "" || row["protein"] != "" || row["fat"] != "" || row["carbs"] != "" {
+		r.Nutrition = &Nutrition{}
+		for key, field := range map[string]*float64{
+			"calories": &r.Nutrition.Calories,
+			"protein":  &r.Nutrition.Protein,
+			"fat":      &r.Nutrition.Fat,
+			"carbs":    &r.Nutrition.Carbs,
+		} {
+			if *field, err = parseFloat(row, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if v := row["prepared"]; v != "" {
+		if r.Prepared, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("prepared: %q is not true or false", v)
+		}
+	}
+	if row["yield"] != "" {
+		yield, err := parseFloat(row, "yield")
+		if err != nil {
+			return nil, err
+		}
+		r.Yield = &yield
+	}
+	if v := row["shelf_life_hours"]; v != "" {
+		hours, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("shelf_life_hours: %q is not a whole number", v)
+		}
+		r.ShelfLifeHours = &hours
+	}
+	if r.Recipe, err = parseComponents(row["recipe"]); err != nil {
+		return nil, fmt.Errorf("recipe: %w", err)
+	}
+	return r, nil
+}
+
+func (r *MenuRecord) CSV() []string {
+	extras := ""
+	if len(r.Extras) > 0 {
+		data, _ := json.Marshal(r.Extras)
+		extras = string(data)
+	}
+	return []string{
+		r.Title,
+		r.Details,
+		formatFloat(r.UnitPrice),
+		r.SizeLabel,
+		r.Category,
+		strings.Join(r.Labels, ";"),
+		extras,
+		formatComponents(r.Components),
+	}
+}
+
+// ParseMenuCSV reads a row keyed by the header. Extras are a JSON object.
+func ParseMenuCSV(row map[string]string) (*MenuRecord, error) {
+	var (
+		r = &MenuRecord{
+			Title:     row["title"],
+			Details:   row["details"],
+			SizeLabel: row["size_label"],
+			Category:  row["category"],
+			Labels:    splitList(row["labels"]),
+		}
+		err error
+	)
+	if r.UnitPrice, err = parseFloat(row, "unit_price"); err != nil {
+		return nil, err
+	}
+	if v := row["extras"]; v != "" {
+		if err := json.Unmarshal([]byte(v), &r.Extras); err != nil {
+			return nil, errors.New("extras: not a JSON object")
+		}
+	}
+	if r.Components, err = parseComponents(row["components"]); err != nil {
+		return nil, fmt.Errorf("components: %w", err)
+	}
+	return r, nil
+}
+
+func parseFloat(row map[string]string, key string) (float64, error) {
+	v := row[key]
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a number", key, v)
+	}
+	return f, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func formatComponents(components []*ComponentRecord) string {
+	parts := make([]string, len(components))
+	for i, c := range components {
+		parts[i] = c.Name + ":" + formatFloat(c.Quantity)
+	}
+	return strings.Join(parts, ";")
+}
+
+// parseComponents reads "name:quantity" pairs. The name ends at the last
+// colon, so names may contain colons themselves.
+func parseComponents(s string) ([]*ComponentRecord, error) {
+	var components []*ComponentRecord
+	for _, part := range splitList(s) {
+		i := strings.LastIndex(part, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("%q should be name:quantity", part)
+		}
+		qty, err := strconv.ParseFloat(strings.TrimSpace(part[i+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q has no valid quantity", part)
+		}
+		components = append(components, &ComponentRecord{Name: strings.TrimSpace(part[:i]), Quantity: qty})
+	}
+	return components, nil
+}
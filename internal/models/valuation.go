@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	CostingFIFO            = "fifo"
+	CostingWeightedAverage = "weighted_average"
+)
+
+type CostLayer struct {
+	Quantity float64   `json:"quantity"`
+	UnitCost float64   `json:"unit_cost"`
+	Received time.Time `json:"received"`
+}
+
+// ValuationInput is the raw cost history of one ingredient up to the
+// valuation date: the quantity on hand at that moment and every stock
+// movement, oldest first. Receipts and positive adjustments have a positive
+// quantity, consumption and waste a negative one.
+type ValuationInput struct {
+	ItemRef     string
+	Title       string
+	Measure     string
+	Quantity    float64
+	CurrentCost float64
+	Movements   []*CostLayer
+}
+
+type ValuationLine struct {
+	ItemRef  string       `json:"inventory_id"`
+	Title    string       `json:"title"`
+	Measure  string       `json:"measure"`
+	Quantity float64      `json:"quantity"`
+	UnitCost float64      `json:"unit_cost"`
+	Value    float64      `json:"value"`
+	Layers   []*CostLayer `json:"layers,omitempty"`
+}
+
+type InventoryValuation struct {
+	AsOf       time.Time        `json:"as_of"`
+	Method     string           `json:"method"`
+	Lines      []*ValuationLine `json:"lines"`
+	ItemCount  int              `json:"item_count"`
+	TotalValue float64          `json:"total_value"`
+}
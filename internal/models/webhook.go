@@ -0,0 +1,128 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Event types a webhook can subscribe to. Order events follow the order
+// status; inventory.low_stock is sent when stock falls to the reorder level.
+const (
+	WebhookOrderCreated       = "order.created"
+	WebhookOrderStatusChanged = "order.status_changed"
+	WebhookOrderCompleted     = "order.completed"
+	WebhookOrderCanceled      = "order.canceled"
+	WebhookLowStock           = "inventory.low_stock"
+)
+
+var WebhookEventTypes = []string{
+	WebhookOrderCreated,
+	WebhookOrderStatusChanged,
+	WebhookOrderCompleted,
+	WebhookOrderCanceled,
+	WebhookLowStock,
+}
+
+// Delivery states. A pending delivery is retried until it succeeds or runs
+// out of attempts and is dead-lettered.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook is a subscription. The secret signs every delivery and is only
+// returned when the webhook is created.
+type Webhook struct {
+	WebhookID  string     `json:"webhook_id"`
+	URL        string     `json:"url"`
+	Secret     string     `json:"secret,omitempty"`
+	EventTypes []string   `json:"event_types"`
+	Active     *bool      `json:"active,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(strings.TrimSpace(w.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if len(w.EventTypes) == 0 {
+		return errors.New("at least one event type is required")
+	}
+	for _, t := range w.EventTypes {
+		if !slices.Contains(WebhookEventTypes, t) {
+			return fmt.Errorf("unknown event type %q (expected one of %s)", t, strings.Join(WebhookEventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// OutboxEvent is an event waiting in or sent from the outbox.
+type OutboxEvent struct {
+	EventID   int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// WebhookDelivery is one event sent to one webhook, with its attempts.
+type WebhookDelivery struct {
+	DeliveryID    string            `json:"delivery_id"`
+	WebhookID     string            `json:"webhook_id"`
+	Event         *OutboxEvent      `json:"event"`
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Log           []*WebhookAttempt `json:"log"`
+
+	// URL and Secret of the webhook, for the dispatcher only.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type WebhookAttempt struct {
+	AttemptedAt time.Time `json:"attempted_at"`
+	StatusCode  *int      `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int       `json:"duration_ms"`
+}
+
+// Succeeded tells whether the receiver accepted the delivery.
+func (a *WebhookAttempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode != nil && *a.StatusCode >= 200 && *a.StatusCode < 300
+}
+
+// WebhookBackoff is the wait before retrying after the given number of
+// failed attempts: 30s doubling each time, at most an hour.
+func WebhookBackoff(attempts int) time.Duration {
+	const base, limit = 30 * time.Second, time.Hour
+	if attempts < 1 {
+		return base
+	}
+	if attempts > 8 {
+		return limit
+	}
+	return min(base<<(attempts-1), limit)
+}
+
+// SignWebhook signs a delivery body for the receiver to check: the hex
+// HMAC-SHA256, keyed with the webhook secret, of the timestamp, a dot and
+// the body. Signing the timestamp lets receivers reject replayed requests.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
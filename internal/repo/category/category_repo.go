@@ -0,0 +1,168 @@
+package category_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"frappuccino/internal/models"
+)
+
+type CategoryRepo interface {
+	GetAll(ctx context.Context) ([]*models.Category, error)
+	GetByID(ctx context.Context, id string) (*models.Category, error)
+	GetByName(ctx context.Context, name string) (*models.Category, error)
+	Create(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, id string, category *models.Category) error
+	Delete(ctx context.Context, id string) error
+}
+
+// querier runs the category queries, on the database or in a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type categoryRepo struct {
+	DB *sql.DB
+	// q is DB, or the transaction given to NewCategoryRepoTx.
+	q querier
+}
+
+func NewCategoryRepo(db *sql.DB) CategoryRepo {
+	return &categoryRepo{
+		DB: db,
+		q:  db,
+	}
+}
+
+// NewCategoryRepoTx returns a repo whose queries run in tx.
+func NewCategoryRepoTx(db *sql.DB, tx *sql.Tx) CategoryRepo {
+	return &categoryRepo{
+		DB: db,
+		q:  tx,
+	}
+}
+
+const selectCategory = `
+	SELECT Category_ID, Name, Parent_ID, Display_Order, Active
+	FROM Categories
+`
+
+func scanCategory(row interface{ Scan(...any) error }) (*models.Category, error) {
+	var (
+		category models.Category
+		id       int
+		parentID sql.NullInt64
+		active   bool
+	)
+	if err := row.Scan(&id, &category.Name, &parentID, &category.DisplayOrder, &active); err != nil {
+		return nil, err
+	}
+	category.CategoryID = strconv.Itoa(id)
+	if parentID.Valid {
+		ref := strconv.FormatInt(parentID.Int64, 10)
+		category.ParentID = &ref
+	}
+	category.Active = &active
+	return &category, nil
+}
+
+func (r *categoryRepo) GetAll(ctx context.Context) ([]*models.Category, error) {
+	rows, err := r.q.QueryContext(ctx, selectCategory+` ORDER BY Display_Order, Name`)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []*models.Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (r *categoryRepo) GetByID(ctx context.Context, id string) (*models.Category, error) {
+	category, err := scanCategory(r.q.QueryRowContext(ctx, selectCategory+` WHERE Category_ID = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("query category: %w", err)
+	}
+	return category, nil
+}
+
+// GetByName looks a category up by name, ignoring case and surrounding spaces.
+func (r *categoryRepo) GetByName(ctx context.Context, name string) (*models.Category, error) {
+	category, err := scanCategory(r.q.QueryRowContext(ctx, selectCategory+` WHERE LOWER(Name) = LOWER(BTRIM($1))`, name))
+	if err != nil {
+		return nil, fmt.Errorf("query category by name: %w", err)
+	}
+	return category, nil
+}
+
+func (r *categoryRepo) Create(ctx context.Context, category *models.Category) error {
+	query := `
+		INSERT INTO Categories (Name, Parent_ID, Display_Order, Active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING Category_ID
+	`
+
+	var id int
+	err := r.q.QueryRowContext(ctx, query,
+		category.Name,
+		category.ParentID,
+		category.DisplayOrder,
+		category.IsActive(),
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("insert category: %w", err)
+	}
+	category.CategoryID = strconv.Itoa(id)
+	return nil
+}
+
+func (r *categoryRepo) Update(ctx context.Context, id string, category *models.Category) error {
+	query := `
+		UPDATE Categories
+		SET Name = $1, Parent_ID = $2, Display_Order = $3, Active = $4
+		WHERE Category_ID = $5
+	`
+
+	res, err := r.q.ExecContext(ctx, query,
+		category.Name,
+		category.ParentID,
+		category.DisplayOrder,
+		category.IsActive(),
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("update category: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Delete removes a category. Subcategories move to the top level; a category
+// still holding menu items cannot be deleted.
+func (r *categoryRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.q.ExecContext(ctx, `DELETE FROM Categories WHERE Category_ID = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete category: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
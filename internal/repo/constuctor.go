@@ -1,29 +1,82 @@
 package internal
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 
+	category_repo "frappuccino/internal/repo/category"
 	customer_repo "frappuccino/internal/repo/customer"
 	inventory_repo "frappuccino/internal/repo/inventory"
+	kitchen_repo "frappuccino/internal/repo/kitchen"
 	menu_repo "frappuccino/internal/repo/menu"
+	menuversion_repo "frappuccino/internal/repo/menuversion"
 	order_repo "frappuccino/internal/repo/order"
+	pickup_repo "frappuccino/internal/repo/pickup"
+	pricing_repo "frappuccino/internal/repo/pricing"
+	report_repo "frappuccino/internal/repo/report"
 	stats_repo "frappuccino/internal/repo/stats"
+	stocktake_repo "frappuccino/internal/repo/stocktake"
+	webhook_repo "frappuccino/internal/repo/webhook"
 )
 
 type Container struct {
-	OrderRepo     order_repo.OrderRepo
-	CustomerRepo  *customer_repo.CustomerRepo
-	MenuRepo      menu_repo.MenuRepo
-	InventoryRepo inventory_repo.InventoryRepo
-	StatsRepo     stats_repo.StatsRepo
+	OrderRepo       order_repo.OrderRepo
+	CustomerRepo    *customer_repo.CustomerRepo
+	MenuRepo        menu_repo.MenuRepo
+	InventoryRepo   inventory_repo.InventoryRepo
+	StatsRepo       stats_repo.StatsRepo
+	StockTakeRepo   stocktake_repo.StockTakeRepo
+	ReportRepo      report_repo.ReportRepo
+	PricingRepo     pricing_repo.PricingRepo
+	CategoryRepo    category_repo.CategoryRepo
+	MenuVersionRepo menuversion_repo.MenuVersionRepo
+	KitchenRepo     kitchen_repo.KitchenRepo
+	WebhookRepo     webhook_repo.WebhookRepo
+	PickupRepo      pickup_repo.PickupRepo
+
+	db *sql.DB
 }
 
 func New(db *sql.DB) *Container {
 	return &Container{
-		OrderRepo:     order_repo.NewOrderRepo(db),
-		CustomerRepo:  customer_repo.NewCustomerRepo(db),
-		MenuRepo:      menu_repo.NewMenuRepo(db),
-		InventoryRepo: inventory_repo.NewInventoryRepo(db),
-		StatsRepo:     stats_repo.NewStatsRepo(db),
+		OrderRepo:       order_repo.NewOrderRepo(db),
+		CustomerRepo:    customer_repo.NewCustomerRepo(db),
+		MenuRepo:        menu_repo.NewMenuRepo(db),
+		InventoryRepo:   inventory_repo.NewInventoryRepo(db),
+		StatsRepo:       stats_repo.NewStatsRepo(db),
+		StockTakeRepo:   stocktake_repo.NewStockTakeRepo(db),
+		ReportRepo:      report_repo.NewReportRepo(db),
+		PricingRepo:     pricing_repo.NewPricingRepo(db),
+		CategoryRepo:    category_repo.NewCategoryRepo(db),
+		MenuVersionRepo: menuversion_repo.NewMenuVersionRepo(db),
+		KitchenRepo:     kitchen_repo.NewKitchenRepo(db),
+		WebhookRepo:     webhook_repo.NewWebhookRepo(db),
+		PickupRepo:      pickup_repo.NewPickupRepo(db),
+		db:              db,
+	}
+}
+
+// InTx runs fn on a container whose inventory, menu and category repos share
+// one transaction, committed when fn succeeds and rolled back when it fails.
+// The other repos of the container keep running on the database.
+func (c *Container) InTx(ctx context.Context, fn func(tx *Container) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	txc := *c
+	txc.InventoryRepo = inventory_repo.NewInventoryRepoTx(c.db, tx)
+	txc.MenuRepo = menu_repo.NewMenuRepoTx(c.db, tx)
+	txc.CategoryRepo = category_repo.NewCategoryRepoTx(c.db, tx)
+	if err := fn(&txc); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
 	}
+	return nil
 }
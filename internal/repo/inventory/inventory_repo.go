@@ -4,31 +4,95 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
+	"time"
 
 	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
 )
 
 type InventoryRepo interface {
-	GetAllInventory(ctx context.Context) ([]*models.InventoryItem, error)
+	GetAllInventory(ctx context.Context, includeArchived bool) ([]*models.InventoryItem, error)
 	GetInventoryByID(ctx context.Context, id string) (*models.InventoryItem, error)
 	CreateInventory(ctx context.Context, item *models.InventoryItem) error
 	UpdateInventoryByID(ctx context.Context, id string, item *models.InventoryItem) error
-	DeleteInventoryByID(ctx context.Context, id string) error
+	SetArchived(ctx context.Context, id string, at *time.Time) error
+	GetActiveUsers(ctx context.Context, id string) ([]string, error)
 	GetInventoryList(ctx context.Context, sortBy string, page, pageSize int) ([]models.InventoryItem, int, bool, int, error)
+	ReceiveStock(ctx context.Context, id string, receipt *models.StockReceipt) error
+	GetCostHistory(ctx context.Context, id string) ([]*models.InventoryTransaction, error)
+	RecordWaste(ctx context.Context, id string, waste *models.WasteRecord) error
+	GetRecipeGraph(ctx context.Context) (map[string]*models.RecipeNode, error)
+	ProducePrep(ctx context.Context, item *models.InventoryItem, batches, unitCost float64) (*models.PrepBatch, error)
+	GetPrepBatches(ctx context.Context, id string) ([]*models.PrepBatch, error)
+}
+
+// querier runs the inventory queries, on the database or in a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txn is the transaction of a write: its own, or the one the repo runs in.
+type txn interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+// joined is a transaction a write joins; whoever began it commits or rolls
+// it back.
+type joined struct {
+	*sql.Tx
 }
 
+func (joined) Commit() error   { return nil }
+func (joined) Rollback() error { return nil }
+
 type inventoryRepo struct {
 	DB *sql.DB
+	// q is DB, or the transaction given to NewInventoryRepoTx.
+	q querier
 }
 
 func NewInventoryRepo(db *sql.DB) InventoryRepo {
-	return &inventoryRepo{DB: db}
+	return &inventoryRepo{
+		DB: db,
+		q:  db,
+	}
 }
 
-func (r *inventoryRepo) GetAllInventory(ctx context.Context) ([]*models.InventoryItem, error) {
-	rows, err := r.DB.QueryContext(ctx, `
-		SELECT Inventory_ID, Name, Quantity, Unit, Price FROM Inventory
-	`)
+// NewInventoryRepoTx returns a repo whose queries and writes run in tx.
+func NewInventoryRepoTx(db *sql.DB, tx *sql.Tx) InventoryRepo {
+	return &inventoryRepo{
+		DB: db,
+		q:  tx,
+	}
+}
+
+// begin starts the transaction of a write, or joins the one the repo runs in.
+func (r *inventoryRepo) begin(ctx context.Context) (txn, error) {
+	if tx, ok := r.q.(*sql.Tx); ok {
+		return joined{tx}, nil
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// GetAllInventory lists the inventory; archived items only when asked for.
+func (r *inventoryRepo) GetAllInventory(ctx context.Context, includeArchived bool) ([]*models.InventoryItem, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT Inventory_ID, Name, Quantity, Unit, Price, Is_Prepared, Yield_Quantity, Shelf_Life_Hours,
+			Allergens, Nutrition, Reorder_Level, Archived_At
+		FROM Inventory
+		WHERE $1 OR Archived_At IS NULL
+		ORDER BY Inventory_ID
+	`, includeArchived)
 	if err != nil {
 		return nil, err
 	}
@@ -43,59 +107,176 @@ func (r *inventoryRepo) GetAllInventory(ctx context.Context) ([]*models.Inventor
 			&item.Stock,
 			&item.Measure,
 			&item.UnitCost,
+			&item.Prepared,
+			&item.Yield,
+			&item.ShelfLifeHours,
+			&item.Allergens,
+			&item.Nutrition,
+			&item.ReorderLevel,
+			&item.ArchivedAt,
 		); err != nil {
 			return nil, err
 		}
 		items = append(items, &item)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.loadRecipes(ctx, items); err != nil {
+		return nil, err
+	}
 	return items, nil
 }
 
 func (r *inventoryRepo) GetInventoryByID(ctx context.Context, id string) (*models.InventoryItem, error) {
 	var item models.InventoryItem
-	err := r.DB.QueryRowContext(ctx,
-		`SELECT Inventory_ID, Name, Quantity, Unit, Price FROM Inventory WHERE Inventory_ID = $1`, id).
-		Scan(&item.IngredientID, &item.Title, &item.Stock, &item.Measure, &item.UnitCost)
+	err := r.q.QueryRowContext(ctx,
+		`SELECT Inventory_ID, Name, Quantity, Unit, Price, Is_Prepared, Yield_Quantity, Shelf_Life_Hours,
+			Allergens, Nutrition, Reorder_Level, Archived_At
+		FROM Inventory WHERE Inventory_ID = $1`, id).
+		Scan(&item.IngredientID, &item.Title, &item.Stock, &item.Measure, &item.UnitCost,
+			&item.Prepared, &item.Yield, &item.ShelfLifeHours, &item.Allergens, &item.Nutrition,
+			&item.ReorderLevel, &item.ArchivedAt)
 	if err != nil {
 		return nil, err
 	}
+
+	if err := r.loadRecipes(ctx, []*models.InventoryItem{&item}); err != nil {
+		return nil, err
+	}
 	return &item, nil
 }
 
 func (r *inventoryRepo) CreateInventory(ctx context.Context, item *models.InventoryItem) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO Inventory (Name, Quantity, Unit, Price)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO Inventory (Name, Quantity, Unit, Price, Is_Prepared, Yield_Quantity, Shelf_Life_Hours, Allergens, Nutrition, Reorder_Level)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING Inventory_ID
 	`
-	_, err := r.DB.ExecContext(ctx, query,
+	var id int
+	err = tx.QueryRowContext(ctx, query,
 		item.Title,
 		item.Stock,
 		item.Measure,
 		item.UnitCost,
-	)
-	return err
+		item.Prepared,
+		item.Yield,
+		item.ShelfLifeHours,
+		item.Allergens,
+		item.Nutrition,
+		item.ReorderLevel,
+	).Scan(&id)
+	if err != nil {
+		return err
+	}
+	item.IngredientID = strconv.Itoa(id)
+
+	if err := saveRecipe(ctx, tx, item.IngredientID, item.Recipe); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *inventoryRepo) UpdateInventoryByID(ctx context.Context, id string, item *models.InventoryItem) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE Inventory 
-		SET Name = $1, Quantity = $2, Unit = $3, Price = $4 
-		WHERE Inventory_ID = $5
+		SET Name = $1, Quantity = $2, Unit = $3, Price = $4,
+			Is_Prepared = $5, Yield_Quantity = $6, Shelf_Life_Hours = $7,
+			Allergens = $8, Nutrition = $9, Reorder_Level = $10
+		WHERE Inventory_ID = $11
 	`
-	_, err := r.DB.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		item.Title,
 		item.Stock,
 		item.Measure,
 		item.UnitCost,
+		item.Prepared,
+		item.Yield,
+		item.ShelfLifeHours,
+		item.Allergens,
+		item.Nutrition,
+		item.ReorderLevel,
 		id,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM Prep_Recipe_Ingredients WHERE Prepared_ID = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	if item.Prepared {
+		if err := saveRecipe(ctx, tx, id, item.Recipe); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (r *inventoryRepo) DeleteInventoryByID(ctx context.Context, id string) error {
-	query := `DELETE FROM Inventory WHERE Inventory_ID = $1`
-	_, err := r.DB.ExecContext(ctx, query, id)
-	return err
+// SetArchived archives the item at the given moment; a nil at restores it.
+// Archived items keep their history, recipes and stock.
+func (r *inventoryRepo) SetArchived(ctx context.Context, id string, at *time.Time) error {
+	_, err := r.q.ExecContext(ctx, `UPDATE Inventory SET Archived_At = $1 WHERE Inventory_ID = $2`, at, id)
+	if err != nil {
+		return fmt.Errorf("set archived: %w", err)
+	}
+	return nil
+}
+
+// GetActiveUsers names the menu items and prepared items on hand that use the
+// inventory item, in a recipe or a modifier, leaving out archived ones.
+func (r *inventoryRepo) GetActiveUsers(ctx context.Context, id string) ([]string, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT mi.Name
+		FROM Menu_Item_Ingredients m
+		JOIN Menu_Items mi ON mi.Menu_Item_ID = m.Menu_Item_ID
+		WHERE m.Inventory_ID = $1 AND mi.Archived_At IS NULL
+		UNION
+		SELECT mi.Name
+		FROM Menu_Item_Modifiers m
+		JOIN Menu_Items mi ON mi.Menu_Item_ID = m.Menu_Item_ID
+		WHERE m.Inventory_ID = $1 AND mi.Archived_At IS NULL
+		UNION
+		SELECT i.Name
+		FROM Prep_Recipe_Ingredients p
+		JOIN Inventory i ON i.Inventory_ID = p.Prepared_ID
+		WHERE p.Inventory_ID = $1 AND i.Archived_At IS NULL
+		ORDER BY 1
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query inventory users: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan inventory user: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return names, nil
 }
 
 func (r *inventoryRepo) GetInventoryList(ctx context.Context, sortBy string, page, pageSize int) ([]models.InventoryItem, int, bool, int, error) {
@@ -118,11 +299,12 @@ func (r *inventoryRepo) GetInventoryList(ctx context.Context, sortBy string, pag
 	query := fmt.Sprintf(`
 		SELECT Inventory_ID, Name, Quantity, Unit, Price
 		FROM Inventory 
+		WHERE Archived_At IS NULL
 		ORDER BY %s ASC
 		LIMIT $1 OFFSET $2
 	`, sortColumn)
 
-	rows, err := r.DB.QueryContext(ctx, query, pageSize, offset)
+	rows, err := r.q.QueryContext(ctx, query, pageSize, offset)
 	if err != nil {
 		return nil, 0, false, 0, err
 	}
@@ -136,7 +318,7 @@ func (r *inventoryRepo) GetInventoryList(ctx context.Context, sortBy string, pag
 		results = append(results, item)
 	}
 
-	err = r.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM Inventory").Scan(&totalCount)
+	err = r.q.QueryRowContext(ctx, "SELECT COUNT(*) FROM Inventory WHERE Archived_At IS NULL").Scan(&totalCount)
 	if err != nil {
 		return nil, 0, false, 0, err
 	}
@@ -146,3 +328,341 @@ func (r *inventoryRepo) GetInventoryList(ctx context.Context, sortBy string, pag
 
 	return results, page, hasNextPage, totalPages, nil
 }
+
+// ReceiveStock books a delivery. The receipt cost becomes the current price of
+// the item, and the transaction trigger keeps it in the cost history.
+func (r *inventoryRepo) ReceiveStock(ctx context.Context, id string, receipt *models.StockReceipt) error {
+	query := `
+		UPDATE Inventory
+		SET Quantity = Quantity + $1, Price = $2
+		WHERE Inventory_ID = $3
+	`
+	res, err := r.q.ExecContext(ctx, query, receipt.Quantity, receipt.UnitCost, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *inventoryRepo) GetCostHistory(ctx context.Context, id string) ([]*models.InventoryTransaction, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT Transaction_ID, Inventory_ID, Change_Amount, Transaction_Type, Unit_Cost, Occurred_At
+		FROM Inventory_Transactions
+		WHERE Inventory_ID = $1
+		ORDER BY Occurred_At, Transaction_ID
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*models.InventoryTransaction
+	for rows.Next() {
+		var (
+			tx         models.InventoryTransaction
+			unitCost   sql.NullFloat64
+			occurredAt time.Time
+		)
+		if err := rows.Scan(&tx.ID, &tx.ItemRef, &tx.Delta, &tx.Type, &unitCost, &occurredAt); err != nil {
+			return nil, err
+		}
+		if unitCost.Valid {
+			tx.UnitCost = &unitCost.Float64
+		}
+		tx.OccurredAt = occurredAt.Format(time.RFC3339)
+		history = append(history, &tx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (r *inventoryRepo) RecordWaste(ctx context.Context, id string, waste *models.WasteRecord) error {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('frappuccino.transaction_type', 'waste', true)`)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE Inventory SET Quantity = Quantity - $1 WHERE Inventory_ID = $2
+	`, waste.Quantity, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// GetRecipeGraph loads every inventory item with its free stock (usable stock
+// minus open reservations) and, for prepared items, the recipe of one batch.
+func (r *inventoryRepo) GetRecipeGraph(ctx context.Context) (map[string]*models.RecipeNode, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT
+			i.Inventory_ID,
+			i.Name,
+			i.Is_Prepared,
+			COALESCE(i.Yield_Quantity, 0),
+			i.Price,
+			u.Quantity - COALESCE((
+				SELECT SUM(ir.Reserved_Quantity)
+				FROM Inventory_Reservations ir
+				WHERE ir.Inventory_ID = i.Inventory_ID
+			), 0),
+			i.Allergens,
+			i.Nutrition,
+			i.Archived_At IS NOT NULL
+		FROM Inventory i
+		JOIN Usable_Stock u ON u.Inventory_ID = i.Inventory_ID
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query recipe nodes: %w", err)
+	}
+	defer rows.Close()
+
+	graph := make(map[string]*models.RecipeNode)
+	for rows.Next() {
+		var (
+			node models.RecipeNode
+			id   int
+		)
+		if err := rows.Scan(&id, &node.Title, &node.Prepared, &node.Yield, &node.UnitCost, &node.Available,
+			(*pq.StringArray)(&node.Allergens), &node.Nutrition, &node.Archived); err != nil {
+			return nil, fmt.Errorf("scan recipe node: %w", err)
+		}
+		node.ItemRef = strconv.Itoa(id)
+		graph[node.ItemRef] = &node
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	edges, err := r.q.QueryContext(ctx, `
+		SELECT p.Prepared_ID, p.Inventory_ID, i.Name, p.Quantity
+		FROM Prep_Recipe_Ingredients p
+		JOIN Inventory i ON i.Inventory_ID = p.Inventory_ID
+		ORDER BY p.Prep_Recipe_Ingredient_ID
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query recipe edges: %w", err)
+	}
+	defer edges.Close()
+
+	for edges.Next() {
+		var (
+			line          models.ProductComponent
+			prepID, invID int
+		)
+		if err := edges.Scan(&prepID, &invID, &line.ComponentName, &line.RequiredQty); err != nil {
+			return nil, fmt.Errorf("scan recipe edge: %w", err)
+		}
+		line.ComponentID = strconv.Itoa(invID)
+		if node, ok := graph[strconv.Itoa(prepID)]; ok {
+			node.Recipe = append(node.Recipe, &line)
+		}
+	}
+	if err := edges.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return graph, nil
+}
+
+// ProducePrep turns raw stock into prep stock: the recipe ingredients of the
+// requested batches are deducted, the yield is added at the given unit cost
+// and the batch is recorded with its expiry date. Only free stock is used:
+// usable stock that open orders have not reserved.
+func (r *inventoryRepo) ProducePrep(ctx context.Context, item *models.InventoryItem, batches, unitCost float64) (*models.PrepBatch, error) {
+	tx, err := r.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('frappuccino.transaction_type', 'production', true)`)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(item.Recipe))
+	for _, line := range item.Recipe {
+		id, err := strconv.Atoi(line.ComponentID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ingredient ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	// Lock the ingredients so that the free stock checked below cannot be
+	// spent by another production before it is deducted
+	_, err = tx.ExecContext(ctx, `
+		SELECT Inventory_ID FROM Inventory
+		WHERE Inventory_ID = ANY($1)
+		ORDER BY Inventory_ID
+		FOR UPDATE
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("lock ingredients: %w", err)
+	}
+
+	for i, line := range item.Recipe {
+		var free float64
+		err := tx.QueryRowContext(ctx, `
+			SELECT u.Quantity - COALESCE((
+				SELECT SUM(ir.Reserved_Quantity)
+				FROM Inventory_Reservations ir
+				WHERE ir.Inventory_ID = u.Inventory_ID
+			), 0)
+			FROM Usable_Stock u
+			WHERE u.Inventory_ID = $1
+		`, ids[i]).Scan(&free)
+		if err != nil {
+			return nil, fmt.Errorf("query free stock: %w", err)
+		}
+
+		need := line.RequiredQty * batches
+		if free < need-1e-9 {
+			return nil, fmt.Errorf("not enough %s to produce %g batches: %w", line.ComponentName, batches, models.ErrInventoryNotAvailable)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE Inventory SET Quantity = Quantity - $1 WHERE Inventory_ID = $2
+		`, need, ids[i])
+		if err != nil {
+			return nil, fmt.Errorf("deduct ingredient: %w", err)
+		}
+	}
+
+	batch := &models.PrepBatch{
+		ItemRef:  item.IngredientID,
+		Quantity: batches * *item.Yield,
+		UnitCost: unitCost,
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE Inventory SET Quantity = Quantity + $1, Price = $2 WHERE Inventory_ID = $3
+	`, batch.Quantity, unitCost, item.IngredientID)
+	if err != nil {
+		return nil, fmt.Errorf("add prep stock: %w", err)
+	}
+
+	var batchID int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO Prep_Batches (Inventory_ID, Quantity, Unit_Cost, Expires_At)
+		VALUES ($1, $2, $3, NOW() + make_interval(hours => $4))
+		RETURNING Batch_ID, Produced_At, Expires_At
+	`, item.IngredientID, batch.Quantity, unitCost, item.ShelfLifeHours).Scan(&batchID, &batch.Produced, &batch.Expires)
+	if err != nil {
+		return nil, fmt.Errorf("insert batch: %w", err)
+	}
+	batch.BatchID = strconv.Itoa(batchID)
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (r *inventoryRepo) GetPrepBatches(ctx context.Context, id string) ([]*models.PrepBatch, error) {
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT Batch_ID, Inventory_ID, Quantity, Unit_Cost, Produced_At, Expires_At,
+		       COALESCE(Expires_At <= NOW(), FALSE)
+		FROM Prep_Batches
+		WHERE Inventory_ID = $1
+		ORDER BY Produced_At DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []*models.PrepBatch
+	for rows.Next() {
+		var batch models.PrepBatch
+		err := rows.Scan(
+			&batch.BatchID,
+			&batch.ItemRef,
+			&batch.Quantity,
+			&batch.UnitCost,
+			&batch.Produced,
+			&batch.Expires,
+			&batch.IsExpired,
+		)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, &batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+func (r *inventoryRepo) loadRecipes(ctx context.Context, items []*models.InventoryItem) error {
+	byID := make(map[string]*models.InventoryItem)
+	var ids []string
+	for _, item := range items {
+		if item.Prepared {
+			byID[item.IngredientID] = item
+			ids = append(ids, item.IngredientID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT p.Prepared_ID, p.Inventory_ID, i.Name, p.Quantity
+		FROM Prep_Recipe_Ingredients p
+		JOIN Inventory i ON i.Inventory_ID = p.Inventory_ID
+		WHERE p.Prepared_ID = ANY($1::int[])
+		ORDER BY p.Prep_Recipe_Ingredient_ID
+	`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			line          models.ProductComponent
+			prepID, invID int
+		)
+		if err := rows.Scan(&prepID, &invID, &line.ComponentName, &line.RequiredQty); err != nil {
+			return err
+		}
+		line.ComponentID = strconv.Itoa(invID)
+		if item, ok := byID[strconv.Itoa(prepID)]; ok {
+			item.Recipe = append(item.Recipe, &line)
+		}
+	}
+	return rows.Err()
+}
+
+func saveRecipe(ctx context.Context, tx querier, id string, recipe []*models.ProductComponent) error {
+	query := `
+		INSERT INTO Prep_Recipe_Ingredients (Prepared_ID, Inventory_ID, Quantity)
+		VALUES ($1, $2, $3)
+	`
+	for _, line := range recipe {
+		if _, err := tx.ExecContext(ctx, query, id, line.ComponentID, line.RequiredQty); err != nil {
+			return fmt.Errorf("insert recipe line: %w", err)
+		}
+	}
+	return nil
+}
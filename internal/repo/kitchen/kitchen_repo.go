@@ -0,0 +1,241 @@
+package kitchen_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+type KitchenRepo interface {
+	GetQueue(ctx context.Context, lead time.Duration) ([]*models.KitchenTicket, error)
+	GetTicket(ctx context.Context, orderID string, lead time.Duration) (*models.KitchenTicket, error)
+	GetReleased(ctx context.Context, lead, window time.Duration) ([]string, error)
+	SetStatus(ctx context.Context, orderID, lineID, status string) (before, after string, err error)
+}
+
+type kitchenRepo struct {
+	DB *sql.DB
+}
+
+func NewKitchenRepo(db *sql.DB) KitchenRepo {
+	return &kitchenRepo{
+		DB: db,
+	}
+}
+
+// GetQueue lists the orders the kitchen still works on or has ready for
+// pickup, first queued first. An order placed ahead joins the queue lead
+// before its pickup time.
+func (r *kitchenRepo) GetQueue(ctx context.Context, lead time.Duration) ([]*models.KitchenTicket, error) {
+	return r.loadTickets(ctx, nil, lead)
+}
+
+// GetTicket returns one order of the queue, or sql.ErrNoRows when the order
+// is not on it.
+func (r *kitchenRepo) GetTicket(ctx context.Context, orderID string, lead time.Duration) (*models.KitchenTicket, error) {
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid order ID: %w", err)
+	}
+
+	tickets, err := r.loadTickets(ctx, &id, lead)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return tickets[0], nil
+}
+
+// GetReleased returns the orders placed ahead that joined the queue within
+// the last window.
+func (r *kitchenRepo) GetReleased(ctx context.Context, lead, window time.Duration) ([]string, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT Order_ID
+		FROM Orders
+		CROSS JOIN LATERAL (SELECT Pickup_At - make_interval(secs => $1) AS Queued_At) q
+		WHERE Status = 'open' AND Pickup_At IS NOT NULL
+		  AND q.Queued_At > Created_At
+		  AND q.Queued_At <= LOCALTIMESTAMP
+		  AND q.Queued_At > LOCALTIMESTAMP - make_interval(secs => $2)
+		ORDER BY q.Queued_At, Order_ID
+	`, lead.Seconds(), window.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("query released orders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan order id: %w", err)
+		}
+		ids = append(ids, strconv.Itoa(id))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (r *kitchenRepo) loadTickets(ctx context.Context, orderID *int, lead time.Duration) ([]*models.KitchenTicket, error) {
+	query := `
+		SELECT o.Order_ID, o.Customer_ID, o.Status, o.Channel, o.Table_Number, o.Pickup_At::TIMESTAMPTZ, o.Created_At,
+		       EXTRACT(EPOCH FROM NOW() - q.Queued_At)::BIGINT,
+		       oi.Order_Item_ID, oi.Menu_Item_ID, COALESCE(mi.Name, ''), oi.Quantity, oi.Customization,
+		       COALESCE(b.Name, ''), oi.Kitchen_Status, oi.Kitchen_Updated_At
+		FROM Orders o
+		CROSS JOIN LATERAL (
+			SELECT GREATEST(o.Created_At, o.Pickup_At - make_interval(secs => $2)) AS Queued_At
+		) q
+		JOIN Order_Items oi ON oi.Order_ID = o.Order_ID AND NOT oi.Is_Bundle
+		LEFT JOIN Menu_Items mi ON mi.Menu_Item_ID = oi.Menu_Item_ID
+		LEFT JOIN Order_Items p ON p.Order_Item_ID = oi.Parent_Item_ID
+		LEFT JOIN Menu_Items b ON b.Menu_Item_ID = p.Menu_Item_ID
+		WHERE o.Status IN ('open', 'in_progress', 'ready')
+		  AND q.Queued_At <= LOCALTIMESTAMP
+		  AND ($1::INTEGER IS NULL OR o.Order_ID = $1)
+		ORDER BY q.Queued_At, o.Order_ID, oi.Order_Item_ID
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, orderID, lead.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("query kitchen queue: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []*models.KitchenTicket{}
+	var current *models.KitchenTicket
+	for rows.Next() {
+		var (
+			ticket        models.KitchenTicket
+			line          models.KitchenLine
+			id, lineID    int
+			waitSeconds   int64
+			itemID        sql.NullInt64
+			quantity      float64
+			customization sql.NullString
+			updatedAt     sql.NullTime
+		)
+		err := rows.Scan(&id, &ticket.CustomerID, &ticket.Status, &ticket.Channel, &ticket.TableNumber, &ticket.PickupAt, &ticket.PlacedAt, &waitSeconds,
+			&lineID, &itemID, &line.Title, &quantity, &customization,
+			&line.Bundle, &line.Status, &updatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scan kitchen line: %w", err)
+		}
+
+		if current == nil || current.OrderID != strconv.Itoa(id) {
+			ticket.OrderID = strconv.Itoa(id)
+			ticket.WaitSeconds = int(waitSeconds)
+			ticket.Lines = []*models.KitchenLine{}
+			current = &ticket
+			tickets = append(tickets, current)
+		}
+
+		line.LineID = strconv.Itoa(lineID)
+		if itemID.Valid {
+			line.ItemID = strconv.FormatInt(itemID.Int64, 10)
+		}
+		line.Count = int(quantity)
+		if customization.Valid {
+			if err := json.Unmarshal([]byte(customization.String), &line.Adjustments); err != nil {
+				return nil, fmt.Errorf("unmarshal customization: %w", err)
+			}
+		}
+		if updatedAt.Valid {
+			line.UpdatedAt = &updatedAt.Time
+		}
+		current.Lines = append(current.Lines, &line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return tickets, nil
+}
+
+// SetStatus moves one line of an order, or all of them when lineID is empty,
+// to a kitchen status and derives the order status from its lines. A change
+// of the order status is recorded in its history. It returns the order
+// status before and after, or sql.ErrNoRows when the order is not in the
+// queue.
+func (r *kitchenRepo) SetStatus(ctx context.Context, orderID, lineID, status string) (string, string, error) {
+	id, err := strconv.Atoi(orderID)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid order ID: %w", err)
+	}
+	var line *int
+	if lineID != "" {
+		n, err := strconv.Atoi(lineID)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid line ID: %w", err)
+		}
+		line = &n
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, `
+		SELECT Status FROM Orders
+		WHERE Order_ID = $1 AND Status IN ('open', 'in_progress', 'ready')
+		FOR UPDATE
+	`, id).Scan(&current)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE Order_Items
+		SET Kitchen_Status = $3, Kitchen_Updated_At = NOW()
+		WHERE Order_ID = $1 AND NOT Is_Bundle
+		  AND ($2::INTEGER IS NULL OR Order_Item_ID = $2)
+		  AND Kitchen_Status <> $3
+	`, id, line, status)
+	if err != nil {
+		return "", "", fmt.Errorf("update lines: %w", err)
+	}
+
+	var total, started, ready int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE Kitchen_Status <> 'queued'),
+		       COUNT(*) FILTER (WHERE Kitchen_Status = 'ready')
+		FROM Order_Items
+		WHERE Order_ID = $1 AND NOT Is_Bundle
+	`, id).Scan(&total, &started, &ready)
+	if err != nil {
+		return "", "", fmt.Errorf("count lines: %w", err)
+	}
+
+	next := models.KitchenOrderStatus(total, started, ready)
+	_, err = tx.ExecContext(ctx, `UPDATE Orders SET Status = $2, Updated_At = NOW() WHERE Order_ID = $1`, id, next)
+	if err != nil {
+		return "", "", fmt.Errorf("update order: %w", err)
+	}
+	if next != current {
+		_, err = tx.ExecContext(ctx, `INSERT INTO Order_Status_History (Order_ID, Status) VALUES ($1, $2)`, id, next)
+		if err != nil {
+			return "", "", fmt.Errorf("insert status history: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", "", err
+	}
+	return current, next, nil
+}
@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"frappuccino/internal/models"
 
@@ -13,98 +15,190 @@ import (
 
 type MenuRepo interface {
 	GetAllProducts(ctx context.Context) (products []*models.Product, err error)
+	FindProducts(ctx context.Context, filter *models.MenuFilter) (products []*models.Product, total int, err error)
 	FetchProductsByIDs(ctx context.Context, ids []string) (products []*models.Product, err error)
 	CreateProduct(ctx context.Context, product *models.Product) (err error)
 	GetProductByID(ctx context.Context, id string) (product *models.Product, err error)
 	UpdateProduct(ctx context.Context, id string, product *models.Product) (err error)
 	UpdatePrice(ctx context.Context, ph *models.PriceHistory) (err error)
-	DeleteProduct(ctx context.Context, id string) (err error)
+	GetPriceHistory(ctx context.Context, id string) (history []*models.PriceHistory, err error)
+	SetArchived(ctx context.Context, id string, at *time.Time) (err error)
+	SetSoldOut(ctx context.Context, id string, until *time.Time) (err error)
 }
 
 type menuRepo struct {
 	DB *sql.DB
+	// q is DB, or the transaction given to NewMenuRepoTx.
+	q querier
 }
 
+// querier runs the menu queries, on the database or in a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txn is the transaction of a write: its own, or the one the repo runs in.
+type txn interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+// joined is a transaction a write joins; whoever began it commits or rolls
+// it back.
+type joined struct {
+	*sql.Tx
+}
+
+func (joined) Commit() error   { return nil }
+func (joined) Rollback() error { return nil }
+
 func NewMenuRepo(db *sql.DB) MenuRepo {
 	return &menuRepo{
 		DB: db,
+		q:  db,
 	}
 }
 
-func (m *menuRepo) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
-	query := `
-		SELECT 
-			Menu_Item_ID, 
-			Name, 
-			Description, 
-			Price, 
-			Size, 
-			Category, 
-			Tags, 
-			Metadata 
-		FROM Menu_Items
-	`
+// NewMenuRepoTx returns a repo whose queries and writes run in tx.
+func NewMenuRepoTx(db *sql.DB, tx *sql.Tx) MenuRepo {
+	return &menuRepo{
+		DB: db,
+		q:  tx,
+	}
+}
 
-	rows, err := m.DB.QueryContext(ctx, query)
+// begin starts the transaction of a write, or joins the one the repo runs in.
+func (m *menuRepo) begin(ctx context.Context) (txn, error) {
+	if tx, ok := m.q.(*sql.Tx); ok {
+		return joined{tx}, nil
+	}
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("query all products: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
+	return tx, nil
+}
 
-	var products []*models.Product
+const selectProducts = `
+	SELECT
+		mi.Menu_Item_ID,
+		mi.Name,
+		mi.Description,
+		mi.Price,
+		mi.Size,
+		mi.Category_ID,
+		c.Name,
+		mi.Tags,
+		mi.Metadata,
+		mi.Sold_Out_Until,
+		mi.Is_Bundle,
+		mi.Archived_At
+	FROM Menu_Items mi
+	JOIN Categories c ON c.Category_ID = mi.Category_ID
+`
+
+// menuSortColumns maps the sort keys of a listing to their columns. Every
+// order ends on the id so pages never overlap.
+var menuSortColumns = map[string]string{
+	"id":       "mi.Menu_Item_ID",
+	"title":    "LOWER(mi.Name)",
+	"price":    "mi.Price",
+	"category": "c.Display_Order, LOWER(c.Name)",
+}
 
-	for rows.Next() {
-		var (
-			id   int
-			item models.Product
-		)
+func (m *menuRepo) GetAllProducts(ctx context.Context) ([]*models.Product, error) {
+	products, _, err := m.FindProducts(ctx, &models.MenuFilter{})
+	return products, err
+}
 
-		err := rows.Scan(
-			&id,
-			&item.Title,
-			&item.Details,
-			&item.UnitPrice,
-			&item.SizeLabel,
-			&item.Group,
-			&item.Labels,
-			&item.Extras,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan product: %w", err)
+// FindProducts lists the menu items matching the filter in the requested
+// order, along with the number of matching items before paging. A category
+// filter also matches the items of its subcategories. Archived items are left
+// out unless the filter includes them.
+func (m *menuRepo) FindProducts(ctx context.Context, f *models.MenuFilter) ([]*models.Product, int, error) {
+	var (
+		conds []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if !f.IncludeArchived {
+		conds = append(conds, "mi.Archived_At IS NULL")
+	}
+	if f.CategoryID != "" {
+		conds = append(conds, `mi.Category_ID IN (
+			WITH RECURSIVE Tree AS (
+				SELECT Category_ID FROM Categories WHERE Category_ID::TEXT = `+arg(f.CategoryID)+`
+				UNION
+				SELECT ch.Category_ID FROM Categories ch JOIN Tree t ON ch.Parent_ID = t.Category_ID
+			)
+			SELECT Category_ID FROM Tree
+		)`)
+	}
+	if len(f.Tags) > 0 {
+		op := "&&"
+		if f.AllTags {
+			op = "@>"
 		}
+		conds = append(conds, "mi.Tags "+op+" "+arg(pq.StringArray(f.Tags))+"::TEXT[]")
+	}
+	if f.Size != "" {
+		conds = append(conds, "mi.Size::TEXT = "+arg(f.Size))
+	}
+	if f.MinPrice != nil {
+		conds = append(conds, "mi.Price >= "+arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conds = append(conds, "mi.Price <= "+arg(*f.MaxPrice))
+	}
+	if f.Search != "" {
+		conds = append(conds, "STRPOS(LOWER(mi.Name || ' ' || COALESCE(mi.Description, '')), LOWER("+arg(f.Search)+")) > 0")
+	}
 
-		item.ProductID = strconv.Itoa(id)
-		item.Components = []*models.ProductComponent{}
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
 
-		if err := m.loadProductComponents(ctx, &item); err != nil {
-			return nil, fmt.Errorf("load components: %w", err)
-		}
+	order := menuSortColumns[f.SortBy]
+	if order == "" {
+		order = menuSortColumns["id"]
+	}
+	if f.Desc {
+		order = strings.ReplaceAll(order, ",", " DESC,") + " DESC"
+	}
+	query := selectProducts + where + " ORDER BY " + order + ", mi.Menu_Item_ID"
+	if f.Paged() {
+		query += " LIMIT " + arg(f.PageSize) + " OFFSET " + arg(f.Offset())
+	}
 
-		products = append(products, &item)
+	products, err := m.queryProducts(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
+	total := len(products)
+	if f.Paged() {
+		countArgs := args[:len(args)-2]
+		err := m.q.QueryRowContext(ctx, `
+			SELECT COUNT(*)
+			FROM Menu_Items mi
+			JOIN Categories c ON c.Category_ID = mi.Category_ID`+where, countArgs...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("count products: %w", err)
+		}
 	}
 
-	return products, nil
+	return products, total, nil
 }
 
 func (m *menuRepo) FetchProductsByIDs(ctx context.Context, ids []string) ([]*models.Product, error) {
-	query := `
-		SELECT 
-			Menu_Item_ID, 
-			Name, 
-			Description, 
-			Price, 
-			Size, 
-			Category, 
-			Tags, 
-			Metadata 
-		FROM Menu_Items
-		WHERE Menu_Item_ID = ANY($1)
-	`
-
 	intIDs := make([]int, len(ids))
 	for i, s := range ids {
 		id, err := strconv.Atoi(s)
@@ -114,68 +208,92 @@ func (m *menuRepo) FetchProductsByIDs(ctx context.Context, ids []string) ([]*mod
 		intIDs[i] = id
 	}
 
-	rows, err := m.DB.QueryContext(ctx, query, pq.Array(intIDs))
+	return m.queryProducts(ctx, selectProducts+` WHERE mi.Menu_Item_ID = ANY($1)`, pq.Array(intIDs))
+}
+
+// queryProducts runs a product query and loads the components, modifiers and
+// slots of all the products found with one query each.
+func (m *menuRepo) queryProducts(ctx context.Context, query string, args ...any) ([]*models.Product, error) {
+	rows, err := m.q.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query products by ids: %w", err)
+		return nil, fmt.Errorf("query products: %w", err)
 	}
 	defer rows.Close()
 
-	var products []*models.Product
-
+	products := []*models.Product{}
 	for rows.Next() {
-		var (
-			id   int
-			item models.Product
-		)
-
-		err := rows.Scan(
-			&id,
-			&item.Title,
-			&item.Details,
-			&item.UnitPrice,
-			&item.SizeLabel,
-			&item.Group,
-			&item.Labels,
-			&item.Extras,
-		)
+		item, err := scanProduct(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scan product: %w", err)
 		}
-
-		item.ProductID = strconv.Itoa(id)
-		item.Components = []*models.ProductComponent{}
-
-		if err := m.loadProductComponents(ctx, &item); err != nil {
-			return nil, fmt.Errorf("load components: %w", err)
-		}
-
-		products = append(products, &item)
+		products = append(products, item)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
+	rows.Close()
+
+	if err := m.loadProductComponents(ctx, products); err != nil {
+		return nil, fmt.Errorf("load components: %w", err)
+	}
+	if err := m.loadModifiers(ctx, products); err != nil {
+		return nil, fmt.Errorf("load modifiers: %w", err)
+	}
+	if err := m.loadBundleSlots(ctx, products); err != nil {
+		return nil, fmt.Errorf("load slots: %w", err)
+	}
 
 	return products, nil
 }
 
+func scanProduct(row interface{ Scan(...any) error }) (*models.Product, error) {
+	var (
+		id   int
+		item models.Product
+	)
+
+	err := row.Scan(
+		&id,
+		&item.Title,
+		&item.Details,
+		&item.UnitPrice,
+		&item.SizeLabel,
+		&item.CategoryID,
+		&item.Group,
+		&item.Labels,
+		&item.Extras,
+		&item.SoldOutUntil,
+		&item.Bundle,
+		&item.ArchivedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	item.ProductID = strconv.Itoa(id)
+	item.Components = []*models.ProductComponent{}
+	return &item, nil
+}
+
 func (m *menuRepo) CreateProduct(ctx context.Context, product *models.Product) error {
 	query := `
 		INSERT INTO Menu_Items (
-			Name, Description, Price, Size, Category, Tags, Metadata
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			Name, Description, Price, Size, Category_ID, Tags, Metadata, Is_Bundle
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING Menu_Item_ID
 	`
 
 	var newID int
-	err := m.DB.QueryRowContext(ctx, query,
+	err := m.q.QueryRowContext(ctx, query,
 		product.Title,
 		product.Details,
 		product.UnitPrice,
 		product.SizeLabel,
-		product.Group,
+		product.CategoryID,
 		product.Labels,
 		product.Extras,
+		product.Bundle,
 	).Scan(&newID)
 	if err != nil {
 		return fmt.Errorf("insert menu item: %w", err)
@@ -194,12 +312,22 @@ func (m *menuRepo) CreateProduct(ctx context.Context, product *models.Product) e
 			return fmt.Errorf("convert component ID: %w", err)
 		}
 
-		_, err = m.DB.ExecContext(ctx, insertComponent, newID, invID, comp.RequiredQty)
+		_, err = m.q.ExecContext(ctx, insertComponent, newID, invID, comp.RequiredQty)
 		if err != nil {
 			return fmt.Errorf("insert ingredient: %w", err)
 		}
 	}
 
+	if err := insertModifiers(ctx, m.q, newID, product.Modifiers); err != nil {
+		return fmt.Errorf("insert modifiers: %w", err)
+	}
+
+	for pos, slot := range product.Slots {
+		if _, err := insertBundleSlot(ctx, m.q, newID, pos, slot); err != nil {
+			return fmt.Errorf("insert slots: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -209,65 +337,62 @@ func (m *menuRepo) GetProductByID(ctx context.Context, id string) (*models.Produ
 		return nil, fmt.Errorf("invalid product ID: %w", err)
 	}
 
-	query := `
-		SELECT Menu_Item_ID, Name, Description, Price, Size, Category, Tags, Metadata
-		FROM Menu_Items
-		WHERE Menu_Item_ID = $1
-	`
-
-	var prod models.Product
-	var prodID int
-
-	err = m.DB.QueryRowContext(ctx, query, intID).Scan(
-		&prodID,
-		&prod.Title,
-		&prod.Details,
-		&prod.UnitPrice,
-		&prod.SizeLabel,
-		&prod.Group,
-		&prod.Labels,
-		&prod.Extras,
-	)
+	prod, err := scanProduct(m.q.QueryRowContext(ctx, selectProducts+` WHERE mi.Menu_Item_ID = $1`, intID))
 	if err != nil {
 		return nil, fmt.Errorf("query product: %w", err)
 	}
-	prod.ProductID = strconv.Itoa(prodID)
-	prod.Components = []*models.ProductComponent{}
 
-	err = m.loadProductComponents(ctx, &prod)
-	if err != nil {
+	products := []*models.Product{prod}
+	if err = m.loadProductComponents(ctx, products); err != nil {
 		return nil, fmt.Errorf("load components: %w", err)
 	}
+	if err = m.loadModifiers(ctx, products); err != nil {
+		return nil, fmt.Errorf("load modifiers: %w", err)
+	}
+	if err = m.loadBundleSlots(ctx, products); err != nil {
+		return nil, fmt.Errorf("load slots: %w", err)
+	}
 
-	return &prod, nil
+	return prod, nil
 }
 
+// UpdateProduct rewrites the item, its components, its modifiers and its
+// bundle slots in one transaction. Slots keep their id, so order lines of the
+// bundle still point to them: slots with an id are updated in place, slots
+// without one are added and slots left out are removed.
 func (m *menuRepo) UpdateProduct(ctx context.Context, id string, product *models.Product) error {
 	intID, err := strconv.Atoi(id)
 	if err != nil {
 		return fmt.Errorf("invalid product ID: %w", err)
 	}
 
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE Menu_Items
-		SET Name = $1, Description = $2, Price = $3, Size = $4, Category = $5, Tags = $6, Metadata = $7
-		WHERE Menu_Item_ID = $8
+		SET Name = $1, Description = $2, Price = $3, Size = $4, Category_ID = $5, Tags = $6, Metadata = $7, Is_Bundle = $8
+		WHERE Menu_Item_ID = $9
 	`
-	_, err = m.DB.ExecContext(ctx, query,
+	_, err = tx.ExecContext(ctx, query,
 		product.Title,
 		product.Details,
 		product.UnitPrice,
 		product.SizeLabel,
-		product.Group,
+		product.CategoryID,
 		product.Labels,
 		product.Extras,
+		product.Bundle,
 		intID,
 	)
 	if err != nil {
 		return fmt.Errorf("update product: %w", err)
 	}
 
-	_, err = m.DB.ExecContext(ctx, `DELETE FROM Menu_Item_Ingredients WHERE Menu_Item_ID = $1`, intID)
+	_, err = tx.ExecContext(ctx, `DELETE FROM Menu_Item_Ingredients WHERE Menu_Item_ID = $1`, intID)
 	if err != nil {
 		return fmt.Errorf("delete old components: %w", err)
 	}
@@ -281,12 +406,27 @@ func (m *menuRepo) UpdateProduct(ctx context.Context, id string, product *models
 		if err != nil {
 			return fmt.Errorf("convert component ID: %w", err)
 		}
-		_, err = m.DB.ExecContext(ctx, insertQuery, intID, compID, comp.RequiredQty)
+		_, err = tx.ExecContext(ctx, insertQuery, intID, compID, comp.RequiredQty)
 		if err != nil {
 			return fmt.Errorf("insert component: %w", err)
 		}
 	}
 
+	_, err = tx.ExecContext(ctx, `DELETE FROM Menu_Item_Modifiers WHERE Menu_Item_ID = $1`, intID)
+	if err != nil {
+		return fmt.Errorf("delete old modifiers: %w", err)
+	}
+	if err := insertModifiers(ctx, tx, intID, product.Modifiers); err != nil {
+		return fmt.Errorf("insert modifiers: %w", err)
+	}
+
+	if err := updateBundleSlots(ctx, tx, intID, product.Slots); err != nil {
+		return fmt.Errorf("update slots: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 	return nil
 }
 
@@ -304,7 +444,7 @@ func (m *menuRepo) UpdatePrice(ctx context.Context, ph *models.PriceHistory) err
 		)
 		VALUES ($1, $2, $3)
 	`
-	_, err = m.DB.ExecContext(ctx, query, intID, ph.OldPrice, ph.NewPrice)
+	_, err = m.q.ExecContext(ctx, query, intID, ph.OldPrice, ph.NewPrice)
 	if err != nil {
 		return fmt.Errorf("insert price history: %w", err)
 	}
@@ -312,38 +452,117 @@ func (m *menuRepo) UpdatePrice(ctx context.Context, ph *models.PriceHistory) err
 	return nil
 }
 
-func (m *menuRepo) DeleteProduct(ctx context.Context, id string) error {
+func (m *menuRepo) GetPriceHistory(ctx context.Context, id string) ([]*models.PriceHistory, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid menu item ID: %w", err)
+	}
+
+	query := `
+		SELECT Price_ID, Menu_Item_ID, Old_Price, New_Price, Changed_At
+		FROM Price_History
+		WHERE Menu_Item_ID = $1
+		ORDER BY Changed_At DESC, Price_ID DESC
+	`
+
+	rows, err := m.q.QueryContext(ctx, query, intID)
+	if err != nil {
+		return nil, fmt.Errorf("query price history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []*models.PriceHistory{}
+	for rows.Next() {
+		var (
+			ph            models.PriceHistory
+			priceID, item int
+			changedAt     time.Time
+		)
+		if err := rows.Scan(&priceID, &item, &ph.OldPrice, &ph.NewPrice, &changedAt); err != nil {
+			return nil, fmt.Errorf("scan price history: %w", err)
+		}
+		ph.ID = strconv.Itoa(priceID)
+		ph.MenuItemID = strconv.Itoa(item)
+		ph.ChangeDate = changedAt.Format(time.RFC3339)
+		history = append(history, &ph)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return history, nil
+}
+
+// SetArchived takes the item off the menu at the given moment; a nil at puts
+// it back. Archived items stay in place for past orders and reports.
+func (m *menuRepo) SetArchived(ctx context.Context, id string, at *time.Time) error {
 	intID, err := strconv.Atoi(id)
 	if err != nil {
 		return fmt.Errorf("invalid product ID: %w", err)
 	}
 
-	query := `DELETE FROM Menu_Items WHERE Menu_Item_ID = $1`
-	_, err = m.DB.ExecContext(ctx, query, intID)
+	_, err = m.q.ExecContext(ctx, `UPDATE Menu_Items SET Archived_At = $1 WHERE Menu_Item_ID = $2`, at, intID)
 	if err != nil {
-		return fmt.Errorf("delete product: %w", err)
+		return fmt.Errorf("set archived: %w", err)
 	}
 
 	return nil
 }
 
-func (m *menuRepo) loadProductComponents(ctx context.Context, product *models.Product) error {
+// SetSoldOut marks the item as sold out until the given moment; a nil until
+// puts it back on sale.
+func (m *menuRepo) SetSoldOut(ctx context.Context, id string, until *time.Time) error {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid product ID: %w", err)
+	}
+
+	_, err = m.q.ExecContext(ctx, `UPDATE Menu_Items SET Sold_Out_Until = $1 WHERE Menu_Item_ID = $2`, until, intID)
+	if err != nil {
+		return fmt.Errorf("set sold out: %w", err)
+	}
+
+	return nil
+}
+
+func (m *menuRepo) loadProductComponents(ctx context.Context, products []*models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*models.Product, len(products))
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		id, err := strconv.Atoi(p.ProductID)
+		if err != nil {
+			return fmt.Errorf("convert ProductID: %w", err)
+		}
+		byID[id] = p
+		ids = append(ids, id)
+	}
+
 	query := `
 		SELECT 
+			m.Menu_Item_ID,
 			i.Inventory_ID, 
 			i.Name,
-			m.Quantity 
+			m.Quantity,
+			i.Price,
+			u.Quantity - COALESCE(r.Reserved, 0) AS Available
 		FROM Menu_Item_Ingredients m
 		JOIN Inventory i ON m.Inventory_ID = i.Inventory_ID
-		WHERE m.Menu_Item_ID = $1
+		JOIN Usable_Stock u ON u.Inventory_ID = i.Inventory_ID
+		LEFT JOIN (
+			SELECT Inventory_ID, SUM(Reserved_Quantity) AS Reserved
+			FROM Inventory_Reservations
+			GROUP BY Inventory_ID
+		) r ON r.Inventory_ID = i.Inventory_ID
+		WHERE m.Menu_Item_ID = ANY($1)
+		ORDER BY m.Menu_Item_ID, i.Inventory_ID
 	`
 
-	menuID, err := strconv.Atoi(product.ProductID)
-	if err != nil {
-		return fmt.Errorf("convert ProductID: %w", err)
-	}
-
-	rows, err := m.DB.QueryContext(ctx, query, menuID)
+	rows, err := m.q.QueryContext(ctx, query, pq.Array(ids))
 	if err != nil {
 		return fmt.Errorf("query product components: %w", err)
 	}
@@ -351,14 +570,15 @@ func (m *menuRepo) loadProductComponents(ctx context.Context, product *models.Pr
 
 	for rows.Next() {
 		var comp models.ProductComponent
-		var inventoryID int
+		var menuID, inventoryID int
 
-		err := rows.Scan(&inventoryID, &comp.ComponentName, &comp.RequiredQty)
+		err := rows.Scan(&menuID, &inventoryID, &comp.ComponentName, &comp.RequiredQty, &comp.UnitCost, &comp.Available)
 		if err != nil {
 			return fmt.Errorf("scan component: %w", err)
 		}
 
 		comp.ComponentID = strconv.Itoa(inventoryID)
+		product := byID[menuID]
 		product.Components = append(product.Components, &comp)
 	}
 
@@ -368,3 +588,226 @@ func (m *menuRepo) loadProductComponents(ctx context.Context, product *models.Pr
 
 	return nil
 }
+
+func (m *menuRepo) loadModifiers(ctx context.Context, products []*models.Product) error {
+	byID := make(map[int]*models.Product, len(products))
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		if p.Bundle {
+			continue
+		}
+		id, err := strconv.Atoi(p.ProductID)
+		if err != nil {
+			return fmt.Errorf("convert ProductID: %w", err)
+		}
+		byID[id] = p
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := m.q.QueryContext(ctx, `
+		SELECT m.Menu_Item_ID, m.Name, m.Inventory_ID, i.Name, m.Quantity, m.Replaces_Inventory_ID
+		FROM Menu_Item_Modifiers m
+		JOIN Inventory i ON i.Inventory_ID = m.Inventory_ID
+		WHERE m.Menu_Item_ID = ANY($1)
+		ORDER BY m.Menu_Item_ID, m.Modifier_ID
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query modifiers: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			mod         models.Modifier
+			menuID      int
+			inventoryID int
+			replaces    sql.NullInt64
+		)
+		if err := rows.Scan(&menuID, &mod.Name, &inventoryID, &mod.ComponentName, &mod.Quantity, &replaces); err != nil {
+			return fmt.Errorf("scan modifier: %w", err)
+		}
+		mod.ComponentID = strconv.Itoa(inventoryID)
+		if replaces.Valid {
+			id := strconv.FormatInt(replaces.Int64, 10)
+			mod.ReplacesID = &id
+		}
+		product := byID[menuID]
+		product.Modifiers = append(product.Modifiers, &mod)
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	return nil
+}
+
+func (m *menuRepo) loadBundleSlots(ctx context.Context, products []*models.Product) error {
+	byID := make(map[int]*models.Product)
+	var ids []int
+	for _, p := range products {
+		if !p.Bundle {
+			continue
+		}
+		id, err := strconv.Atoi(p.ProductID)
+		if err != nil {
+			return fmt.Errorf("convert ProductID: %w", err)
+		}
+		p.Slots = []*models.BundleSlot{}
+		byID[id] = p
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT s.Bundle_ID, s.Slot_ID, s.Name, s.Quantity, o.Menu_Item_ID, o.Category_ID
+		FROM Bundle_Slots s
+		LEFT JOIN Bundle_Slot_Options o ON o.Slot_ID = s.Slot_ID
+		WHERE s.Bundle_ID = ANY($1)
+		ORDER BY s.Bundle_ID, s.Position, s.Slot_ID, o.Option_ID
+	`
+
+	rows, err := m.q.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("query bundle slots: %w", err)
+	}
+	defer rows.Close()
+
+	var slot *models.BundleSlot
+
+	for rows.Next() {
+		var (
+			bundleID int
+			slotID   int
+			name     string
+			quantity int
+			itemID   sql.NullInt64
+			category sql.NullInt64
+		)
+		if err := rows.Scan(&bundleID, &slotID, &name, &quantity, &itemID, &category); err != nil {
+			return fmt.Errorf("scan bundle slot: %w", err)
+		}
+
+		if slot == nil || slot.SlotID != strconv.Itoa(slotID) {
+			slot = &models.BundleSlot{
+				SlotID:   strconv.Itoa(slotID),
+				Name:     name,
+				Quantity: quantity,
+				Options:  []*models.BundleOption{},
+			}
+			bundle := byID[bundleID]
+			bundle.Slots = append(bundle.Slots, slot)
+		}
+
+		switch {
+		case itemID.Valid:
+			id := strconv.FormatInt(itemID.Int64, 10)
+			slot.Options = append(slot.Options, &models.BundleOption{ItemID: &id})
+		case category.Valid:
+			id := strconv.FormatInt(category.Int64, 10)
+			slot.Options = append(slot.Options, &models.BundleOption{CategoryID: &id})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	return nil
+}
+
+func insertBundleSlot(ctx context.Context, q querier, bundleID, pos int, slot *models.BundleSlot) (int, error) {
+	var slotID int
+	err := q.QueryRowContext(ctx, `
+		INSERT INTO Bundle_Slots (Bundle_ID, Name, Quantity, Position)
+		VALUES ($1, $2, $3, $4)
+		RETURNING Slot_ID
+	`, bundleID, slot.Name, slot.Quantity, pos).Scan(&slotID)
+	if err != nil {
+		return 0, fmt.Errorf("insert slot: %w", err)
+	}
+	slot.SlotID = strconv.Itoa(slotID)
+
+	return slotID, insertSlotOptions(ctx, q, slotID, slot.Options)
+}
+
+// updateBundleSlots brings the slots of a bundle in line with slots, keeping
+// the id of every slot that is still there.
+func updateBundleSlots(ctx context.Context, q querier, bundleID int, slots []*models.BundleSlot) error {
+	keep := []int{}
+	for pos, slot := range slots {
+		if slot.SlotID == "" {
+			slotID, err := insertBundleSlot(ctx, q, bundleID, pos, slot)
+			if err != nil {
+				return err
+			}
+			keep = append(keep, slotID)
+			continue
+		}
+
+		slotID, err := strconv.Atoi(slot.SlotID)
+		if err != nil {
+			return fmt.Errorf("invalid slot ID: %w", err)
+		}
+		res, err := q.ExecContext(ctx, `
+			UPDATE Bundle_Slots SET Name = $1, Quantity = $2, Position = $3
+			WHERE Slot_ID = $4 AND Bundle_ID = $5
+		`, slot.Name, slot.Quantity, pos, slotID, bundleID)
+		if err != nil {
+			return fmt.Errorf("update slot: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("update slot: %w", err)
+		} else if n == 0 {
+			return fmt.Errorf("slot %d of bundle %d: %w", slotID, bundleID, sql.ErrNoRows)
+		}
+
+		_, err = q.ExecContext(ctx, `DELETE FROM Bundle_Slot_Options WHERE Slot_ID = $1`, slotID)
+		if err != nil {
+			return fmt.Errorf("delete old slot options: %w", err)
+		}
+		if err := insertSlotOptions(ctx, q, slotID, slot.Options); err != nil {
+			return err
+		}
+		keep = append(keep, slotID)
+	}
+
+	_, err := q.ExecContext(ctx, `
+		DELETE FROM Bundle_Slots WHERE Bundle_ID = $1 AND Slot_ID <> ALL($2)
+	`, bundleID, pq.Array(keep))
+	if err != nil {
+		return fmt.Errorf("delete removed slots: %w", err)
+	}
+	return nil
+}
+
+func insertModifiers(ctx context.Context, q querier, menuItemID int, modifiers []*models.Modifier) error {
+	for _, mod := range modifiers {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO Menu_Item_Modifiers (Menu_Item_ID, Name, Inventory_ID, Quantity, Replaces_Inventory_ID)
+			VALUES ($1, $2, $3, $4, $5)
+		`, menuItemID, mod.Name, mod.ComponentID, mod.Quantity, mod.ReplacesID)
+		if err != nil {
+			return fmt.Errorf("insert modifier: %w", err)
+		}
+	}
+	return nil
+}
+
+func insertSlotOptions(ctx context.Context, q querier, slotID int, options []*models.BundleOption) error {
+	for _, opt := range options {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO Bundle_Slot_Options (Slot_ID, Menu_Item_ID, Category_ID)
+			VALUES ($1, $2, $3)
+		`, slotID, opt.ItemID, opt.CategoryID)
+		if err != nil {
+			return fmt.Errorf("insert slot option: %w", err)
+		}
+	}
+	return nil
+}
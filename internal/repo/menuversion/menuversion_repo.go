@@ -0,0 +1,474 @@
+package menuversion_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type MenuVersionRepo interface {
+	Create(ctx context.Context, version *models.MenuVersion) error
+	GetAll(ctx context.Context) ([]*models.MenuVersion, error)
+	GetByID(ctx context.Context, id string) (*models.MenuVersion, error)
+	GetLiveID(ctx context.Context) (string, error)
+	Delete(ctx context.Context, id string) error
+	SaveEntry(ctx context.Context, versionID string, entry *models.MenuVersionEntry) error
+	DeleteEntry(ctx context.Context, versionID, entryID string) error
+	Publish(ctx context.Context, id string, at time.Time) error
+}
+
+type menuVersionRepo struct {
+	DB *sql.DB
+}
+
+func NewMenuVersionRepo(db *sql.DB) MenuVersionRepo {
+	return &menuVersionRepo{
+		DB: db,
+	}
+}
+
+const selectVersion = `
+	SELECT Version_ID, Name, Status, Created_At, Published_At
+	FROM Menu_Versions
+`
+
+func scanVersion(row interface{ Scan(...any) error }) (*models.MenuVersion, error) {
+	var (
+		version models.MenuVersion
+		id      int
+	)
+	if err := row.Scan(&id, &version.Name, &version.Status, &version.CreatedAt, &version.PublishedAt); err != nil {
+		return nil, err
+	}
+	version.VersionID = strconv.Itoa(id)
+	return &version, nil
+}
+
+func (r *menuVersionRepo) Create(ctx context.Context, version *models.MenuVersion) error {
+	query := `
+		INSERT INTO Menu_Versions (Name)
+		VALUES ($1)
+		RETURNING Version_ID, Status, Created_At
+	`
+
+	var id int
+	err := r.DB.QueryRowContext(ctx, query, version.Name).Scan(&id, &version.Status, &version.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert menu version: %w", err)
+	}
+	version.VersionID = strconv.Itoa(id)
+	return nil
+}
+
+func (r *menuVersionRepo) GetAll(ctx context.Context) ([]*models.MenuVersion, error) {
+	rows, err := r.DB.QueryContext(ctx, selectVersion+` ORDER BY Version_ID DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("query menu versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []*models.MenuVersion{}
+	for rows.Next() {
+		version, err := scanVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan menu version: %w", err)
+		}
+		versions = append(versions, version)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetByID returns the version with its entries in the order they were made.
+func (r *menuVersionRepo) GetByID(ctx context.Context, id string) (*models.MenuVersion, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid menu version ID: %w", err)
+	}
+
+	version, err := scanVersion(r.DB.QueryRowContext(ctx, selectVersion+` WHERE Version_ID = $1`, intID))
+	if err != nil {
+		return nil, fmt.Errorf("query menu version: %w", err)
+	}
+
+	if version.Entries, err = r.loadEntries(ctx, intID); err != nil {
+		return nil, fmt.Errorf("load entries: %w", err)
+	}
+
+	return version, nil
+}
+
+func (r *menuVersionRepo) GetLiveID(ctx context.Context) (string, error) {
+	var id int
+	err := r.DB.QueryRowContext(ctx, `SELECT Version_ID FROM Menu_Versions WHERE Status = 'live'`).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("query live menu version: %w", err)
+	}
+	return strconv.Itoa(id), nil
+}
+
+// Delete drops a draft along with its entries.
+func (r *menuVersionRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM Menu_Versions WHERE Version_ID = $1 AND Status = 'draft'`, id)
+	if err != nil {
+		return fmt.Errorf("delete menu version: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SaveEntry adds a change to a draft. A draft holds one change per menu item,
+// so a new change of an item replaces the previous one.
+func (r *menuVersionRepo) SaveEntry(ctx context.Context, versionID string, entry *models.MenuVersionEntry) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var productID *string
+	if entry.ProductID != "" {
+		productID = &entry.ProductID
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM Menu_Version_Items
+			WHERE Version_ID = $1 AND Menu_Item_ID = $2
+		`, versionID, entry.ProductID)
+		if err != nil {
+			return fmt.Errorf("delete previous entry: %w", err)
+		}
+	}
+
+	// a removal only names the item
+	var (
+		id                            int
+		name, details, size, category *string
+		price                         *float64
+		labels                        pq.StringArray
+		extras                        = models.ExtrasMap{}
+		components                    []*models.ProductComponent
+	)
+	if item := entry.Item; item != nil {
+		name, details, price = &item.Title, &item.Details, &item.UnitPrice
+		category, labels, components = &item.CategoryID, item.Labels, item.Components
+		if item.SizeLabel != "" {
+			size = &item.SizeLabel
+		}
+		if item.Extras != nil {
+			extras = item.Extras
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO Menu_Version_Items (
+			Version_ID, Menu_Item_ID, Removed, Name, Description, Price, Size, Category_ID, Tags, Metadata
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING Entry_ID
+	`,
+		versionID,
+		productID,
+		entry.Remove,
+		name,
+		details,
+		price,
+		size,
+		category,
+		labels,
+		extras,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("insert entry: %w", err)
+	}
+	entry.EntryID = strconv.Itoa(id)
+
+	for _, comp := range components {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO Menu_Version_Ingredients (Entry_ID, Inventory_ID, Quantity)
+			VALUES ($1, $2, $3)
+		`, id, comp.ComponentID, comp.RequiredQty)
+		if err != nil {
+			return fmt.Errorf("insert entry ingredient: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *menuVersionRepo) DeleteEntry(ctx context.Context, versionID, entryID string) error {
+	res, err := r.DB.ExecContext(ctx, `
+		DELETE FROM Menu_Version_Items
+		WHERE Entry_ID = $1 AND Version_ID = $2
+	`, entryID, versionID)
+	if err != nil {
+		return fmt.Errorf("delete entry: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Publish applies the changes of a draft to the menu and makes it the live
+// version in one transaction, so orders see either the old menu or the new
+// one. Removed items are archived, price changes go into Price_History.
+// It returns sql.ErrNoRows when the version is no longer a draft.
+func (r *menuVersionRepo) Publish(ctx context.Context, id string, at time.Time) error {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid menu version ID: %w", err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// one publish at a time; reads of the live version go on
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE Menu_Versions IN EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("lock menu versions: %w", err)
+	}
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT Status FROM Menu_Versions WHERE Version_ID = $1`, intID).Scan(&status)
+	if err != nil {
+		return fmt.Errorf("query menu version: %w", err)
+	}
+	if status != models.MenuVersionDraft {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE Menu_Items m
+		SET Archived_At = $2
+		FROM Menu_Version_Items e
+		WHERE e.Version_ID = $1 AND e.Removed
+		  AND m.Menu_Item_ID = e.Menu_Item_ID AND m.Archived_At IS NULL
+	`, intID, at)
+	if err != nil {
+		return fmt.Errorf("archive removed items: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO Price_History (Menu_Item_ID, Old_Price, New_Price, Changed_At)
+		SELECT m.Menu_Item_ID, m.Price, e.Price, $2
+		FROM Menu_Version_Items e
+		JOIN Menu_Items m ON m.Menu_Item_ID = e.Menu_Item_ID
+		WHERE e.Version_ID = $1 AND NOT e.Removed AND m.Price <> e.Price
+	`, intID, at)
+	if err != nil {
+		return fmt.Errorf("insert price history: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE Menu_Items m
+		SET Name = e.Name, Description = e.Description, Price = e.Price, Size = e.Size,
+		    Category_ID = e.Category_ID, Tags = e.Tags, Metadata = e.Metadata
+		FROM Menu_Version_Items e
+		WHERE e.Version_ID = $1 AND NOT e.Removed AND m.Menu_Item_ID = e.Menu_Item_ID
+	`, intID)
+	if err != nil {
+		return fmt.Errorf("update changed items: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM Menu_Item_Ingredients
+		WHERE Menu_Item_ID IN (
+			SELECT Menu_Item_ID FROM Menu_Version_Items
+			WHERE Version_ID = $1 AND NOT Removed AND Menu_Item_ID IS NOT NULL
+		)
+	`, intID)
+	if err != nil {
+		return fmt.Errorf("delete old components: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO Menu_Item_Ingredients (Menu_Item_ID, Inventory_ID, Quantity)
+		SELECT e.Menu_Item_ID, g.Inventory_ID, g.Quantity
+		FROM Menu_Version_Items e
+		JOIN Menu_Version_Ingredients g ON g.Entry_ID = e.Entry_ID
+		WHERE e.Version_ID = $1 AND NOT e.Removed AND e.Menu_Item_ID IS NOT NULL
+	`, intID)
+	if err != nil {
+		return fmt.Errorf("insert components: %w", err)
+	}
+
+	if err := addNewItems(ctx, tx, intID); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE Menu_Versions SET Status = 'retired' WHERE Status = 'live'`)
+	if err != nil {
+		return fmt.Errorf("retire live version: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE Menu_Versions SET Status = 'live', Published_At = $2
+		WHERE Version_ID = $1
+	`, intID, at)
+	if err != nil {
+		return fmt.Errorf("set live version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// addNewItems creates the menu items a draft adds, with their components.
+func addNewItems(ctx context.Context, tx *sql.Tx, versionID int) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT Entry_ID FROM Menu_Version_Items
+		WHERE Version_ID = $1 AND Menu_Item_ID IS NULL
+		ORDER BY Entry_ID
+	`, versionID)
+	if err != nil {
+		return fmt.Errorf("query new items: %w", err)
+	}
+
+	var entries []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan new item: %w", err)
+		}
+		entries = append(entries, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+
+	for _, entryID := range entries {
+		var itemID int
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO Menu_Items (Name, Description, Price, Size, Category_ID, Tags, Metadata)
+			SELECT Name, Description, Price, Size, Category_ID, Tags, Metadata
+			FROM Menu_Version_Items
+			WHERE Entry_ID = $1
+			RETURNING Menu_Item_ID
+		`, entryID).Scan(&itemID)
+		if err != nil {
+			return fmt.Errorf("insert menu item: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO Menu_Item_Ingredients (Menu_Item_ID, Inventory_ID, Quantity)
+			SELECT $1, Inventory_ID, Quantity
+			FROM Menu_Version_Ingredients
+			WHERE Entry_ID = $2
+		`, itemID, entryID)
+		if err != nil {
+			return fmt.Errorf("insert components: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (r *menuVersionRepo) loadEntries(ctx context.Context, versionID int) ([]*models.MenuVersionEntry, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT e.Entry_ID, e.Menu_Item_ID, e.Removed, e.Name, e.Description, e.Price,
+		       e.Size, e.Category_ID, c.Name, e.Tags, e.Metadata
+		FROM Menu_Version_Items e
+		LEFT JOIN Categories c ON c.Category_ID = e.Category_ID
+		WHERE e.Version_ID = $1
+		ORDER BY e.Entry_ID
+	`, versionID)
+	if err != nil {
+		return nil, fmt.Errorf("query entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*models.MenuVersionEntry{}
+	byID := make(map[int]*models.Product)
+	var ids []int
+	for rows.Next() {
+		var (
+			entry                             models.MenuVersionEntry
+			id                                int
+			productID, categoryID             sql.NullInt64
+			name, details, size, categoryName sql.NullString
+			price                             sql.NullFloat64
+			labels                            pq.StringArray
+			extras                            models.ExtrasMap
+		)
+		err := rows.Scan(&id, &productID, &entry.Remove, &name, &details, &price,
+			&size, &categoryID, &categoryName, &labels, &extras)
+		if err != nil {
+			return nil, fmt.Errorf("scan entry: %w", err)
+		}
+
+		entry.EntryID = strconv.Itoa(id)
+		if productID.Valid {
+			entry.ProductID = strconv.FormatInt(productID.Int64, 10)
+		}
+		if !entry.Remove {
+			entry.Item = &models.Product{
+				ProductID:  entry.ProductID,
+				Title:      name.String,
+				Details:    details.String,
+				UnitPrice:  price.Float64,
+				SizeLabel:  size.String,
+				CategoryID: strconv.FormatInt(categoryID.Int64, 10),
+				Group:      categoryName.String,
+				Labels:     labels,
+				Extras:     extras,
+				Components: []*models.ProductComponent{},
+			}
+			byID[id] = entry.Item
+			ids = append(ids, id)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	rows, err = r.DB.QueryContext(ctx, `
+		SELECT g.Entry_ID, g.Inventory_ID, i.Name, g.Quantity
+		FROM Menu_Version_Ingredients g
+		JOIN Inventory i ON i.Inventory_ID = g.Inventory_ID
+		WHERE g.Entry_ID = ANY($1)
+		ORDER BY g.Entry_ID, g.Inventory_ID
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query entry ingredients: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			comp           models.ProductComponent
+			entryID, invID int
+		)
+		if err := rows.Scan(&entryID, &invID, &comp.ComponentName, &comp.RequiredQty); err != nil {
+			return nil, fmt.Errorf("scan entry ingredient: %w", err)
+		}
+		comp.ComponentID = strconv.Itoa(invID)
+		item := byID[entryID]
+		item.Components = append(item.Components, &comp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return entries, nil
+}
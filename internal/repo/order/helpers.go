@@ -2,8 +2,10 @@ package order_repo
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 
 	"frappuccino/internal/models"
@@ -11,18 +13,35 @@ import (
 
 func (o *orderRepo) createOrderRecord(ctx context.Context, order *models.Purchase) error {
 	query := `
-		INSERT INTO Orders (Customer_ID, Status, Total_Amount)
-		VALUES ($1, $2, $3)
+		INSERT INTO Orders (
+			Customer_ID,
+			Status,
+			Total_Amount,
+			Menu_Version_ID,
+			Channel,
+			Table_Number,
+			Delivery_Address,
+			Pickup_At,
+			Tax_Amount,
+			Delivery_Fee
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8::TIMESTAMPTZ, $9, $10)
 		RETURNING Order_ID, Created_At, Updated_At
 	`
 
 	var id int
-	err := o.DB.QueryRowContext(
+	err := o.q.QueryRowContext(
 		ctx,
 		query,
 		order.CustomerID,
 		order.Status,
 		order.Amount,
+		order.MenuVersionID,
+		order.Channel,
+		order.TableNumber,
+		order.DeliveryAddress,
+		order.PickupAt,
+		order.Tax,
+		order.DeliveryFee,
 	).Scan(&id, &order.Created, &order.Updated)
 	if err != nil {
 		return err
@@ -39,8 +58,10 @@ func (r *orderRepo) insertOrderItems(ctx context.Context, orderID string, items
 			Menu_Item_ID,
 			Quantity,
 			Price,
-			Customization
-		) VALUES ($1, $2, $3, $4, $5)
+			Customization,
+			Is_Bundle
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING Order_Item_ID
 	`
 
 	orderIDInt, err := strconv.Atoi(orderID)
@@ -59,16 +80,63 @@ func (r *orderRepo) insertOrderItems(ctx context.Context, orderID string, items
 			return fmt.Errorf("marshal customization: %w", err)
 		}
 
-		_, err = r.DB.ExecContext(ctx, query,
+		var lineID int
+		err = r.q.QueryRowContext(ctx, query,
 			orderIDInt,
 			menuID,
 			item.Count,
 			item.UnitPrice,
 			customization,
-		)
+			len(item.Selections) > 0,
+		).Scan(&lineID)
 		if err != nil {
 			return fmt.Errorf("insert order item: %w", err)
 		}
+		item.LineID = strconv.Itoa(lineID)
+
+		if err := r.insertBundleLines(ctx, orderIDInt, lineID, item); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertBundleLines stores the selected items of a bundle line as component
+// lines carrying their share of the bundle price.
+func (r *orderRepo) insertBundleLines(ctx context.Context, orderID, parentID int, item *models.LineItem) error {
+	query := `
+		INSERT INTO Order_Items (
+			Order_ID,
+			Menu_Item_ID,
+			Quantity,
+			Price,
+			Parent_Item_ID,
+			Bundle_Slot_ID
+		) VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	for _, sel := range item.Selections {
+		menuID, err := strconv.Atoi(sel.ItemID)
+		if err != nil {
+			return fmt.Errorf("invalid item ID: %w", err)
+		}
+		slotID, err := strconv.Atoi(sel.SlotID)
+		if err != nil {
+			return fmt.Errorf("invalid slot ID: %w", err)
+		}
+
+		_, err = r.q.ExecContext(ctx, query,
+			orderID,
+			menuID,
+			item.Count*sel.Count,
+			sel.UnitPrice,
+			parentID,
+			slotID,
+		)
+		if err != nil {
+			return fmt.Errorf("insert bundle line: %w", err)
+		}
 	}
 
 	return nil
@@ -86,39 +154,16 @@ func (r *orderRepo) reserveInventory(ctx context.Context, orderID string, items
 			return fmt.Errorf("invalid menu item ID: %w", err)
 		}
 
-		query := `
-			SELECT Inventory_ID, Quantity
+		// one statement per line: in a transaction the connection cannot
+		// take an insert while the ingredients are still being read
+		_, err = r.q.ExecContext(ctx, `
+			INSERT INTO Inventory_Reservations (Order_ID, Menu_Item_ID, Inventory_ID, Reserved_Quantity)
+			SELECT $1, Menu_Item_ID, Inventory_ID, Quantity * $3
 			FROM Menu_Item_Ingredients
-			WHERE Menu_Item_ID = $1
-		`
-
-		rows, err := r.DB.QueryContext(ctx, query, menuItemID)
+			WHERE Menu_Item_ID = $2
+		`, orderIDInt, menuItemID, item.Count)
 		if err != nil {
-			return fmt.Errorf("query ingredients: %w", err)
-		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var inventoryID int
-			var qtyPerUnit float64
-
-			if err := rows.Scan(&inventoryID, &qtyPerUnit); err != nil {
-				return fmt.Errorf("scan ingredient: %w", err)
-			}
-
-			reserveQty := qtyPerUnit * float64(item.Count)
-
-			insert := `
-				INSERT INTO Inventory_Reservations (Order_ID, Inventory_ID, Reserved_Quantity)
-				VALUES ($1, $2, $3)
-			`
-			_, err = r.DB.ExecContext(ctx, insert, orderIDInt, inventoryID, reserveQty)
-			if err != nil {
-				return fmt.Errorf("insert reservation: %w", err)
-			}
-		}
-		if err := rows.Err(); err != nil {
-			return fmt.Errorf("read rows: %w", err)
+			return fmt.Errorf("insert reservation: %w", err)
 		}
 	}
 
@@ -131,7 +176,7 @@ func (r *orderRepo) removeReserve(ctx context.Context, id string) error {
 		return fmt.Errorf("invalid order ID: %w", err)
 	}
 
-	_, err = r.DB.ExecContext(ctx, `DELETE FROM Inventory_Reservations WHERE Order_ID = $1`, orderIDInt)
+	_, err = r.q.ExecContext(ctx, `DELETE FROM Inventory_Reservations WHERE Order_ID = $1`, orderIDInt)
 	return err
 }
 
@@ -148,7 +193,7 @@ func (r *orderRepo) recordOrderStatus(ctx context.Context, order *models.Purchas
 		return fmt.Errorf("invalid order ID: %w", err)
 	}
 
-	_, err = r.DB.ExecContext(ctx, query, orderIDInt, order.Status)
+	_, err = r.q.ExecContext(ctx, query, orderIDInt, order.Status)
 	if err != nil {
 		return fmt.Errorf("insert status history: %w", err)
 	}
@@ -162,6 +207,48 @@ func (r *orderRepo) removeItemsByOrderID(ctx context.Context, id string) error {
 		return fmt.Errorf("invalid order ID: %w", err)
 	}
 
-	_, err = r.DB.ExecContext(ctx, `DELETE FROM Order_Items WHERE Order_ID = $1`, orderIDInt)
+	_, err = r.q.ExecContext(ctx, `DELETE FROM Order_Items WHERE Order_ID = $1`, orderIDInt)
 	return err
 }
+
+// attachBundleLine turns a stored component line back into a selection of
+// its bundle line. Component lines hold the units of all bundles on the line.
+func attachBundleLine(parent *models.LineItem, productID, slotID sql.NullInt64, quantity, price float64) {
+	sel := &models.BundleSelection{
+		ItemID:    strconv.FormatInt(productID.Int64, 10),
+		Count:     int(quantity),
+		UnitPrice: price,
+	}
+	if slotID.Valid {
+		sel.SlotID = strconv.FormatInt(slotID.Int64, 10)
+	}
+	if parent.Count > 0 {
+		sel.Count = int(quantity) / parent.Count
+	}
+	parent.Selections = append(parent.Selections, sel)
+}
+
+// setSubtotal works out the price of the items from the stored total and
+// what the channel added to it.
+func setSubtotal(order *models.Purchase) {
+	if order.Amount == nil {
+		return
+	}
+	subtotal := *order.Amount
+	if order.Tax != nil {
+		subtotal -= *order.Tax
+	}
+	if order.DeliveryFee != nil {
+		subtotal -= *order.DeliveryFee
+	}
+	subtotal = math.Round(subtotal*100) / 100
+	order.Subtotal = &subtotal
+}
+
+func nullID(id sql.NullInt64) *string {
+	if !id.Valid {
+		return nil
+	}
+	s := strconv.FormatInt(id.Int64, 10)
+	return &s
+}
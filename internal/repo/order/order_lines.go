@@ -0,0 +1,126 @@
+package order_repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"frappuccino/internal/models"
+)
+
+// LockOrder holds an order until the transaction ends, so that changes to
+// its lines are made one after the other. A missing order is sql.ErrNoRows.
+func (r *orderRepo) LockOrder(ctx context.Context, orderID string) error {
+	orderIDInt, err := strconv.Atoi(orderID)
+	if err != nil {
+		return fmt.Errorf("invalid order ID: %w", err)
+	}
+
+	var id int
+	err = r.q.QueryRowContext(ctx, `SELECT Order_ID FROM Orders WHERE Order_ID = $1 FOR UPDATE`, orderIDInt).Scan(&id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddLine adds a priced line to an order and sets its LineID.
+func (r *orderRepo) AddLine(ctx context.Context, orderID string, line *models.LineItem) error {
+	if err := r.insertOrderItems(ctx, orderID, []*models.LineItem{line}); err != nil {
+		return fmt.Errorf("insert order line: %w", err)
+	}
+	return nil
+}
+
+// UpdateLine stores the count, price and adjustments of a line. The items of
+// a bundle line are written anew. A line not on the order is sql.ErrNoRows.
+func (r *orderRepo) UpdateLine(ctx context.Context, orderID string, line *models.LineItem) error {
+	orderIDInt, lineID, err := lineKey(orderID, line.LineID)
+	if err != nil {
+		return err
+	}
+
+	customization, err := json.Marshal(line.Adjustments)
+	if err != nil {
+		return fmt.Errorf("marshal customization: %w", err)
+	}
+
+	res, err := r.q.ExecContext(ctx, `
+		UPDATE Order_Items
+		SET Quantity = $1, Price = $2, Customization = $3, Is_Bundle = $4
+		WHERE Order_Item_ID = $5 AND Order_ID = $6 AND Parent_Item_ID IS NULL
+	`, line.Count, line.UnitPrice, customization, len(line.Selections) > 0, lineID, orderIDInt)
+	if err != nil {
+		return fmt.Errorf("update order line: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = r.q.ExecContext(ctx, `DELETE FROM Order_Items WHERE Parent_Item_ID = $1`, lineID)
+	if err != nil {
+		return fmt.Errorf("remove bundle lines: %w", err)
+	}
+	return r.insertBundleLines(ctx, orderIDInt, lineID, line)
+}
+
+// DeleteLine takes a line, and the items of a bundle line, off an order. A
+// line not on the order is sql.ErrNoRows.
+func (r *orderRepo) DeleteLine(ctx context.Context, orderID, lineID string) error {
+	orderIDInt, lineIDInt, err := lineKey(orderID, lineID)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.q.ExecContext(ctx, `
+		DELETE FROM Order_Items
+		WHERE Order_Item_ID = $1 AND Order_ID = $2 AND Parent_Item_ID IS NULL
+	`, lineIDInt, orderIDInt)
+	if err != nil {
+		return fmt.Errorf("delete order line: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateTotals stores the totals and menu version of an order whose lines
+// changed and reserves the stock for its lines anew.
+func (r *orderRepo) UpdateTotals(ctx context.Context, order *models.Purchase) error {
+	orderIDInt, err := strconv.Atoi(order.PurchaseID)
+	if err != nil {
+		return fmt.Errorf("invalid order ID: %w", err)
+	}
+
+	_, err = r.q.ExecContext(ctx, `
+		UPDATE Orders
+		SET Total_Amount = $1, Tax_Amount = $2, Delivery_Fee = $3, Menu_Version_ID = $4, Updated_At = NOW()
+		WHERE Order_ID = $5
+	`, order.Amount, order.Tax, order.DeliveryFee, order.MenuVersionID, orderIDInt)
+	if err != nil {
+		return fmt.Errorf("update totals: %w", err)
+	}
+
+	if err := r.removeReserve(ctx, order.PurchaseID); err != nil {
+		return fmt.Errorf("remove old reserve: %w", err)
+	}
+	if err := r.reserveInventory(ctx, order.PurchaseID, order.ComponentLines()); err != nil {
+		return fmt.Errorf("reserve inventory: %w", err)
+	}
+	return nil
+}
+
+func lineKey(orderID, lineID string) (int, int, error) {
+	orderIDInt, err := strconv.Atoi(orderID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid order ID: %w", err)
+	}
+	lineIDInt, err := strconv.Atoi(lineID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid line ID: %w", err)
+	}
+	return orderIDInt, lineIDInt, nil
+}
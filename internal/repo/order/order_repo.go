@@ -14,42 +14,90 @@ import (
 )
 
 type OrderRepo interface {
-	GetAllOrders(ctx context.Context) ([]*models.Purchase, error)
+	GetAllOrders(ctx context.Context, filter models.OrderFilter) ([]*models.Purchase, error)
 	GetOrderByID(ctx context.Context, id string) (*models.Purchase, error)
 	CreateOrder(ctx context.Context, order *models.Purchase) error
 	UpdateOrder(ctx context.Context, id string, order *models.Purchase) error
 	DeleteOrder(ctx context.Context, id string) error
-	CheckInventoryForOrder(ctx context.Context, order *models.Purchase) (bool, error)
+	CheckInventoryForOrder(ctx context.Context, order *models.Purchase, excludeOrderID string) (bool, error)
 	CloseOrder(ctx context.Context, order *models.Purchase) error
 	GetNumberOfOrderedItems(ctx context.Context, startDate, endDate *time.Time) (map[string]int, error)
+	GetReservations(ctx context.Context) ([]*models.OrderReservation, error)
+	CancelStaleOrders(ctx context.Context, ttl time.Duration) ([]*models.Purchase, error)
+	InTx(ctx context.Context, fn func(tx OrderRepo) error) error
+	GetStockEvents(ctx context.Context, orderIDs []string) ([]*models.StockEvent, error)
+	GetStockLevels(ctx context.Context) (map[string]float64, error)
+	LockOrder(ctx context.Context, orderID string) error
+	AddLine(ctx context.Context, orderID string, line *models.LineItem) error
+	UpdateLine(ctx context.Context, orderID string, line *models.LineItem) error
+	DeleteLine(ctx context.Context, orderID, lineID string) error
+	UpdateTotals(ctx context.Context, order *models.Purchase) error
+}
+
+// querier runs the order queries, on the database or in a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
 type orderRepo struct {
 	DB *sql.DB
+	// q is DB, or the transaction of the repo handed out by InTx.
+	q querier
 }
 
 func NewOrderRepo(db *sql.DB) OrderRepo {
 	return &orderRepo{
 		DB: db,
+		q:  db,
+	}
+}
+
+// InTx runs fn on a repo whose queries share one transaction, committed when
+// fn succeeds and rolled back when it fails. Called on a repo already in a
+// transaction, fn joins it.
+func (r *orderRepo) InTx(ctx context.Context, fn func(tx OrderRepo) error) error {
+	if _, ok := r.q.(*sql.Tx); ok {
+		return fn(r)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&orderRepo{DB: r.DB, q: tx}); err != nil {
+		return err
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
 }
 
-func (r *orderRepo) GetAllOrders(ctx context.Context) ([]*models.Purchase, error) {
+func (r *orderRepo) GetAllOrders(ctx context.Context, filter models.OrderFilter) ([]*models.Purchase, error) {
 	query := `
-		SELECT o.Order_ID, o.Customer_ID, o.Status, o.Total_Amount, o.Created_At, o.Updated_At,
-		       oi.Order_Item_ID, oi.Menu_Item_ID, oi.Quantity, oi.Price, oi.Customization
+		SELECT o.Order_ID, o.Customer_ID, o.Status, o.Total_Amount, o.Created_At, o.Updated_At, o.Menu_Version_ID,
+		       o.Channel, o.Table_Number, o.Delivery_Address, o.Pickup_At::TIMESTAMPTZ, o.Tax_Amount, o.Delivery_Fee,
+		       oi.Order_Item_ID, oi.Menu_Item_ID, oi.Quantity, oi.Price, oi.Customization,
+		       oi.Parent_Item_ID, oi.Bundle_Slot_ID
 		FROM Orders o
 		LEFT JOIN Order_Items oi ON o.Order_ID = oi.Order_ID
+		WHERE ($1 = '' OR o.Channel::TEXT = $1)
 		ORDER BY o.Created_At DESC, oi.Order_Item_ID
 	`
 
-	rows, err := r.DB.QueryContext(ctx, query)
+	rows, err := r.q.QueryContext(ctx, query, filter.Channel)
 	if err != nil {
 		return nil, fmt.Errorf("query orders: %w", err)
 	}
 	defer rows.Close()
 
 	ordersMap := make(map[string]*models.Purchase)
+	lines := make(map[int64]*models.LineItem)
 	var orders []*models.Purchase
 
 	for rows.Next() {
@@ -58,6 +106,7 @@ func (r *orderRepo) GetAllOrders(ctx context.Context) ([]*models.Purchase, error
 		var itemID, productID sql.NullInt64
 		var quantity, price sql.NullFloat64
 		var customization sql.NullString
+		var parentID, slotID, versionID sql.NullInt64
 
 		err := rows.Scan(
 			&orderID,
@@ -66,11 +115,20 @@ func (r *orderRepo) GetAllOrders(ctx context.Context) ([]*models.Purchase, error
 			&temp.Amount,
 			&temp.Created,
 			&temp.Updated,
+			&versionID,
+			&temp.Channel,
+			&temp.TableNumber,
+			&temp.DeliveryAddress,
+			&temp.PickupAt,
+			&temp.Tax,
+			&temp.DeliveryFee,
 			&itemID,
 			&productID,
 			&quantity,
 			&price,
 			&customization,
+			&parentID,
+			&slotID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan order row: %w", err)
@@ -78,6 +136,8 @@ func (r *orderRepo) GetAllOrders(ctx context.Context) ([]*models.Purchase, error
 
 		orderKey := strconv.Itoa(orderID)
 		temp.PurchaseID = orderKey
+		temp.MenuVersionID = nullID(versionID)
+		setSubtotal(&temp)
 
 		existingOrder, exists := ordersMap[orderKey]
 		if !exists {
@@ -87,6 +147,13 @@ func (r *orderRepo) GetAllOrders(ctx context.Context) ([]*models.Purchase, error
 			existingOrder = &temp
 		}
 
+		if itemID.Valid && parentID.Valid {
+			if parent, ok := lines[parentID.Int64]; ok {
+				attachBundleLine(parent, productID, slotID, quantity.Float64, price.Float64)
+			}
+			continue
+		}
+
 		if itemID.Valid {
 			var customMap models.ConfigMap
 			if customization.Valid {
@@ -95,12 +162,15 @@ func (r *orderRepo) GetAllOrders(ctx context.Context) ([]*models.Purchase, error
 				}
 			}
 
-			existingOrder.Positions = append(existingOrder.Positions, &models.LineItem{
+			line := &models.LineItem{
+				LineID:      strconv.FormatInt(itemID.Int64, 10),
 				ItemID:      strconv.FormatInt(productID.Int64, 10),
 				Count:       int(quantity.Float64),
 				UnitPrice:   price.Float64,
 				Adjustments: customMap,
-			})
+			}
+			lines[itemID.Int64] = line
+			existingOrder.Positions = append(existingOrder.Positions, line)
 		}
 	}
 
@@ -120,7 +190,7 @@ func (o *orderRepo) CreateOrder(ctx context.Context, order *models.Purchase) err
 		return fmt.Errorf("insert order items: %w", err)
 	}
 
-	if err := o.reserveInventory(ctx, order.PurchaseID, order.Positions); err != nil {
+	if err := o.reserveInventory(ctx, order.PurchaseID, order.ComponentLines()); err != nil {
 		return fmt.Errorf("reserve inventory: %w", err)
 	}
 
@@ -138,14 +208,17 @@ func (r *orderRepo) GetOrderByID(ctx context.Context, id string) (*models.Purcha
 	}
 
 	query := `
-		SELECT o.Order_ID, o.Customer_ID, o.Status, o.Total_Amount, o.Created_At, o.Updated_At,
-		       oi.Order_Item_ID, oi.Menu_Item_ID, oi.Quantity, oi.Price, oi.Customization
+		SELECT o.Order_ID, o.Customer_ID, o.Status, o.Total_Amount, o.Created_At, o.Updated_At, o.Menu_Version_ID,
+		       o.Channel, o.Table_Number, o.Delivery_Address, o.Pickup_At::TIMESTAMPTZ, o.Tax_Amount, o.Delivery_Fee,
+		       oi.Order_Item_ID, oi.Menu_Item_ID, oi.Quantity, oi.Price, oi.Customization,
+		       oi.Parent_Item_ID, oi.Bundle_Slot_ID
 		FROM Orders o
 		LEFT JOIN Order_Items oi ON o.Order_ID = oi.Order_ID
 		WHERE o.Order_ID = $1
+		ORDER BY oi.Order_Item_ID
 	`
 
-	rows, err := r.DB.QueryContext(ctx, query, orderIDInt)
+	rows, err := r.q.QueryContext(ctx, query, orderIDInt)
 	if err != nil {
 		return nil, fmt.Errorf("query order by ID: %w", err)
 	}
@@ -153,12 +226,14 @@ func (r *orderRepo) GetOrderByID(ctx context.Context, id string) (*models.Purcha
 
 	var order *models.Purchase
 	var items []*models.LineItem
+	lines := make(map[int64]*models.LineItem)
 
 	for rows.Next() {
 		var temp models.Purchase
 		var itemID, productID sql.NullInt64
 		var quantity, price sql.NullFloat64
 		var customization sql.NullString
+		var parentID, slotID, versionID sql.NullInt64
 
 		err := rows.Scan(
 			&temp.PurchaseID,
@@ -167,11 +242,20 @@ func (r *orderRepo) GetOrderByID(ctx context.Context, id string) (*models.Purcha
 			&temp.Amount,
 			&temp.Created,
 			&temp.Updated,
+			&versionID,
+			&temp.Channel,
+			&temp.TableNumber,
+			&temp.DeliveryAddress,
+			&temp.PickupAt,
+			&temp.Tax,
+			&temp.DeliveryFee,
 			&itemID,
 			&productID,
 			&quantity,
 			&price,
 			&customization,
+			&parentID,
+			&slotID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scan order row: %w", err)
@@ -179,10 +263,19 @@ func (r *orderRepo) GetOrderByID(ctx context.Context, id string) (*models.Purcha
 
 		if order == nil {
 			temp.PurchaseID = strconv.Itoa(orderIDInt)
+			temp.MenuVersionID = nullID(versionID)
+			setSubtotal(&temp)
 			order = &temp
 			order.Positions = []*models.LineItem{}
 		}
 
+		if itemID.Valid && parentID.Valid {
+			if parent, ok := lines[parentID.Int64]; ok {
+				attachBundleLine(parent, productID, slotID, quantity.Float64, price.Float64)
+			}
+			continue
+		}
+
 		if itemID.Valid {
 			var customMap models.ConfigMap
 			if customization.Valid {
@@ -191,12 +284,15 @@ func (r *orderRepo) GetOrderByID(ctx context.Context, id string) (*models.Purcha
 				}
 			}
 
-			items = append(items, &models.LineItem{
+			line := &models.LineItem{
+				LineID:      strconv.FormatInt(itemID.Int64, 10),
 				ItemID:      strconv.FormatInt(productID.Int64, 10),
 				Count:       int(quantity.Float64),
 				UnitPrice:   price.Float64,
 				Adjustments: customMap,
-			})
+			}
+			lines[itemID.Int64] = line
+			items = append(items, line)
 		}
 	}
 
@@ -230,7 +326,7 @@ func (r *orderRepo) UpdateOrder(ctx context.Context, id string, order *models.Pu
 		return fmt.Errorf("remove old reserve: %w", err)
 	}
 
-	if err := r.reserveInventory(ctx, id, order.Positions); err != nil {
+	if err := r.reserveInventory(ctx, id, order.ComponentLines()); err != nil {
 		return fmt.Errorf("reserve new inventory: %w", err)
 	}
 
@@ -238,13 +334,27 @@ func (r *orderRepo) UpdateOrder(ctx context.Context, id string, order *models.Pu
 		UPDATE Orders
 		SET Customer_ID = $1,
 			Total_Amount = $2,
+			Menu_Version_ID = $3,
+			Channel = $4,
+			Table_Number = $5,
+			Delivery_Address = $6,
+			Pickup_At = $7::TIMESTAMPTZ,
+			Tax_Amount = $8,
+			Delivery_Fee = $9,
 			Updated_At = NOW()
-		WHERE Order_ID = $3
+		WHERE Order_ID = $10
 	`
 
-	_, err = r.DB.ExecContext(ctx, query,
+	_, err = r.q.ExecContext(ctx, query,
 		order.CustomerID,
 		order.Amount,
+		order.MenuVersionID,
+		order.Channel,
+		order.TableNumber,
+		order.DeliveryAddress,
+		order.PickupAt,
+		order.Tax,
+		order.DeliveryFee,
 		orderIDInt,
 	)
 	if err != nil {
@@ -260,12 +370,12 @@ func (r *orderRepo) DeleteOrder(ctx context.Context, id string) error {
 		return fmt.Errorf("invalid order ID: %w", err)
 	}
 
-	_, err = r.DB.ExecContext(ctx, `DELETE FROM Order_Items WHERE Order_ID = $1`, orderIDInt)
+	_, err = r.q.ExecContext(ctx, `DELETE FROM Order_Items WHERE Order_ID = $1`, orderIDInt)
 	if err != nil {
 		return fmt.Errorf("delete order items: %w", err)
 	}
 
-	_, err = r.DB.ExecContext(ctx, `DELETE FROM Orders WHERE Order_ID = $1`, orderIDInt)
+	_, err = r.q.ExecContext(ctx, `DELETE FROM Orders WHERE Order_ID = $1`, orderIDInt)
 	if err != nil {
 		return fmt.Errorf("delete order: %w", err)
 	}
@@ -277,7 +387,19 @@ func (r *orderRepo) DeleteOrder(ctx context.Context, id string) error {
 	return nil
 }
 
-func (r *orderRepo) CheckInventoryForOrder(ctx context.Context, order *models.Purchase) (bool, error) {
+// CheckInventoryForOrder reports whether the stock not yet reserved covers
+// the order. The reservations of excludeOrderID, the order being changed,
+// are counted as free.
+func (r *orderRepo) CheckInventoryForOrder(ctx context.Context, order *models.Purchase, excludeOrderID string) (bool, error) {
+	var exclude *int
+	if excludeOrderID != "" {
+		id, err := strconv.Atoi(excludeOrderID)
+		if err != nil {
+			return false, fmt.Errorf("invalid order ID: %w", err)
+		}
+		exclude = &id
+	}
+
 	type pair struct {
 		MenuItemID int
 		Quantity   int
@@ -285,7 +407,7 @@ func (r *orderRepo) CheckInventoryForOrder(ctx context.Context, order *models.Pu
 
 	var menuPairs []pair
 
-	for _, item := range order.Positions {
+	for _, item := range order.ComponentLines() {
 		id, err := strconv.Atoi(item.ItemID)
 		if err != nil {
 			return false, fmt.Errorf("invalid menu item ID: %w", err)
@@ -314,8 +436,9 @@ func (r *orderRepo) CheckInventoryForOrder(ctx context.Context, order *models.Pu
 			SELECT 
 				i.Inventory_ID,
 				i.Quantity - COALESCE(SUM(ir.Reserved_Quantity), 0) AS Available_Quantity
-			FROM Inventory i
+			FROM Usable_Stock i
 			LEFT JOIN Inventory_Reservations ir ON ir.Inventory_ID = i.Inventory_ID
+				AND ir.Order_ID IS DISTINCT FROM $3::INTEGER
 			GROUP BY i.Inventory_ID, i.Quantity
 		)
 		SELECT ni.Inventory_ID, ni.Required_Quantity, a.Available_Quantity
@@ -324,7 +447,7 @@ func (r *orderRepo) CheckInventoryForOrder(ctx context.Context, order *models.Pu
 		WHERE ni.Required_Quantity > a.Available_Quantity
 	`
 
-	rows, err := r.DB.QueryContext(ctx, query, pq.Array(menuIDs), pq.Array(quantities))
+	rows, err := r.q.QueryContext(ctx, query, pq.Array(menuIDs), pq.Array(quantities), exclude)
 	if err != nil {
 		return false, fmt.Errorf("check inventory: %w", err)
 	}
@@ -344,12 +467,29 @@ func (r *orderRepo) CloseOrder(ctx context.Context, order *models.Purchase) erro
 	}
 
 	query := `
+		INSERT INTO Order_Consumption (Order_ID, Menu_Item_ID, Inventory_ID, Quantity, Unit_Cost)
+		SELECT ir.Order_ID, ir.Menu_Item_ID, ir.Inventory_ID, ir.Reserved_Quantity, i.Price
+		FROM Inventory_Reservations ir
+		JOIN Inventory i ON i.Inventory_ID = ir.Inventory_ID
+		WHERE ir.Order_ID = $1
+	`
+	_, err = r.q.ExecContext(ctx, query, orderIDInt)
+	if err != nil {
+		return fmt.Errorf("record consumption: %w", err)
+	}
+
+	query = `
 		UPDATE Inventory
 		SET Quantity = Quantity - ir.Reserved_Quantity
-		FROM Inventory_Reservations ir
-		WHERE ir.Order_ID = $1 AND Inventory.Inventory_ID = ir.Inventory_ID
+		FROM (
+			SELECT Inventory_ID, SUM(Reserved_Quantity) AS Reserved_Quantity
+			FROM Inventory_Reservations
+			WHERE Order_ID = $1
+			GROUP BY Inventory_ID
+		) ir
+		WHERE Inventory.Inventory_ID = ir.Inventory_ID
 	`
-	_, err = r.DB.ExecContext(ctx, query, orderIDInt)
+	_, err = r.q.ExecContext(ctx, query, orderIDInt)
 	if err != nil {
 		return fmt.Errorf("deduct inventory: %w", err)
 	}
@@ -359,10 +499,11 @@ func (r *orderRepo) CloseOrder(ctx context.Context, order *models.Purchase) erro
 		SET Status = 'completed', Updated_At = NOW()
 		WHERE Order_ID = $1
 	`
-	_, err = r.DB.ExecContext(ctx, query, orderIDInt)
+	_, err = r.q.ExecContext(ctx, query, orderIDInt)
 	if err != nil {
 		return fmt.Errorf("update status: %w", err)
 	}
+	order.Status = "completed"
 
 	if err := r.removeReserve(ctx, order.PurchaseID); err != nil {
 		return fmt.Errorf("remove reserve: %w", err)
@@ -375,21 +516,25 @@ func (r *orderRepo) CloseOrder(ctx context.Context, order *models.Purchase) erro
 	return nil
 }
 
+// GetNumberOfOrderedItems counts, per menu item, the orders that include it.
+// Only the lines ordered as such are counted, not the components of bundles.
 func (r *orderRepo) GetNumberOfOrderedItems(ctx context.Context, startDate, endDate *time.Time) (map[string]int, error) {
 	results := make(map[string]int)
 
 	query := `
 		SELECT 
 			mi.Name AS menu_item_name, 
-			COUNT(*) AS order_count
+			-- an item may be on several lines of an order
+			COUNT(DISTINCT oi.Order_ID) AS order_count
 		FROM Order_Items oi
 		JOIN Menu_Items mi ON oi.Menu_Item_ID = mi.Menu_Item_ID
 		WHERE oi.Created_At BETWEEN $1 AND $2
+			AND oi.Parent_Item_ID IS NULL
 		GROUP BY mi.Name
 		ORDER BY order_count DESC
 	`
 
-	rows, err := r.DB.QueryContext(ctx, query, startDate, endDate)
+	rows, err := r.q.QueryContext(ctx, query, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("query ordered items: %w", err)
 	}
@@ -410,3 +555,206 @@ func (r *orderRepo) GetNumberOfOrderedItems(ctx context.Context, startDate, endD
 
 	return results, nil
 }
+
+// GetReservations lists the stock held per order, oldest reservation first,
+// with its age in seconds as seen by the database clock.
+func (r *orderRepo) GetReservations(ctx context.Context) ([]*models.OrderReservation, error) {
+	query := `
+		SELECT o.Order_ID, o.Customer_ID, o.Status, o.Updated_At, o.Pickup_At::TIMESTAMPTZ,
+		       MIN(ir.Created_At) OVER (PARTITION BY o.Order_ID) AS Reserved_At,
+		       EXTRACT(EPOCH FROM NOW() - MIN(ir.Created_At) OVER (PARTITION BY o.Order_ID))::BIGINT,
+		       ir.Inventory_ID, i.Name, ir.Reserved_Quantity
+		FROM Inventory_Reservations ir
+		JOIN Orders o ON o.Order_ID = ir.Order_ID
+		JOIN Inventory i ON i.Inventory_ID = ir.Inventory_ID
+		ORDER BY Reserved_At, o.Order_ID, ir.Reservation_ID
+	`
+
+	rows, err := r.q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query reservations: %w", err)
+	}
+	defer rows.Close()
+
+	reservations := []*models.OrderReservation{}
+	var current *models.OrderReservation
+	for rows.Next() {
+		var (
+			res            models.OrderReservation
+			stock          models.ReservedStock
+			orderID, invID int
+			ageSeconds     int64
+		)
+		err := rows.Scan(&orderID, &res.CustomerID, &res.Status, &res.LastActivity, &res.PickupAt, &res.ReservedAt, &ageSeconds,
+			&invID, &stock.Title, &stock.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("scan reservation: %w", err)
+		}
+
+		if current == nil || current.OrderID != strconv.Itoa(orderID) {
+			res.OrderID = strconv.Itoa(orderID)
+			res.AgeMinutes = int(ageSeconds / 60)
+			res.Items = []*models.ReservedStock{}
+			current = &res
+			reservations = append(reservations, current)
+		}
+		stock.InventoryID = strconv.Itoa(invID)
+		current.Items = append(current.Items, &stock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return reservations, nil
+}
+
+// CancelStaleOrders cancels the open orders left unchanged for longer than ttl,
+// or for orders placed ahead not picked up ttl after their pickup time,
+// records the cancellation in their history and frees their reservations.
+// Reservations left behind by orders no longer waiting to be completed are
+// freed too.
+// Orders locked by a concurrent change are left for the next run.
+func (r *orderRepo) CancelStaleOrders(ctx context.Context, ttl time.Duration) ([]*models.Purchase, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE Orders
+		SET Status = 'canceled', Updated_At = NOW()
+		WHERE Order_ID IN (
+			SELECT Order_ID FROM Orders
+			WHERE Status = 'open' AND Updated_At < NOW() - make_interval(secs => $1)
+			  AND (Pickup_At IS NULL OR Pickup_At < NOW() - make_interval(secs => $1))
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING Order_ID, Customer_ID, Updated_At
+	`, ttl.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("cancel stale orders: %w", err)
+	}
+
+	var (
+		ids      []int
+		canceled []*models.Purchase
+	)
+	for rows.Next() {
+		var (
+			id      int
+			order   = &models.Purchase{Status: "canceled"}
+			updated time.Time
+		)
+		if err := rows.Scan(&id, &order.CustomerID, &updated); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan order id: %w", err)
+		}
+		order.PurchaseID = strconv.Itoa(id)
+		order.Updated = &updated
+		ids = append(ids, id)
+		canceled = append(canceled, order)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO Order_Status_History (Order_ID, Status)
+		SELECT UNNEST($1::int[]), 'canceled'
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("insert status history: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM Inventory_Reservations ir
+		USING Orders o
+		WHERE o.Order_ID = ir.Order_ID AND o.Status NOT IN ('open', 'in_progress', 'ready')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("free reservations: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return canceled, nil
+}
+
+// GetStockEvents reports the stock of every ingredient the given closed
+// orders consumed. The consumption and the stock left are read in one
+// statement, so they agree with each other; in a transaction that closed the
+// orders the rows are still locked and nothing else can have moved them.
+func (r *orderRepo) GetStockEvents(ctx context.Context, orderIDs []string) ([]*models.StockEvent, error) {
+	ids := make([]int, 0, len(orderIDs))
+	for _, id := range orderIDs {
+		idInt, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid order ID: %w", err)
+		}
+		ids = append(ids, idInt)
+	}
+
+	rows, err := r.q.QueryContext(ctx, `
+		SELECT i.Inventory_ID, i.Name, i.Unit, i.Quantity, c.Consumed, i.Reorder_Level
+		FROM (
+			SELECT Inventory_ID, SUM(Quantity) AS Consumed
+			FROM Order_Consumption
+			WHERE Order_ID = ANY($1)
+			GROUP BY Inventory_ID
+		) c
+		JOIN Inventory i ON i.Inventory_ID = c.Inventory_ID
+		ORDER BY i.Inventory_ID
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query stock events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.StockEvent{}
+	for rows.Next() {
+		var (
+			id                int
+			title, measure    string
+			closing, consumed float64
+			reorderLevel      *float64
+		)
+		if err := rows.Scan(&id, &title, &measure, &closing, &consumed, &reorderLevel); err != nil {
+			return nil, fmt.Errorf("scan stock event: %w", err)
+		}
+		events = append(events, models.NewStockEvent(strconv.Itoa(id), title, measure, closing, consumed, reorderLevel))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return events, nil
+}
+
+// GetStockLevels reads the quantity on hand of every inventory item, keyed
+// by inventory ID.
+func (r *orderRepo) GetStockLevels(ctx context.Context) (map[string]float64, error) {
+	rows, err := r.q.QueryContext(ctx, `SELECT Inventory_ID, Quantity FROM Inventory`)
+	if err != nil {
+		return nil, fmt.Errorf("query stock levels: %w", err)
+	}
+	defer rows.Close()
+
+	levels := make(map[string]float64)
+	for rows.Next() {
+		var (
+			id  int
+			qty float64
+		)
+		if err := rows.Scan(&id, &qty); err != nil {
+			return nil, fmt.Errorf("scan stock level: %w", err)
+		}
+		levels[strconv.Itoa(id)] = qty
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return levels, nil
+}
@@ -0,0 +1,133 @@
+package pickup_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+type PickupRepo interface {
+	GetHours(ctx context.Context) ([]*models.PickupHours, error)
+	GetHoursByDay(ctx context.Context, day int) (*models.PickupHours, error)
+	SaveHours(ctx context.Context, hours *models.PickupHours) error
+	DeleteHours(ctx context.Context, day int) error
+	GetBookings(ctx context.Context, from, to time.Time, excludeOrderID string) ([]*models.PickupBooking, error)
+}
+
+type pickupRepo struct {
+	DB *sql.DB
+}
+
+func NewPickupRepo(db *sql.DB) PickupRepo {
+	return &pickupRepo{
+		DB: db,
+	}
+}
+
+func (r *pickupRepo) GetHours(ctx context.Context) ([]*models.PickupHours, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT Day, TO_CHAR(Start_Time, 'HH24:MI'), TO_CHAR(End_Time, 'HH24:MI'), Capacity
+		FROM Pickup_Hours
+		ORDER BY Day
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query pickup hours: %w", err)
+	}
+	defer rows.Close()
+
+	hours := []*models.PickupHours{}
+	for rows.Next() {
+		var h models.PickupHours
+		if err := rows.Scan(&h.Day, &h.Start, &h.End, &h.Capacity); err != nil {
+			return nil, fmt.Errorf("scan pickup hours: %w", err)
+		}
+		hours = append(hours, &h)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return hours, nil
+}
+
+// GetHoursByDay returns the pickup hours of an ISO weekday, or sql.ErrNoRows
+// when pre-orders cannot be picked up that day.
+func (r *pickupRepo) GetHoursByDay(ctx context.Context, day int) (*models.PickupHours, error) {
+	var h models.PickupHours
+	err := r.DB.QueryRowContext(ctx, `
+		SELECT Day, TO_CHAR(Start_Time, 'HH24:MI'), TO_CHAR(End_Time, 'HH24:MI'), Capacity
+		FROM Pickup_Hours
+		WHERE Day = $1
+	`, day).Scan(&h.Day, &h.Start, &h.End, &h.Capacity)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// SaveHours sets the pickup hours of a weekday, replacing those it had.
+func (r *pickupRepo) SaveHours(ctx context.Context, hours *models.PickupHours) error {
+	_, err := r.DB.ExecContext(ctx, `
+		INSERT INTO Pickup_Hours (Day, Start_Time, End_Time, Capacity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (Day) DO UPDATE
+		SET Start_Time = EXCLUDED.Start_Time,
+		    End_Time = EXCLUDED.End_Time,
+		    Capacity = EXCLUDED.Capacity
+	`, hours.Day, hours.Start, hours.End, hours.Capacity)
+	if err != nil {
+		return fmt.Errorf("save pickup hours: %w", err)
+	}
+	return nil
+}
+
+func (r *pickupRepo) DeleteHours(ctx context.Context, day int) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM Pickup_Hours WHERE Day = $1`, day)
+	if err != nil {
+		return fmt.Errorf("delete pickup hours: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetBookings sums the items of the orders still to be picked up between
+// from and to, per pickup time. Canceled orders have given their slot back;
+// bundles count by their items. An order being changed is left out so it
+// does not compete with itself.
+func (r *pickupRepo) GetBookings(ctx context.Context, from, to time.Time, excludeOrderID string) ([]*models.PickupBooking, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT o.Pickup_At::TIMESTAMPTZ, SUM(oi.Quantity)::INTEGER
+		FROM Orders o
+		JOIN Order_Items oi ON oi.Order_ID = o.Order_ID AND NOT oi.Is_Bundle
+		WHERE o.Pickup_At >= $1::TIMESTAMPTZ AND o.Pickup_At < $2::TIMESTAMPTZ
+		  AND o.Status <> 'canceled'
+		  AND o.Order_ID::TEXT <> $3
+		GROUP BY o.Pickup_At
+		ORDER BY o.Pickup_At
+	`, from, to, excludeOrderID)
+	if err != nil {
+		return nil, fmt.Errorf("query pickup bookings: %w", err)
+	}
+	defer rows.Close()
+
+	var bookings []*models.PickupBooking
+	for rows.Next() {
+		var b models.PickupBooking
+		if err := rows.Scan(&b.PickupAt, &b.Items); err != nil {
+			return nil, fmt.Errorf("scan pickup booking: %w", err)
+		}
+		bookings = append(bookings, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return bookings, nil
+}
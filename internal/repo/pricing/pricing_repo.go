@@ -0,0 +1,269 @@
+package pricing_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+type PricingRepo interface {
+	CreateScheduledChange(ctx context.Context, change *models.ScheduledPriceChange) error
+	GetScheduledChanges(ctx context.Context, menuItemID string) ([]*models.ScheduledPriceChange, error)
+	CancelScheduledChange(ctx context.Context, menuItemID, changeID string) error
+	ApplyDueChanges(ctx context.Context, now time.Time) ([]*models.PriceHistory, error)
+	CreateWindow(ctx context.Context, window *models.PriceWindow) error
+	GetWindows(ctx context.Context) ([]*models.PriceWindow, error)
+	DeleteWindow(ctx context.Context, id string) error
+}
+
+type pricingRepo struct {
+	DB *sql.DB
+}
+
+func NewPricingRepo(db *sql.DB) PricingRepo {
+	return &pricingRepo{
+		DB: db,
+	}
+}
+
+func (r *pricingRepo) CreateScheduledChange(ctx context.Context, change *models.ScheduledPriceChange) error {
+	query := `
+		INSERT INTO Scheduled_Price_Changes (Menu_Item_ID, New_Price, Effective_At)
+		VALUES ($1, $2, $3)
+		RETURNING Change_ID, Created_At
+	`
+
+	var id int
+	err := r.DB.QueryRowContext(ctx, query, change.MenuItemID, change.NewPrice, change.EffectiveAt).
+		Scan(&id, &change.Created)
+	if err != nil {
+		return fmt.Errorf("insert scheduled change: %w", err)
+	}
+	change.ChangeID = strconv.Itoa(id)
+	return nil
+}
+
+func (r *pricingRepo) GetScheduledChanges(ctx context.Context, menuItemID string) ([]*models.ScheduledPriceChange, error) {
+	query := `
+		SELECT Change_ID, Menu_Item_ID, New_Price, Effective_At, Applied_At, Created_At
+		FROM Scheduled_Price_Changes
+		WHERE Menu_Item_ID = $1
+		ORDER BY Effective_At, Change_ID
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, menuItemID)
+	if err != nil {
+		return nil, fmt.Errorf("query scheduled changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []*models.ScheduledPriceChange{}
+	for rows.Next() {
+		var (
+			change        models.ScheduledPriceChange
+			id, productID int
+		)
+		err := rows.Scan(&id, &productID, &change.NewPrice, &change.EffectiveAt, &change.AppliedAt, &change.Created)
+		if err != nil {
+			return nil, fmt.Errorf("scan scheduled change: %w", err)
+		}
+		change.ChangeID = strconv.Itoa(id)
+		change.MenuItemID = strconv.Itoa(productID)
+		changes = append(changes, &change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return changes, nil
+}
+
+// CancelScheduledChange drops a change that has not been applied yet.
+func (r *pricingRepo) CancelScheduledChange(ctx context.Context, menuItemID, changeID string) error {
+	res, err := r.DB.ExecContext(ctx, `
+		DELETE FROM Scheduled_Price_Changes
+		WHERE Change_ID = $1 AND Menu_Item_ID = $2 AND Applied_At IS NULL
+	`, changeID, menuItemID)
+	if err != nil {
+		return fmt.Errorf("delete scheduled change: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ApplyDueChanges moves every change whose time has come onto the menu, in
+// effective order, and writes each into Price_History. Rows are locked so two
+// schedulers never apply the same change twice.
+func (r *pricingRepo) ApplyDueChanges(ctx context.Context, now time.Time) ([]*models.PriceHistory, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.Change_ID, c.Menu_Item_ID, c.New_Price, c.Effective_At, m.Price
+		FROM Scheduled_Price_Changes c
+		JOIN Menu_Items m ON m.Menu_Item_ID = c.Menu_Item_ID
+		WHERE c.Applied_At IS NULL AND c.Effective_At <= $1
+		ORDER BY c.Effective_At, c.Change_ID
+		FOR UPDATE OF c SKIP LOCKED
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("query due changes: %w", err)
+	}
+
+	type dueChange struct {
+		id, itemID  int
+		price, old  float64
+		effectiveAt time.Time
+	}
+	var due []dueChange
+	for rows.Next() {
+		var c dueChange
+		if err := rows.Scan(&c.id, &c.itemID, &c.price, &c.effectiveAt, &c.old); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan due change: %w", err)
+		}
+		due = append(due, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	// several changes of one item may fall due together
+	current := make(map[int]float64)
+	applied := make([]*models.PriceHistory, 0, len(due))
+
+	for _, c := range due {
+		old, ok := current[c.itemID]
+		if !ok {
+			old = c.old
+		}
+
+		_, err := tx.ExecContext(ctx, `UPDATE Menu_Items SET Price = $1 WHERE Menu_Item_ID = $2`, c.price, c.itemID)
+		if err != nil {
+			return nil, fmt.Errorf("update price: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO Price_History (Menu_Item_ID, Old_Price, New_Price, Changed_At)
+			VALUES ($1, $2, $3, $4)
+		`, c.itemID, old, c.price, c.effectiveAt)
+		if err != nil {
+			return nil, fmt.Errorf("insert price history: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE Scheduled_Price_Changes SET Applied_At = NOW() WHERE Change_ID = $1`, c.id)
+		if err != nil {
+			return nil, fmt.Errorf("mark change applied: %w", err)
+		}
+
+		current[c.itemID] = c.price
+		applied = append(applied, &models.PriceHistory{
+			MenuItemID: strconv.Itoa(c.itemID),
+			OldPrice:   old,
+			NewPrice:   c.price,
+			ChangeDate: c.effectiveAt.Format(time.RFC3339),
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (r *pricingRepo) CreateWindow(ctx context.Context, window *models.PriceWindow) error {
+	query := `
+		INSERT INTO Price_Windows (Name, Menu_Item_ID, Category_ID, Price, Discount_Pct, Days, Start_Time, End_Time)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING Window_ID
+	`
+
+	var id int
+	err := r.DB.QueryRowContext(ctx, query,
+		window.Name,
+		window.ItemID,
+		window.CategoryID,
+		window.Price,
+		window.DiscountPct,
+		window.Days,
+		window.Start,
+		window.End,
+	).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("insert price window: %w", err)
+	}
+	window.WindowID = strconv.Itoa(id)
+	return nil
+}
+
+func (r *pricingRepo) GetWindows(ctx context.Context) ([]*models.PriceWindow, error) {
+	query := `
+		SELECT Window_ID, Name, Menu_Item_ID, Category_ID, Price, Discount_Pct, Days,
+		       TO_CHAR(Start_Time, 'HH24:MI'), TO_CHAR(End_Time, 'HH24:MI')
+		FROM Price_Windows
+		ORDER BY Window_ID
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query price windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []*models.PriceWindow{}
+	for rows.Next() {
+		var (
+			window models.PriceWindow
+			id     int
+			itemID sql.NullInt64
+		)
+		err := rows.Scan(
+			&id,
+			&window.Name,
+			&itemID,
+			&window.CategoryID,
+			&window.Price,
+			&window.DiscountPct,
+			&window.Days,
+			&window.Start,
+			&window.End,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan price window: %w", err)
+		}
+		window.WindowID = strconv.Itoa(id)
+		if itemID.Valid {
+			ref := strconv.FormatInt(itemID.Int64, 10)
+			window.ItemID = &ref
+		}
+		windows = append(windows, &window)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return windows, nil
+}
+
+func (r *pricingRepo) DeleteWindow(ctx context.Context, id string) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM Price_Windows WHERE Window_ID = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete price window: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
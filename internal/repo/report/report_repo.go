@@ -0,0 +1,346 @@
+package report_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+type ReportRepo interface {
+	GetValuationInputs(ctx context.Context, asOf time.Time) ([]*models.ValuationInput, error)
+	GetMenuSales(ctx context.Context, from, to time.Time) ([]*models.MenuItemSales, error)
+	GetProfitByPeriod(ctx context.Context, from, to time.Time, groupBy string) ([]*models.ProfitLine, error)
+	GetProfitByCategory(ctx context.Context, from, to time.Time) ([]*models.ProfitLine, error)
+	GetPriceChangeSales(ctx context.Context, from, to time.Time, windowDays int) ([]*models.PriceImpactLine, error)
+}
+
+type reportRepo struct {
+	DB *sql.DB
+}
+
+func NewReportRepo(db *sql.DB) ReportRepo {
+	return &reportRepo{
+		DB: db,
+	}
+}
+
+// GetValuationInputs rebuilds the stock position of every ingredient at asOf
+// by backing the later movements out of the current quantity.
+func (r *reportRepo) GetValuationInputs(ctx context.Context, asOf time.Time) ([]*models.ValuationInput, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT
+			i.Inventory_ID,
+			i.Name,
+			i.Unit,
+			i.Price,
+			i.Quantity - COALESCE((
+				SELECT SUM(t.Change_Amount)
+				FROM Inventory_Transactions t
+				WHERE t.Inventory_ID = i.Inventory_ID AND t.Occurred_At > $1
+			), 0) AS On_Hand
+		FROM Inventory i
+		ORDER BY i.Inventory_ID
+	`, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("query stock position: %w", err)
+	}
+	defer rows.Close()
+
+	var inputs []*models.ValuationInput
+	byID := make(map[int]*models.ValuationInput)
+
+	for rows.Next() {
+		var (
+			in models.ValuationInput
+			id int
+		)
+		if err := rows.Scan(&id, &in.Title, &in.Measure, &in.CurrentCost, &in.Quantity); err != nil {
+			return nil, fmt.Errorf("scan stock position: %w", err)
+		}
+		in.ItemRef = strconv.Itoa(id)
+		in.Movements = []*models.CostLayer{}
+		byID[id] = &in
+		inputs = append(inputs, &in)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	movements, err := r.DB.QueryContext(ctx, `
+		SELECT t.Inventory_ID, t.Change_Amount, COALESCE(t.Unit_Cost, i.Price), t.Occurred_At
+		FROM Inventory_Transactions t
+		JOIN Inventory i ON i.Inventory_ID = t.Inventory_ID
+		WHERE t.Change_Amount <> 0 AND t.Occurred_At <= $1
+		ORDER BY t.Inventory_ID, t.Occurred_At, t.Transaction_ID
+	`, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("query movements: %w", err)
+	}
+	defer movements.Close()
+
+	for movements.Next() {
+		var (
+			layer models.CostLayer
+			id    int
+		)
+		if err := movements.Scan(&id, &layer.Quantity, &layer.UnitCost, &layer.Received); err != nil {
+			return nil, fmt.Errorf("scan movement: %w", err)
+		}
+		if in, ok := byID[id]; ok {
+			in.Movements = append(in.Movements, &layer)
+		}
+	}
+
+	if err := movements.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return inputs, nil
+}
+
+// GetMenuSales returns units sold and revenue of every menu item over the
+// completed orders of the period. Items without sales are included with zeros.
+// Items sold in a bundle are counted through its component lines, credited
+// with their allocated share of the bundle price; bundles are left out.
+func (r *reportRepo) GetMenuSales(ctx context.Context, from, to time.Time) ([]*models.MenuItemSales, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT
+			mi.Menu_Item_ID,
+			mi.Name,
+			cat.Name,
+			mi.Price,
+			COALESCE(SUM(oi.Quantity), 0)::FLOAT,
+			COALESCE(SUM(oi.Quantity * oi.Price), 0)::FLOAT
+		FROM Menu_Items mi
+		JOIN Categories cat ON cat.Category_ID = mi.Category_ID
+		LEFT JOIN (
+			SELECT oi.Menu_Item_ID, oi.Quantity, oi.Price
+			FROM Order_Items oi
+			JOIN Orders o ON o.Order_ID = oi.Order_ID
+			WHERE o.Status = 'completed' AND o.Created_At BETWEEN $1 AND $2 AND NOT oi.Is_Bundle
+		) oi ON oi.Menu_Item_ID = mi.Menu_Item_ID
+		WHERE NOT mi.Is_Bundle
+		GROUP BY mi.Menu_Item_ID, mi.Name, cat.Name, mi.Price
+		ORDER BY mi.Menu_Item_ID
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query menu sales: %w", err)
+	}
+	defer rows.Close()
+
+	var sales []*models.MenuItemSales
+	for rows.Next() {
+		var (
+			item models.MenuItemSales
+			id   int
+		)
+		if err := rows.Scan(&id, &item.Title, &item.Group, &item.Price, &item.Sold, &item.Revenue); err != nil {
+			return nil, fmt.Errorf("scan menu sales: %w", err)
+		}
+		item.ItemRef = strconv.Itoa(id)
+		sales = append(sales, &item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return sales, nil
+}
+
+// GetProfitByPeriod buckets revenue and COGS of completed orders and the cost
+// of written-off stock into day, week or month periods. Revenue is what the
+// items sold for: tax is collected for others and the delivery fee is not
+// item sales, so both are left out, as in GetProfitByCategory.
+func (r *reportRepo) GetProfitByPeriod(ctx context.Context, from, to time.Time, groupBy string) ([]*models.ProfitLine, error) {
+	query := `
+		WITH Revenue AS (
+			SELECT DATE_TRUNC($3, Created_At) AS Period, COUNT(*) AS Orders, SUM(Total_Amount - Tax_Amount - Delivery_Fee) AS Revenue
+			FROM Orders
+			WHERE Status = 'completed' AND Created_At BETWEEN $1 AND $2
+			GROUP BY 1
+		),
+		COGS AS (
+			SELECT DATE_TRUNC($3, o.Created_At) AS Period, SUM(c.Quantity * c.Unit_Cost) AS COGS
+			FROM Order_Consumption c
+			JOIN Orders o ON o.Order_ID = c.Order_ID
+			WHERE o.Status = 'completed' AND o.Created_At BETWEEN $1 AND $2
+			GROUP BY 1
+		),
+		Waste AS (
+			SELECT DATE_TRUNC($3, t.Occurred_At) AS Period, SUM(-t.Change_Amount * COALESCE(t.Unit_Cost, i.Price)) AS Waste
+			FROM Inventory_Transactions t
+			JOIN Inventory i ON i.Inventory_ID = t.Inventory_ID
+			WHERE t.Transaction_Type = 'waste' AND t.Occurred_At BETWEEN $1 AND $2
+			GROUP BY 1
+		)
+		SELECT
+			COALESCE(r.Period, c.Period, w.Period) AS Period,
+			COALESCE(r.Orders, 0),
+			COALESCE(r.Revenue, 0)::FLOAT,
+			COALESCE(c.COGS, 0)::FLOAT,
+			COALESCE(w.Waste, 0)::FLOAT
+		FROM Revenue r
+		FULL JOIN COGS c ON c.Period = r.Period
+		FULL JOIN Waste w ON w.Period = COALESCE(r.Period, c.Period)
+		ORDER BY 1
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, from, to, groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("query profit by period: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []*models.ProfitLine
+	for rows.Next() {
+		var (
+			line   models.ProfitLine
+			period time.Time
+		)
+		if err := rows.Scan(&period, &line.Orders, &line.Revenue, &line.COGS, &line.WasteCost); err != nil {
+			return nil, fmt.Errorf("scan profit line: %w", err)
+		}
+		line.Period = &period
+		lines = append(lines, &line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return lines, nil
+}
+
+// GetProfitByCategory splits item revenue and COGS of completed orders by
+// menu category. Lines of deleted menu items are reported as "Uncategorized";
+// bundle revenue is reported under the categories of its components.
+func (r *reportRepo) GetProfitByCategory(ctx context.Context, from, to time.Time) ([]*models.ProfitLine, error) {
+	query := `
+		WITH Revenue AS (
+			SELECT
+				COALESCE(cat.Name, 'Uncategorized') AS Category,
+				COUNT(DISTINCT o.Order_ID) AS Orders,
+				SUM(oi.Quantity * oi.Price) AS Revenue
+			FROM Order_Items oi
+			JOIN Orders o ON o.Order_ID = oi.Order_ID
+			LEFT JOIN Menu_Items mi ON mi.Menu_Item_ID = oi.Menu_Item_ID
+			LEFT JOIN Categories cat ON cat.Category_ID = mi.Category_ID
+			WHERE o.Status = 'completed' AND o.Created_At BETWEEN $1 AND $2 AND NOT oi.Is_Bundle
+			GROUP BY 1
+		),
+		COGS AS (
+			SELECT
+				COALESCE(cat.Name, 'Uncategorized') AS Category,
+				SUM(c.Quantity * c.Unit_Cost) AS COGS
+			FROM Order_Consumption c
+			JOIN Orders o ON o.Order_ID = c.Order_ID
+			LEFT JOIN Menu_Items mi ON mi.Menu_Item_ID = c.Menu_Item_ID
+			LEFT JOIN Categories cat ON cat.Category_ID = mi.Category_ID
+			WHERE o.Status = 'completed' AND o.Created_At BETWEEN $1 AND $2
+			GROUP BY 1
+		)
+		SELECT
+			COALESCE(r.Category, c.Category),
+			COALESCE(r.Orders, 0),
+			COALESCE(r.Revenue, 0)::FLOAT,
+			COALESCE(c.COGS, 0)::FLOAT
+		FROM Revenue r
+		FULL JOIN COGS c ON c.Category = r.Category
+		ORDER BY 1
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query profit by category: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []*models.ProfitLine
+	for rows.Next() {
+		var line models.ProfitLine
+		if err := rows.Scan(&line.Category, &line.Orders, &line.Revenue, &line.COGS); err != nil {
+			return nil, fmt.Errorf("scan profit line: %w", err)
+		}
+		lines = append(lines, &line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return lines, nil
+}
+
+// GetPriceChangeSales returns every price change of the period with the units
+// and revenue of the item over windowDays before and after it. Only lines
+// ordered on their own count: items sold inside a bundle were not sold at
+// their list price.
+func (r *reportRepo) GetPriceChangeSales(ctx context.Context, from, to time.Time, windowDays int) ([]*models.PriceImpactLine, error) {
+	query := `
+		SELECT
+			ph.Price_ID,
+			ph.Menu_Item_ID,
+			mi.Name,
+			ph.Old_Price,
+			ph.New_Price,
+			ph.Changed_At,
+			COALESCE(SUM(oi.Quantity) FILTER (WHERE o.Created_At < ph.Changed_At), 0)::FLOAT,
+			COALESCE(SUM(oi.Quantity * oi.Price) FILTER (WHERE o.Created_At < ph.Changed_At), 0)::FLOAT,
+			COALESCE(SUM(oi.Quantity) FILTER (WHERE o.Created_At >= ph.Changed_At), 0)::FLOAT,
+			COALESCE(SUM(oi.Quantity * oi.Price) FILTER (WHERE o.Created_At >= ph.Changed_At), 0)::FLOAT
+		FROM Price_History ph
+		JOIN Menu_Items mi ON mi.Menu_Item_ID = ph.Menu_Item_ID
+		LEFT JOIN Order_Items oi ON oi.Menu_Item_ID = ph.Menu_Item_ID AND oi.Parent_Item_ID IS NULL
+		LEFT JOIN Orders o ON o.Order_ID = oi.Order_ID
+			AND o.Status = 'completed'
+			AND o.Created_At >= ph.Changed_At - make_interval(days => $3)
+			AND o.Created_At < ph.Changed_At + make_interval(days => $3)
+		WHERE ph.Changed_At BETWEEN $1 AND $2
+		GROUP BY ph.Price_ID, ph.Menu_Item_ID, mi.Name, ph.Old_Price, ph.New_Price, ph.Changed_At
+		ORDER BY ph.Changed_At, ph.Price_ID
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, from, to, windowDays)
+	if err != nil {
+		return nil, fmt.Errorf("query price change sales: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []*models.PriceImpactLine
+	for rows.Next() {
+		var (
+			line          models.PriceImpactLine
+			id, productID int
+		)
+		err := rows.Scan(
+			&id,
+			&productID,
+			&line.Title,
+			&line.OldPrice,
+			&line.NewPrice,
+			&line.ChangedAt,
+			&line.Before.Units,
+			&line.Before.Revenue,
+			&line.After.Units,
+			&line.After.Revenue,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan price change sales: %w", err)
+		}
+		line.PriceID = strconv.Itoa(id)
+		line.ItemRef = strconv.Itoa(productID)
+		lines = append(lines, &line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return lines, nil
+}
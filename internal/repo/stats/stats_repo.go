@@ -12,11 +12,11 @@ import (
 )
 
 type StatsRepo interface {
-	GetPopularItem(ctx context.Context) ([]models.TopProduct, error)
-	GetTotalSum(ctx context.Context) (*models.RevenueSummary, error)
+	GetPopularItem(ctx context.Context, channel string) ([]models.TopProduct, error)
+	GetTotalSum(ctx context.Context, channel string) (*models.RevenueSummary, error)
 	SearchMenu(ctx context.Context, query string, minPrice, maxPrice *float64) ([]models.ProductPreview, error)
-	SearchOrders(ctx context.Context, query string, minPrice, maxPrice *float64) ([]models.OrderBrief, error)
-	GetItemByPeriod(ctx context.Context, period string, month string, year int) ([]models.OrderStats, error)
+	SearchOrders(ctx context.Context, query string, minPrice, maxPrice *float64, channel string) ([]models.OrderBrief, error)
+	GetItemByPeriod(ctx context.Context, period string, month string, year int, channel string) ([]models.OrderStats, error)
 }
 
 type statsRepo struct {
@@ -29,7 +29,7 @@ func NewStatsRepo(db *sql.DB) StatsRepo {
 	}
 }
 
-func (m *statsRepo) GetPopularItem(ctx context.Context) ([]models.TopProduct, error) {
+func (m *statsRepo) GetPopularItem(ctx context.Context, channel string) ([]models.TopProduct, error) {
 	query := `
 		SELECT 
 			oi.Menu_Item_ID, 
@@ -37,13 +37,15 @@ func (m *statsRepo) GetPopularItem(ctx context.Context) ([]models.TopProduct, er
 			SUM(oi.Quantity)::FLOAT AS total_quantity
 		FROM Order_Items oi
 		JOIN Menu_Items mi ON oi.Menu_Item_ID = mi.Menu_Item_ID
+		JOIN Orders o ON o.Order_ID = oi.Order_ID
 		WHERE oi.Menu_Item_ID IS NOT NULL
+		  AND ($1 = '' OR o.Channel::TEXT = $1)
 		GROUP BY oi.Menu_Item_ID, mi.Name
 		ORDER BY total_quantity DESC
 		LIMIT 10;
 	`
 
-	rows, err := m.DB.QueryContext(ctx, query)
+	rows, err := m.DB.QueryContext(ctx, query, channel)
 	if err != nil {
 		return nil, err
 	}
@@ -65,10 +67,10 @@ func (m *statsRepo) GetPopularItem(ctx context.Context) ([]models.TopProduct, er
 	return items, nil
 }
 
-func (m *statsRepo) GetTotalSum(ctx context.Context) (*models.RevenueSummary, error) {
+func (m *statsRepo) GetTotalSum(ctx context.Context, channel string) (*models.RevenueSummary, error) {
 	totalSales := &models.RevenueSummary{}
-	query := `SELECT COALESCE(SUM(total_amount), 0) FROM orders`
-	err := m.DB.QueryRowContext(ctx, query).Scan(&totalSales.Sum)
+	query := `SELECT COALESCE(SUM(total_amount - tax_amount - delivery_fee), 0) FROM orders WHERE status = 'completed' AND ($1 = '' OR channel::TEXT = $1)`
+	err := m.DB.QueryRowContext(ctx, query, channel).Scan(&totalSales.Sum)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			totalSales.Sum = 0
@@ -85,7 +87,7 @@ func (m *statsRepo) SearchMenu(ctx context.Context, query string, minPrice, maxP
 	baseQuery := `
         SELECT Menu_Item_ID AS id, Name, Description, Price
         FROM Menu_Items
-        WHERE (Name ILIKE $1 OR Description ILIKE $1) 
+        WHERE (Name ILIKE $1 OR Description ILIKE $1) AND Archived_At IS NULL
     `
 	params := []interface{}{"%" + query + "%"}
 	conditions := ""
@@ -118,7 +120,7 @@ func (m *statsRepo) SearchMenu(ctx context.Context, query string, minPrice, maxP
 	return results, nil
 }
 
-func (m *statsRepo) SearchOrders(ctx context.Context, query string, minPrice, maxPrice *float64) ([]models.OrderBrief, error) {
+func (m *statsRepo) SearchOrders(ctx context.Context, query string, minPrice, maxPrice *float64, channel string) ([]models.OrderBrief, error) {
 	var results []models.OrderBrief
 	baseQuery := `
         SELECT 
@@ -144,6 +146,10 @@ func (m *statsRepo) SearchOrders(ctx context.Context, query string, minPrice, ma
 		conditions += " AND o.Total_Amount <= $" + strconv.Itoa(len(params)+1)
 		params = append(params, *maxPrice)
 	}
+	if channel != "" {
+		conditions += " AND o.Channel::TEXT = $" + strconv.Itoa(len(params)+1)
+		params = append(params, channel)
+	}
 
 	baseQuery += conditions + " GROUP BY o.Order_ID, c.Name ORDER BY o.Created_At DESC"
 
@@ -166,7 +172,7 @@ func (m *statsRepo) SearchOrders(ctx context.Context, query string, minPrice, ma
 	return results, nil
 }
 
-func (m *statsRepo) GetItemByPeriod(ctx context.Context, period string, month string, year int) ([]models.OrderStats, error) {
+func (m *statsRepo) GetItemByPeriod(ctx context.Context, period string, month string, year int, channel string) ([]models.OrderStats, error) {
 	var query string
 	var rows *sql.Rows
 	var err error
@@ -177,19 +183,21 @@ func (m *statsRepo) GetItemByPeriod(ctx context.Context, period string, month st
 			FROM Orders
 			WHERE EXTRACT(MONTH FROM Created_At) = EXTRACT(MONTH FROM TO_DATE($1, 'Month'))
 			AND EXTRACT(YEAR FROM Created_At) = $2
+			AND ($3 = '' OR Channel::TEXT = $3)
 			GROUP BY period
 			ORDER BY period;
 		`
-		rows, err = m.DB.QueryContext(ctx, query, month, year)
+		rows, err = m.DB.QueryContext(ctx, query, month, year, channel)
 	} else if period == "month" {
 		query = `
 			SELECT DATE_TRUNC('month', Created_At) AS period, COUNT(*) AS total_orders
 			FROM Orders
 			WHERE EXTRACT(YEAR FROM Created_At) = $1
+			AND ($2 = '' OR Channel::TEXT = $2)
 			GROUP BY period
 			ORDER BY period;
 		`
-		rows, err = m.DB.QueryContext(ctx, query, year)
+		rows, err = m.DB.QueryContext(ctx, query, year, channel)
 	} else {
 		return nil, fmt.Errorf("invalid period: %s", period)
 	}
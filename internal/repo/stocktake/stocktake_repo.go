@@ -0,0 +1,389 @@
+package stocktake_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"frappuccino/internal/models"
+)
+
+type StockTakeRepo interface {
+	CreateStockTake(ctx context.Context, st *models.StockTake) error
+	GetStockTakeByID(ctx context.Context, id string) (*models.StockTake, error)
+	SaveCounts(ctx context.Context, id string, submission *models.CountSubmission) error
+	CommitStockTake(ctx context.Context, id string) (*models.VarianceReport, error)
+	CancelStockTake(ctx context.Context, id string) error
+	GetVarianceReport(ctx context.Context, id string) (*models.VarianceReport, error)
+}
+
+type stockTakeRepo struct {
+	DB *sql.DB
+}
+
+func NewStockTakeRepo(db *sql.DB) StockTakeRepo {
+	return &stockTakeRepo{
+		DB: db,
+	}
+}
+
+func (r *stockTakeRepo) CreateStockTake(ctx context.Context, st *models.StockTake) error {
+	query := `
+		INSERT INTO Stock_Takes (Note)
+		VALUES ($1)
+		RETURNING Stock_Take_ID, Status, Started_At
+	`
+
+	var id int
+	err := r.DB.QueryRowContext(ctx, query, st.Note).Scan(&id, &st.Status, &st.Started)
+	if err != nil {
+		return fmt.Errorf("insert stock take: %w", err)
+	}
+
+	st.StockTakeID = strconv.Itoa(id)
+	st.Counts = []*models.StockCount{}
+	return nil
+}
+
+func (r *stockTakeRepo) GetStockTakeByID(ctx context.Context, id string) (*models.StockTake, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stock take ID: %w", err)
+	}
+
+	query := `
+		SELECT Stock_Take_ID, Status, Note, Started_At, Committed_At
+		FROM Stock_Takes
+		WHERE Stock_Take_ID = $1
+	`
+
+	var (
+		st     models.StockTake
+		stID   int
+		note   sql.NullString
+		commit sql.NullTime
+	)
+	err = r.DB.QueryRowContext(ctx, query, intID).Scan(&stID, &st.Status, &note, &st.Started, &commit)
+	if err != nil {
+		return nil, fmt.Errorf("query stock take: %w", err)
+	}
+
+	st.StockTakeID = strconv.Itoa(stID)
+	if note.Valid {
+		st.Note = &note.String
+	}
+	if commit.Valid {
+		st.Committed = &commit.Time
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT Inventory_ID, Device, Counted_Quantity, Counted_At
+		FROM Stock_Take_Counts
+		WHERE Stock_Take_ID = $1
+		ORDER BY Inventory_ID, Device
+	`, intID)
+	if err != nil {
+		return nil, fmt.Errorf("query counts: %w", err)
+	}
+	defer rows.Close()
+
+	st.Counts = []*models.StockCount{}
+	for rows.Next() {
+		var (
+			count models.StockCount
+			invID int
+		)
+		if err := rows.Scan(&invID, &count.Device, &count.CountedQty, &count.CountedAt); err != nil {
+			return nil, fmt.Errorf("scan count: %w", err)
+		}
+		count.ItemRef = strconv.Itoa(invID)
+		st.Counts = append(st.Counts, &count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return &st, nil
+}
+
+// SaveCounts stores the quantities counted by one device. A device that
+// counts the same item again overwrites its previous figure, while counts
+// from different devices are summed on commit.
+func (r *stockTakeRepo) SaveCounts(ctx context.Context, id string, submission *models.CountSubmission) error {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid stock take ID: %w", err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO Stock_Take_Counts (Stock_Take_ID, Inventory_ID, Device, Counted_Quantity)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (Stock_Take_ID, Inventory_ID, Device)
+		DO UPDATE SET Counted_Quantity = EXCLUDED.Counted_Quantity, Counted_At = NOW()
+	`
+
+	for _, count := range submission.Counts {
+		invID, err := strconv.Atoi(count.ItemRef)
+		if err != nil {
+			return fmt.Errorf("invalid inventory ID: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, query, intID, invID, submission.Device, count.CountedQty)
+		if err != nil {
+			return fmt.Errorf("upsert count: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CommitStockTake compares the counted quantities with the theoretical stock
+// and writes the differences back to Inventory as adjustment transactions.
+//
+// The theoretical quantity of an item is reconciled with orders closed while
+// the session was open: every stock movement recorded after the count of the
+// item is backed out of the current quantity, so the count is compared with
+// what the system believed was on the shelf at the moment it was taken. When
+// devices counted an item at different moments, their counts are only summed
+// if no stock moved in between; otherwise the commit fails with
+// models.ErrCountsSpanMovement and the item has to be counted again.
+func (r *stockTakeRepo) CommitStockTake(ctx context.Context, id string) (*models.VarianceReport, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stock take ID: %w", err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &models.VarianceReport{
+		StockTakeID: strconv.Itoa(intID),
+		Lines:       []*models.VarianceLine{},
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		UPDATE Stock_Takes
+		SET Status = 'committed', Committed_At = NOW()
+		WHERE Stock_Take_ID = $1 AND Status = 'open'
+		RETURNING Committed_At
+	`, intID).Scan(&report.Committed)
+	if err != nil {
+		return nil, fmt.Errorf("close stock take: %w", err)
+	}
+
+	// Lock counted items so no order can be closed against them mid-commit
+	_, err = tx.ExecContext(ctx, `
+		SELECT Inventory_ID FROM Inventory
+		WHERE Inventory_ID IN (SELECT Inventory_ID FROM Stock_Take_Counts WHERE Stock_Take_ID = $1)
+		ORDER BY Inventory_ID
+		FOR UPDATE
+	`, intID)
+	if err != nil {
+		return nil, fmt.Errorf("lock inventory: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH Counted AS (
+			SELECT
+				Inventory_ID,
+				SUM(Counted_Quantity) AS Counted,
+				MIN(Counted_At) AS First_Counted_At,
+				MAX(Counted_At) AS Counted_At
+			FROM Stock_Take_Counts
+			WHERE Stock_Take_ID = $1
+			GROUP BY Inventory_ID
+		), Lines AS (
+			SELECT
+				i.Inventory_ID,
+				i.Name,
+				i.Unit,
+				i.Price,
+				i.Quantity - COALESCE((
+					SELECT SUM(t.Change_Amount)
+					FROM Inventory_Transactions t
+					WHERE t.Inventory_ID = i.Inventory_ID AND t.Occurred_At > c.Counted_At
+				), 0) AS Theoretical,
+				c.Counted,
+				EXISTS (
+					SELECT 1
+					FROM Inventory_Transactions t
+					WHERE t.Inventory_ID = i.Inventory_ID
+						AND t.Occurred_At > c.First_Counted_At
+						AND t.Occurred_At <= c.Counted_At
+				) AS Moved
+			FROM Counted c
+			JOIN Inventory i ON i.Inventory_ID = c.Inventory_ID
+		)
+		SELECT
+			Inventory_ID,
+			Name,
+			Unit,
+			Price,
+			Theoretical,
+			Counted,
+			ROUND(Counted - Theoretical, 4) AS Variance,
+			ROUND(ROUND(Counted - Theoretical, 4) * Price, 2) AS Variance_Value,
+			Moved
+		FROM Lines
+		ORDER BY Inventory_ID
+	`, intID)
+	if err != nil {
+		return nil, fmt.Errorf("query variance: %w", err)
+	}
+
+	for rows.Next() {
+		var (
+			line  models.VarianceLine
+			invID int
+			moved bool
+		)
+		if err := rows.Scan(&invID, &line.Title, &line.Measure, &line.UnitCost, &line.TheoreticalQty, &line.CountedQty, &line.Variance, &line.VarianceValue, &moved); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan variance: %w", err)
+		}
+		if moved {
+			rows.Close()
+			return nil, fmt.Errorf("item %s: %w", line.Title, models.ErrCountsSpanMovement)
+		}
+		line.ItemRef = strconv.Itoa(invID)
+		report.Lines = append(report.Lines, &line)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `SELECT set_config('frappuccino.transaction_type', 'adjustment', true)`)
+	if err != nil {
+		return nil, fmt.Errorf("label adjustments: %w", err)
+	}
+
+	for _, line := range report.Lines {
+		if line.Variance != 0 {
+			_, err = tx.ExecContext(ctx, `
+				UPDATE Inventory SET Quantity = Quantity + $1 WHERE Inventory_ID = $2
+			`, line.Variance, line.ItemRef)
+			if err != nil {
+				return nil, fmt.Errorf("adjust inventory: %w", err)
+			}
+			report.ItemsAdjusted++
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO Stock_Take_Lines (
+				Stock_Take_ID, Inventory_ID, Theoretical_Quantity, Counted_Quantity,
+				Variance, Unit_Price, Variance_Value
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, intID, line.ItemRef, line.TheoreticalQty, line.CountedQty, line.Variance, line.UnitCost, line.VarianceValue)
+		if err != nil {
+			return nil, fmt.Errorf("insert variance line: %w", err)
+		}
+
+		report.TotalValue += line.VarianceValue
+	}
+
+	report.ItemsCounted = len(report.Lines)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+
+	return report, nil
+}
+
+func (r *stockTakeRepo) CancelStockTake(ctx context.Context, id string) error {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid stock take ID: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE Stock_Takes SET Status = 'canceled'
+		WHERE Stock_Take_ID = $1 AND Status = 'open'
+	`, intID)
+	if err != nil {
+		return fmt.Errorf("cancel stock take: %w", err)
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *stockTakeRepo) GetVarianceReport(ctx context.Context, id string) (*models.VarianceReport, error) {
+	intID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stock take ID: %w", err)
+	}
+
+	report := &models.VarianceReport{
+		StockTakeID: strconv.Itoa(intID),
+		Lines:       []*models.VarianceLine{},
+	}
+
+	err = r.DB.QueryRowContext(ctx, `
+		SELECT Committed_At FROM Stock_Takes
+		WHERE Stock_Take_ID = $1 AND Status = 'committed'
+	`, intID).Scan(&report.Committed)
+	if err != nil {
+		return nil, fmt.Errorf("query stock take: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT l.Inventory_ID, i.Name, i.Unit, l.Theoretical_Quantity, l.Counted_Quantity,
+		       l.Variance, l.Unit_Price, l.Variance_Value
+		FROM Stock_Take_Lines l
+		JOIN Inventory i ON i.Inventory_ID = l.Inventory_ID
+		WHERE l.Stock_Take_ID = $1
+		ORDER BY l.Inventory_ID
+	`, intID)
+	if err != nil {
+		return nil, fmt.Errorf("query variance lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			line  models.VarianceLine
+			invID int
+		)
+		err := rows.Scan(
+			&invID,
+			&line.Title,
+			&line.Measure,
+			&line.TheoreticalQty,
+			&line.CountedQty,
+			&line.Variance,
+			&line.UnitCost,
+			&line.VarianceValue,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan variance line: %w", err)
+		}
+		line.ItemRef = strconv.Itoa(invID)
+		if line.Variance != 0 {
+			report.ItemsAdjusted++
+		}
+		report.TotalValue += line.VarianceValue
+		report.Lines = append(report.Lines, &line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	report.ItemsCounted = len(report.Lines)
+	return report, nil
+}
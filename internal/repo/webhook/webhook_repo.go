@@ -0,0 +1,345 @@
+package webhook_repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepo interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	GetAll(ctx context.Context) ([]*models.Webhook, error)
+	GetByID(ctx context.Context, id string) (*models.Webhook, error)
+	Update(ctx context.Context, id string, webhook *models.Webhook) error
+	Delete(ctx context.Context, id string) error
+	GetDeliveries(ctx context.Context, webhookID, status string, limit int) ([]*models.WebhookDelivery, error)
+	Retry(ctx context.Context, webhookID, deliveryID string) error
+	DispatchOutbox(ctx context.Context, limit int) (int, error)
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, deliveryID string, attempt *models.WebhookAttempt, status string, retryIn time.Duration) error
+}
+
+type webhookRepo struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepo(db *sql.DB) WebhookRepo {
+	return &webhookRepo{
+		DB: db,
+	}
+}
+
+func (r *webhookRepo) Create(ctx context.Context, webhook *models.Webhook) error {
+	var id int
+	err := r.DB.QueryRowContext(ctx, `
+		INSERT INTO Webhooks (URL, Secret, Event_Types, Active)
+		VALUES ($1, $2, $3, $4)
+		RETURNING Webhook_ID, Created_At
+	`, webhook.URL, webhook.Secret, pq.Array(webhook.EventTypes), webhook.Active).Scan(&id, &webhook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert webhook: %w", err)
+	}
+	webhook.WebhookID = strconv.Itoa(id)
+	return nil
+}
+
+// GetAll lists the webhooks without their secrets.
+func (r *webhookRepo) GetAll(ctx context.Context) ([]*models.Webhook, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT Webhook_ID, URL, Event_Types, Active, Created_At
+		FROM Webhooks
+		ORDER BY Webhook_ID
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return webhooks, nil
+}
+
+func (r *webhookRepo) GetByID(ctx context.Context, id string) (*models.Webhook, error) {
+	row := r.DB.QueryRowContext(ctx, `
+		SELECT Webhook_ID, URL, Event_Types, Active, Created_At
+		FROM Webhooks
+		WHERE Webhook_ID = $1
+	`, id)
+	return scanWebhook(row)
+}
+
+func scanWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	var (
+		webhook   models.Webhook
+		id        int
+		active    bool
+		createdAt time.Time
+	)
+	err := row.Scan(&id, &webhook.URL, (*pq.StringArray)(&webhook.EventTypes), &active, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	webhook.WebhookID = strconv.Itoa(id)
+	webhook.Active = &active
+	webhook.CreatedAt = &createdAt
+	return &webhook, nil
+}
+
+// Update replaces the URL, event types and active flag; the secret only
+// when a new one is given.
+func (r *webhookRepo) Update(ctx context.Context, id string, webhook *models.Webhook) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE Webhooks
+		SET URL = $1,
+		    Secret = COALESCE(NULLIF($2, ''), Secret),
+		    Event_Types = $3,
+		    Active = $4
+		WHERE Webhook_ID = $5
+	`, webhook.URL, webhook.Secret, pq.Array(webhook.EventTypes), webhook.Active, id)
+	if err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, id string) error {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM Webhooks WHERE Webhook_ID = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetDeliveries lists the latest deliveries of a webhook, newest first,
+// with their attempts; status narrows them down when not empty.
+func (r *webhookRepo) GetDeliveries(ctx context.Context, webhookID, status string, limit int) ([]*models.WebhookDelivery, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		SELECT d.Delivery_ID, d.Webhook_ID, d.Status, d.Attempts, d.Next_Attempt_At, d.Delivered_At, d.Created_At,
+		       e.Event_ID, e.Event_Type, e.Created_At, e.Payload
+		FROM Webhook_Deliveries d
+		JOIN Outbox_Events e ON e.Event_ID = d.Event_ID
+		WHERE d.Webhook_ID = $1 AND (NULLIF($2, '') IS NULL OR d.Status::TEXT = $2)
+		ORDER BY d.Delivery_ID DESC
+		LIMIT $3
+	`, webhookID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	byID := make(map[int64]*models.WebhookDelivery)
+	var ids []int64
+	for rows.Next() {
+		var (
+			d          models.WebhookDelivery
+			event      models.OutboxEvent
+			id, hookID int64
+			next       time.Time
+		)
+		err := rows.Scan(&id, &hookID, &d.Status, &d.Attempts, &next, &d.DeliveredAt, &d.CreatedAt,
+			&event.EventID, &event.Type, &event.CreatedAt, &event.Data)
+		if err != nil {
+			return nil, fmt.Errorf("scan delivery: %w", err)
+		}
+		d.DeliveryID = strconv.FormatInt(id, 10)
+		d.WebhookID = strconv.FormatInt(hookID, 10)
+		d.Event = &event
+		if d.Status == models.DeliveryPending {
+			d.NextAttemptAt = &next
+		}
+		d.Log = []*models.WebhookAttempt{}
+		deliveries = append(deliveries, &d)
+		byID[id] = &d
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return deliveries, nil
+	}
+
+	attempts, err := r.DB.QueryContext(ctx, `
+		SELECT Delivery_ID, Attempted_At, Status_Code, COALESCE(Error, ''), Duration_Ms
+		FROM Webhook_Attempts
+		WHERE Delivery_ID = ANY($1)
+		ORDER BY Attempt_ID
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query attempts: %w", err)
+	}
+	defer attempts.Close()
+
+	for attempts.Next() {
+		var (
+			a          models.WebhookAttempt
+			deliveryID int64
+		)
+		if err := attempts.Scan(&deliveryID, &a.AttemptedAt, &a.StatusCode, &a.Error, &a.DurationMs); err != nil {
+			return nil, fmt.Errorf("scan attempt: %w", err)
+		}
+		byID[deliveryID].Log = append(byID[deliveryID].Log, &a)
+	}
+	if err := attempts.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Retry puts a dead delivery back in line with a fresh set of attempts. It
+// returns sql.ErrNoRows when the webhook has no such dead delivery.
+func (r *webhookRepo) Retry(ctx context.Context, webhookID, deliveryID string) error {
+	res, err := r.DB.ExecContext(ctx, `
+		UPDATE Webhook_Deliveries
+		SET Status = 'pending', Attempts = 0, Next_Attempt_At = NOW()
+		WHERE Delivery_ID = $1 AND Webhook_ID = $2 AND Status = 'dead'
+	`, deliveryID, webhookID)
+	if err != nil {
+		return fmt.Errorf("retry delivery: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DispatchOutbox queues a delivery of up to limit outbox events for every
+// active webhook subscribed to them and marks the events dispatched, all in
+// one statement. It returns how many events it dispatched.
+func (r *webhookRepo) DispatchOutbox(ctx context.Context, limit int) (int, error) {
+	res, err := r.DB.ExecContext(ctx, `
+		WITH batch AS (
+			SELECT Event_ID, Event_Type
+			FROM Outbox_Events
+			WHERE Dispatched_At IS NULL
+			ORDER BY Event_ID
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), queued AS (
+			INSERT INTO Webhook_Deliveries (Webhook_ID, Event_ID)
+			SELECT w.Webhook_ID, b.Event_ID
+			FROM batch b
+			JOIN Webhooks w ON w.Active AND b.Event_Type = ANY(w.Event_Types)
+			ON CONFLICT (Webhook_ID, Event_ID) DO NOTHING
+		)
+		UPDATE Outbox_Events
+		SET Dispatched_At = NOW()
+		WHERE Event_ID IN (SELECT Event_ID FROM batch)
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("dispatch outbox: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// ClaimDue takes up to limit pending deliveries that are due and pushes
+// their next attempt back by lease, so that another dispatcher leaves them
+// alone while they are sent. A delivery whose sender dies is picked up again
+// once the lease runs out.
+func (r *webhookRepo) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	rows, err := r.DB.QueryContext(ctx, `
+		WITH claimed AS (
+			UPDATE Webhook_Deliveries
+			SET Next_Attempt_At = NOW() + make_interval(secs => $2)
+			WHERE Delivery_ID IN (
+				SELECT Delivery_ID
+				FROM Webhook_Deliveries
+				WHERE Status = 'pending' AND Next_Attempt_At <= NOW()
+				ORDER BY Next_Attempt_At
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING Delivery_ID, Webhook_ID, Event_ID, Attempts, Created_At
+		)
+		SELECT c.Delivery_ID, c.Webhook_ID, c.Attempts, c.Created_At, w.URL, w.Secret,
+		       e.Event_ID, e.Event_Type, e.Created_At, e.Payload
+		FROM claimed c
+		JOIN Webhooks w ON w.Webhook_ID = c.Webhook_ID
+		JOIN Outbox_Events e ON e.Event_ID = c.Event_ID
+		ORDER BY c.Delivery_ID
+	`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*models.WebhookDelivery
+	for rows.Next() {
+		var (
+			d          models.WebhookDelivery
+			event      models.OutboxEvent
+			id, hookID int64
+		)
+		err := rows.Scan(&id, &hookID, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret,
+			&event.EventID, &event.Type, &event.CreatedAt, &event.Data)
+		if err != nil {
+			return nil, fmt.Errorf("scan delivery: %w", err)
+		}
+		d.DeliveryID = strconv.FormatInt(id, 10)
+		d.WebhookID = strconv.FormatInt(hookID, 10)
+		d.Status = models.DeliveryPending
+		d.Event = &event
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt logs an attempt and moves the delivery to status; a pending
+// delivery is tried again after retryIn.
+func (r *webhookRepo) RecordAttempt(ctx context.Context, deliveryID string, attempt *models.WebhookAttempt, status string, retryIn time.Duration) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO Webhook_Attempts (Delivery_ID, Status_Code, Error, Duration_Ms)
+		VALUES ($1, $2, NULLIF($3, ''), $4)
+	`, deliveryID, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if err != nil {
+		return fmt.Errorf("insert attempt: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE Webhook_Deliveries
+		SET Attempts = Attempts + 1,
+		    Status = $2,
+		    Next_Attempt_At = NOW() + make_interval(secs => $3),
+		    Delivered_At = CASE WHEN $2 = 'delivered' THEN NOW() END
+		WHERE Delivery_ID = $1
+	`, deliveryID, status, retryIn.Seconds())
+	if err != nil {
+		return fmt.Errorf("update delivery: %w", err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"frappuccino/internal/models"
+	"frappuccino/internal/slog"
+)
+
+const (
+	// batchQueueSize is how many batch jobs may wait for the worker.
+	batchQueueSize = 32
+	// batchJobTimeout bounds the processing of one batch job.
+	batchJobTimeout = 5 * time.Minute
+	// batchJobRetention is how long a finished job can still be looked up.
+	batchJobRetention = time.Hour
+)
+
+// batchJobs holds the batches processed in the background. Jobs live in
+// memory: a restart loses the queued ones and the results.
+type batchJobs struct {
+	mu    sync.Mutex
+	jobs  map[string]*models.BatchJob
+	queue chan *models.BatchJob
+}
+
+func newBatchJobs() *batchJobs {
+	return &batchJobs{
+		jobs:  make(map[string]*models.BatchJob),
+		queue: make(chan *models.BatchJob, batchQueueSize),
+	}
+}
+
+// SubmitBatch queues a batch for the worker and returns its job.
+func (s *orderService) SubmitBatch(batch *models.PurchaseBatch) (*models.BatchJob, error) {
+	if err := validateBatch(batch); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	job := &models.BatchJob{
+		JobID:     hex.EncodeToString(id),
+		Status:    models.JobQueued,
+		Mode:      batch.Mode,
+		Total:     len(batch.Purchases),
+		Created:   time.Now(),
+		Purchases: batch.Purchases,
+	}
+
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	s.jobs.prune(job.Created)
+	select {
+	case s.jobs.queue <- job:
+	default:
+		return nil, models.NewError(models.ErrBusy, errors.New("too many batches waiting, try again later"))
+	}
+	s.jobs.jobs[job.JobID] = job
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// GetBatchJob reports the progress of a batch job, with its result once it
+// is done.
+func (s *orderService) GetBatchJob(id string) (*models.BatchJob, error) {
+	s.jobs.mu.Lock()
+	defer s.jobs.mu.Unlock()
+
+	job, ok := s.jobs.jobs[id]
+	if !ok {
+		return nil, models.NewError(models.ErrNotFound, errors.New("batch job not found"))
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// RunBatchWorker processes queued batch jobs one at a time until ctx is
+// done.
+func (s *orderService) RunBatchWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.jobs.queue:
+			s.runBatchJob(ctx, job)
+		}
+	}
+}
+
+func (s *orderService) runBatchJob(ctx context.Context, job *models.BatchJob) {
+	ctx, cancel := context.WithTimeout(ctx, batchJobTimeout)
+	defer cancel()
+
+	s.jobs.update(func() { job.Status = models.JobRunning })
+
+	result := s.processBatch(ctx, job.Purchases, job.Mode, func(processed int) {
+		s.jobs.update(func() { job.Processed = processed })
+	})
+
+	s.jobs.update(func() {
+		now := time.Now()
+		job.Finished = &now
+		job.Result = result
+		job.Status = models.JobDone
+		if err := ctx.Err(); err != nil {
+			job.Status = models.JobFailed
+			job.Error = err.Error()
+		}
+		job.Purchases = nil
+	})
+	slog.Info("Batch job %s finished: %d confirmed, %d declined", job.JobID, result.Report.Confirmed, result.Report.Declined)
+}
+
+func (j *batchJobs) update(fn func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn()
+}
+
+// prune forgets the jobs finished more than batchJobRetention ago. The
+// caller holds mu.
+func (j *batchJobs) prune(now time.Time) {
+	for id, job := range j.jobs {
+		if job.Finished != nil && now.Sub(*job.Finished) > batchJobRetention {
+			delete(j.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+
+	"github.com/lib/pq"
+)
+
+type CategoryService interface {
+	GetAllCategories(ctx context.Context) ([]*models.Category, error)
+	GetCategoryByID(ctx context.Context, id string) (*models.Category, error)
+	CreateCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, id string, category *models.Category) error
+	DeleteCategory(ctx context.Context, id string) error
+}
+
+type categoryService struct {
+	Repo *repo.Container
+}
+
+func NewCategoryService(r *repo.Container) CategoryService {
+	return &categoryService{
+		Repo: r,
+	}
+}
+
+func (s *categoryService) GetAllCategories(ctx context.Context) ([]*models.Category, error) {
+	categories, err := s.Repo.CategoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return categories, nil
+}
+
+func (s *categoryService) GetCategoryByID(ctx context.Context, id string) (*models.Category, error) {
+	category, err := s.Repo.CategoryRepo.GetByID(ctx, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("invalid category id"))
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrNotFound, errors.New("category not found"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return category, nil
+}
+
+func (s *categoryService) CreateCategory(ctx context.Context, category *models.Category) error {
+	if err := category.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	if err := s.checkParent(ctx, "", category.ParentID); err != nil {
+		return err
+	}
+
+	if err := s.Repo.CategoryRepo.Create(ctx, category); err != nil {
+		return categoryWriteError(err)
+	}
+	return nil
+}
+
+func (s *categoryService) UpdateCategory(ctx context.Context, id string, category *models.Category) error {
+	if _, err := s.GetCategoryByID(ctx, id); err != nil {
+		return err
+	}
+
+	category.CategoryID = id
+	if err := category.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	if err := s.checkParent(ctx, id, category.ParentID); err != nil {
+		return err
+	}
+
+	if err := s.Repo.CategoryRepo.Update(ctx, id, category); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("category not found"))
+		}
+		return categoryWriteError(err)
+	}
+	return nil
+}
+
+func (s *categoryService) DeleteCategory(ctx context.Context, id string) error {
+	if _, err := s.GetCategoryByID(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.Repo.CategoryRepo.Delete(ctx, id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+			return models.NewError(models.ErrInvalidInput, errors.New("category still has menu items"))
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("category not found"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+// checkParent makes sure the parent exists and that hanging the category
+// under it does not make the category its own ancestor.
+func (s *categoryService) checkParent(ctx context.Context, id string, parentID *string) error {
+	if parentID == nil {
+		return nil
+	}
+
+	categories, err := s.Repo.CategoryRepo.GetAll(ctx)
+	if err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	parents := make(map[string]*string, len(categories))
+	for _, c := range categories {
+		parents[c.CategoryID] = c.ParentID
+	}
+
+	if _, ok := parents[*parentID]; !ok {
+		return models.NewError(models.ErrNotFound, errors.New("parent category not found"))
+	}
+
+	seen := map[string]bool{}
+	for cur := parentID; cur != nil && !seen[*cur]; cur = parents[*cur] {
+		if *cur == id {
+			return models.NewError(models.ErrInvalidInput, errors.New("category cannot be nested under its own subcategory"))
+		}
+		seen[*cur] = true
+	}
+	return nil
+}
+
+func categoryWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+		return models.NewError(models.ErrElemExist, errors.New("category already exists"))
+	}
+	return models.NewError(models.ErrInternal, err)
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"math"
+	"sort"
+	"time"
 
 	"frappuccino/internal/models"
 
@@ -12,9 +15,15 @@ import (
 )
 
 func (s *orderService) validateOrderInput(ctx context.Context, order *models.Purchase) error {
+	if order.Channel == "" {
+		order.Channel = models.ChannelTakeaway
+	}
 	if err := order.Validate(); err != nil {
 		return models.NewError(models.ErrInvalidInput, err)
 	}
+	if order.MergeLines {
+		order.Positions = models.MergeLines(order.Positions)
+	}
 
 	// Check if customer exists
 	if _, err := s.Repo.CustomerRepo.GetCustomerByID(ctx, order.CustomerID); err != nil {
@@ -27,40 +36,279 @@ func (s *orderService) validateOrderInput(ctx context.Context, order *models.Pur
 	return nil
 }
 
+// calculateOrderPrices prices the order against the live menu, adds what its
+// channel charges and records the menu version it used.
 func (s *orderService) calculateOrderPrices(ctx context.Context, order *models.Purchase) error {
-	ids := order.GetItemIDs()
-	menus, err := s.Repo.MenuRepo.FetchProductsByIDs(ctx, ids)
+	versionID, err := s.priceLines(ctx, order.Positions)
 	if err != nil {
 		return err
 	}
+	order.MenuVersionID = &versionID
+	s.setTotals(order)
+	return nil
+}
+
+// priceLines prices lines against the live menu and returns the menu version
+// they were priced with. Lines priced while a new version was being published
+// are refused rather than attributed to the wrong version.
+func (s *orderService) priceLines(ctx context.Context, lines []*models.LineItem) (string, error) {
+	versionID, err := s.Repo.MenuVersionRepo.GetLiveID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ids := (&models.Purchase{Positions: lines}).GetItemIDs()
+	menus, err := s.Repo.MenuRepo.FetchProductsByIDs(ctx, ids)
+	if err != nil {
+		return "", err
+	}
 
 	if len(menus) != len(ids) {
-		return models.NewError(models.ErrInvalidInput, errors.New("product not found"))
+		return "", models.NewError(models.ErrInvalidInput, errors.New("product not found"))
 	}
 
-	menuMap := make(map[string]float64, len(ids))
+	now := time.Now()
+	menuMap := make(map[string]*models.Product, len(ids))
 	for _, menu := range menus {
-		menuMap[menu.ProductID] = menu.UnitPrice
+		if menu.ArchivedAt != nil {
+			return "", models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is no longer on the menu", menu.Title))
+		}
+		if menu.IsSoldOut(now) {
+			return "", models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is sold out", menu.Title))
+		}
+		menuMap[menu.ProductID] = menu
+	}
+
+	windows, err := s.Repo.PricingRepo.GetWindows(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, item := range lines {
+		menu, ok := menuMap[item.ItemID]
+		if !ok {
+			continue
+		}
+
+		item.UnitPrice = effectivePrice(menu, windows, now)
+
+		if menu.Bundle {
+			if err := s.fillBundle(ctx, menu, item, item.UnitPrice, now); err != nil {
+				return "", err
+			}
+		} else if len(item.Selections) > 0 {
+			return "", models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is not a bundle", menu.Title))
+		}
 	}
 
-	if order.Amount == nil {
-		total := 0.0
-		order.Amount = &total
+	liveID, err := s.Repo.MenuVersionRepo.GetLiveID(ctx)
+	if err != nil {
+		return "", err
+	}
+	if liveID != versionID {
+		return "", models.NewError(models.ErrElemExist, errors.New("the menu changed while the order was priced, please try again"))
 	}
+	return versionID, nil
+}
 
+// setTotals adds up the priced lines of an order and what its channel
+// charges.
+func (s *orderService) setTotals(order *models.Purchase) {
+	subtotal := 0.0
 	for _, item := range order.Positions {
-		if price, ok := menuMap[item.ItemID]; ok {
-			item.UnitPrice = price
-			*order.Amount += float64(item.Count) * price
+		subtotal += float64(item.Count) * item.UnitPrice
+	}
+
+	subtotal = roundFloat(subtotal, 2)
+	tax, fee := s.channels.Charges(order.Channel, subtotal)
+	amount := roundFloat(subtotal+tax+fee, 2)
+	order.Subtotal, order.Tax, order.DeliveryFee, order.Amount = &subtotal, &tax, &fee, &amount
+}
+
+// fillBundle checks that the selections of a bundle line fill every slot with
+// an item the slot offers, and spreads the bundle price over them.
+func (s *orderService) fillBundle(ctx context.Context, bundle *models.Product, line *models.LineItem, price float64, now time.Time) error {
+	bySlot := make(map[string]*models.BundleSelection, len(line.Selections))
+	for _, sel := range line.Selections {
+		if _, dup := bySlot[sel.SlotID]; dup {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("slot %s selected twice", sel.SlotID))
+		}
+		bySlot[sel.SlotID] = sel
+	}
+
+	var ids []string
+	seen := make(map[string]bool)
+	for _, slot := range bundle.Slots {
+		sel, ok := bySlot[slot.SlotID]
+		if !ok {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s: choose an item for %s", bundle.Title, slot.Name))
+		}
+		if !seen[sel.ItemID] {
+			seen[sel.ItemID] = true
+			ids = append(ids, sel.ItemID)
+		}
+	}
+	if len(bySlot) != len(bundle.Slots) {
+		return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s: unknown slot selected", bundle.Title))
+	}
+
+	chosen, err := s.Repo.MenuRepo.FetchProductsByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	if len(chosen) != len(ids) {
+		return models.NewError(models.ErrInvalidInput, errors.New("product not found"))
+	}
+	chosenMap := make(map[string]*models.Product, len(chosen))
+	for _, p := range chosen {
+		chosenMap[p.ProductID] = p
+	}
+
+	selections := make([]*models.BundleSelection, 0, len(bundle.Slots))
+	prices := make([]float64, 0, len(bundle.Slots))
+	for _, slot := range bundle.Slots {
+		sel := bySlot[slot.SlotID]
+		item := chosenMap[sel.ItemID]
+		if item.Bundle || !slot.Offers(item) {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is not offered for %s", item.Title, slot.Name))
+		}
+		if item.ArchivedAt != nil {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is no longer on the menu", item.Title))
+		}
+		if item.IsSoldOut(now) {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is sold out", item.Title))
+		}
+		sel.Count = slot.Quantity
+		selections = append(selections, sel)
+		prices = append(prices, item.UnitPrice)
+	}
+
+	if err := allocateBundlePrice(price, selections, prices); err != nil {
+		return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s: %w", bundle.Title, err))
+	}
+	line.Selections = selections
+	return nil
+}
+
+// allocateBundlePrice splits the bundle price across the selections in
+// proportion to the list price of the selected items, so each component is
+// credited with its share of the revenue. Shares are worked out in whole
+// cents per unit and the cents lost to rounding are spread over the
+// selections, so that unit price times count adds up to the bundle price
+// exactly. It fails when no whole-cent unit prices add up to the price, as
+// with 9.99 over two slots of two.
+func allocateBundlePrice(price float64, selections []*models.BundleSelection, listPrices []float64) error {
+	var listTotal float64
+	for i, sel := range selections {
+		listTotal += listPrices[i] * float64(sel.Count)
+	}
+
+	cents := int64(math.Round(price * 100))
+	units := make([]int64, len(selections))
+	counts := make([]int64, len(selections))
+	rest := cents
+	for i, sel := range selections {
+		share := float64(cents) / float64(len(selections))
+		if listTotal > 0 {
+			share = float64(cents) * listPrices[i] * float64(sel.Count) / listTotal
+		}
+		counts[i] = int64(sel.Count)
+		units[i] = int64(math.Round(share / float64(sel.Count)))
+		rest -= units[i] * counts[i]
+	}
+
+	adjust, ok := spreadCents(units, counts, rest)
+	if !ok {
+		return fmt.Errorf("%.2f cannot be split into whole cents across the slots", price)
+	}
+	for i, sel := range selections {
+		sel.UnitPrice = float64(units[i]+adjust[i]) / 100
+	}
+	return nil
+}
+
+// spreadCents finds how many cents to add to (or take from) the unit price
+// of each selection so that the counts times the changes add up to rest. A
+// selection of one unit takes the whole rest; otherwise the smallest total
+// change is picked, leaving no unit price below zero.
+func spreadCents(units, counts []int64, rest int64) ([]int64, bool) {
+	adjust := make([]int64, len(units))
+	for i := len(counts) - 1; i >= 0; i-- {
+		if counts[i] == 1 && units[i]+rest >= 0 {
+			adjust[i] = rest
+			return adjust, true
 		}
 	}
 
-	*order.Amount = math.Round(*order.Amount)
+	var bound int64
+	for _, c := range counts {
+		bound += c
+	}
+
+	type plan struct {
+		cost   int64
+		adjust []int64
+	}
+	plans := map[int64]plan{0: {}}
+	for i, c := range counts {
+		sums := make([]int64, 0, len(plans))
+		for sum := range plans {
+			sums = append(sums, sum)
+		}
+		sort.Slice(sums, func(a, b int) bool { return sums[a] < sums[b] })
+
+		next := make(map[int64]plan)
+		for _, sum := range sums {
+			p := plans[sum]
+			for k := -bound; k <= bound; k++ {
+				if units[i]+k < 0 {
+					continue
+				}
+				cost := p.cost + k*k
+				if q, ok := next[sum+k*c]; ok && q.cost <= cost {
+					continue
+				}
+				next[sum+k*c] = plan{cost: cost, adjust: append(append([]int64{}, p.adjust...), k)}
+			}
+		}
+		plans = next
+	}
+
+	p, ok := plans[rest]
+	if !ok {
+		return nil, false
+	}
+	return p.adjust, true
+}
+
+// checkPickupSlot makes sure an order placed ahead fits in the slot of its
+// pickup time; orderID names the order being changed, if any. Two orders
+// racing for the last room in a slot may both get it.
+func (s *orderService) checkPickupSlot(ctx context.Context, order *models.Purchase, orderID string) error {
+	if order.PickupAt == nil {
+		return nil
+	}
+
+	slots, err := pickupSlots(ctx, s.Repo, *order.PickupAt, orderID)
+	if err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	slot := models.SlotFor(slots, *order.PickupAt)
+	if slot == nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("orders cannot be picked up at this time"))
+	}
+	if items := order.Items(); items > slot.Available {
+		return models.NewError(models.ErrInvalidInput,
+			fmt.Errorf("the %s pickup slot has room for %d more items, the order has %d", slot.Start.Format("15:04"), slot.Available, items))
+	}
 	return nil
 }
 
-func (s *orderService) checkInventoryAvailability(ctx context.Context, order *models.Purchase) error {
-	hasIngredients, err := s.Repo.OrderRepo.CheckInventoryForOrder(ctx, order)
+// checkInventoryAvailability makes sure the free stock covers the order;
+// orderID names the order being changed, if any, whose own reservations
+// count as free.
+func (s *orderService) checkInventoryAvailability(ctx context.Context, order *models.Purchase, orderID string) error {
+	hasIngredients, err := s.Repo.OrderRepo.CheckInventoryForOrder(ctx, order, orderID)
 	if err != nil {
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) && pqErr.Code == "22P02" {
@@ -80,6 +328,13 @@ func (s *orderService) checkInventoryAvailability(ctx context.Context, order *mo
 	return nil
 }
 
+// wholeUnits is how many whole times required fits into available. The
+// quotient is nudged up before flooring so that float error, such as 0.6/0.2
+// coming out just under 3, does not lose the last portion.
+func wholeUnits(available, required float64) float64 {
+	return math.Floor(available/required + 1e-9)
+}
+
 func roundFloat(f float64, precision int) float64 {
 	factor := math.Pow(10, float64(precision))
 	return math.Round(f*factor) / factor
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"frappuccino/internal/models"
+)
+
+func TestAllocateBundlePrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      float64
+		counts     []int
+		listPrices []float64
+		want       []float64
+		wantErr    bool
+	}{
+		{
+			name:       "odd cent goes to a single unit",
+			price:      10,
+			counts:     []int{1, 2},
+			listPrices: []float64{1, 1},
+			want:       []float64{3.34, 3.33},
+		},
+		{
+			name:       "shares rounding to the cent add up",
+			price:      10,
+			counts:     []int{1, 1, 2},
+			listPrices: []float64{3, 3, 1.5},
+			want:       []float64{3.33, 3.33, 1.67},
+		},
+		{
+			name:       "rest spread over counts without a single unit",
+			price:      10.01,
+			counts:     []int{2, 3},
+			listPrices: []float64{1, 1},
+			want:       []float64{1.99, 2.01},
+		},
+		{
+			name:       "no list prices splits evenly",
+			price:      5,
+			counts:     []int{1, 1},
+			listPrices: []float64{0, 0},
+			want:       []float64{2.5, 2.5},
+		},
+		{
+			name:       "price not divisible into whole cents per unit",
+			price:      9.99,
+			counts:     []int{2, 2},
+			listPrices: []float64{1, 1},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selections := make([]*models.BundleSelection, len(tt.counts))
+			for i, c := range tt.counts {
+				selections[i] = &models.BundleSelection{Count: c}
+			}
+
+			err := allocateBundlePrice(tt.price, selections, tt.listPrices)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("allocateBundlePrice() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("allocateBundlePrice() error = %v", err)
+			}
+
+			var total int64
+			for i, sel := range selections {
+				if sel.UnitPrice != tt.want[i] {
+					t.Errorf("selection %d unit price = %v, want %v", i, sel.UnitPrice, tt.want[i])
+				}
+				total += int64(math.Round(sel.UnitPrice*100)) * int64(sel.Count)
+			}
+			if want := int64(math.Round(tt.price * 100)); total != want {
+				t.Errorf("allocated %d cents, want %d", total, want)
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	repo "frappuccino/internal/repo"
 
@@ -13,12 +16,18 @@ import (
 )
 
 type InventoryService interface {
-	GetAllInventory(ctx context.Context) ([]*models.InventoryItem, error)
+	GetAllInventory(ctx context.Context, includeArchived bool) ([]*models.InventoryItem, error)
 	CreateInventory(ctx context.Context, item *models.InventoryItem) error
 	GetInventoryByID(ctx context.Context, id string) (*models.InventoryItem, error)
 	UpdateInventoryByID(ctx context.Context, id string, item *models.InventoryItem) error
-	DeleteInventoryByID(ctx context.Context, id string) error
+	ArchiveInventory(ctx context.Context, id string) error
+	RestoreInventory(ctx context.Context, id string) error
 	GetInventoryList(ctx context.Context, sortBy string, page, pageSize int) ([]models.InventoryItem, int, bool, int, error)
+	ReceiveStock(ctx context.Context, id string, receipt *models.StockReceipt) error
+	GetCostHistory(ctx context.Context, id string) ([]*models.InventoryTransaction, error)
+	RecordWaste(ctx context.Context, id string, waste *models.WasteRecord) error
+	Produce(ctx context.Context, id string, req *models.ProductionRequest) (*models.PrepBatch, error)
+	GetPrepBatches(ctx context.Context, id string) ([]*models.PrepBatch, error)
 }
 
 type inventoryService struct {
@@ -29,14 +38,18 @@ func NewInventoryService(r *repo.Container) *inventoryService {
 	return &inventoryService{repo: r}
 }
 
-func (s *inventoryService) GetAllInventory(ctx context.Context) ([]*models.InventoryItem, error) {
-	items, err := s.repo.InventoryRepo.GetAllInventory(ctx)
+func (s *inventoryService) GetAllInventory(ctx context.Context, includeArchived bool) ([]*models.InventoryItem, error) {
+	items, err := s.repo.InventoryRepo.GetAllInventory(ctx, includeArchived)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return items, nil
 		}
 		return nil, err
 	}
+
+	if err := s.applyMaxProducible(ctx, items); err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
 	return items, nil
 }
 
@@ -45,6 +58,12 @@ func (s *inventoryService) CreateInventory(ctx context.Context, item *models.Inv
 		return models.NewError(models.ErrInvalidInput, errors.New("invalid input"))
 	}
 
+	if item.Prepared {
+		if err := s.priceRecipe(ctx, "", item); err != nil {
+			return err
+		}
+	}
+
 	if err := s.repo.InventoryRepo.CreateInventory(ctx, item); err != nil {
 		return err
 	}
@@ -59,6 +78,10 @@ func (s *inventoryService) GetInventoryByID(ctx context.Context, id string) (*mo
 		}
 		return nil, err
 	}
+
+	if err := s.applyMaxProducible(ctx, []*models.InventoryItem{item}); err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
 	return item, nil
 }
 
@@ -71,6 +94,13 @@ func (s *inventoryService) UpdateInventoryByID(ctx context.Context, id string, i
 		return err
 	}
 
+	if item.Prepared {
+		item.IngredientID = id
+		if err := s.priceRecipe(ctx, id, item); err != nil {
+			return err
+		}
+	}
+
 	if err := s.repo.InventoryRepo.UpdateInventoryByID(ctx, id, item); err != nil {
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
@@ -81,12 +111,43 @@ func (s *inventoryService) UpdateInventoryByID(ctx context.Context, id string, i
 	return nil
 }
 
-func (s *inventoryService) DeleteInventoryByID(ctx context.Context, id string) error {
-	if _, err := s.GetInventoryByID(ctx, id); err != nil {
+// ArchiveInventory takes the item out of use. Items still needed by the menu
+// or by a prepared item have to be taken off there first.
+func (s *inventoryService) ArchiveInventory(ctx context.Context, id string) error {
+	item, err := s.GetInventoryByID(ctx, id)
+	if err != nil {
 		return err
 	}
+	if item.ArchivedAt != nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("inventory item is already archived"))
+	}
 
-	if err := s.repo.InventoryRepo.DeleteInventoryByID(ctx, id); err != nil {
+	users, err := s.repo.InventoryRepo.GetActiveUsers(ctx, id)
+	if err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	if len(users) > 0 {
+		return models.NewError(models.ErrInvalidInput,
+			fmt.Errorf("inventory item is still used by %s", strings.Join(users, ", ")))
+	}
+
+	now := time.Now()
+	if err := s.repo.InventoryRepo.SetArchived(ctx, id, &now); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *inventoryService) RestoreInventory(ctx context.Context, id string) error {
+	item, err := s.GetInventoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if item.ArchivedAt == nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("inventory item is not archived"))
+	}
+
+	if err := s.repo.InventoryRepo.SetArchived(ctx, id, nil); err != nil {
 		return models.NewError(models.ErrInternal, err)
 	}
 	return nil
@@ -113,3 +174,157 @@ func (s *inventoryService) GetInventoryList(ctx context.Context, sortBy string,
 
 	return items, currentPage, hasNext, totalPages, nil
 }
+
+func (s *inventoryService) ReceiveStock(ctx context.Context, id string, receipt *models.StockReceipt) error {
+	if err := receipt.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	if err := s.repo.InventoryRepo.ReceiveStock(ctx, id, receipt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("inventory item not found"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *inventoryService) GetCostHistory(ctx context.Context, id string) ([]*models.InventoryTransaction, error) {
+	if _, err := s.GetInventoryByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	history, err := s.repo.InventoryRepo.GetCostHistory(ctx, id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return history, nil
+}
+
+func (s *inventoryService) RecordWaste(ctx context.Context, id string, waste *models.WasteRecord) error {
+	if err := waste.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	item, err := s.GetInventoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if waste.Quantity > item.Stock {
+		return models.NewError(models.ErrInvalidInput, errors.New("wasted quantity exceeds stock"))
+	}
+
+	if err := s.repo.InventoryRepo.RecordWaste(ctx, id, waste); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *inventoryService) Produce(ctx context.Context, id string, req *models.ProductionRequest) (*models.PrepBatch, error) {
+	if err := req.Validate(); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	item, err := s.GetInventoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !item.Prepared {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("inventory item is not a prepared item"))
+	}
+
+	graph, err := s.repo.InventoryRepo.GetRecipeGraph(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	node, ok := graph[id]
+	if !ok {
+		return nil, models.NewError(models.ErrNotFound, errors.New("inventory item not found"))
+	}
+
+	batch, err := s.repo.InventoryRepo.ProducePrep(ctx, item, req.Batches, roundFloat(recipeGraph(graph).batchCost(node), 2))
+	if err != nil {
+		if errors.Is(err, models.ErrInventoryNotAvailable) {
+			return nil, models.NewError(models.ErrInvalidInput, err)
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return batch, nil
+}
+
+func (s *inventoryService) GetPrepBatches(ctx context.Context, id string) ([]*models.PrepBatch, error) {
+	if _, err := s.GetInventoryByID(ctx, id); err != nil {
+		return nil, err
+	}
+
+	batches, err := s.repo.InventoryRepo.GetPrepBatches(ctx, id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return batches, nil
+}
+
+// priceRecipe checks the recipe of a prepared item against the current graph
+// and sets its cost to the cost of the recipe. id is empty for a new item.
+func (s *inventoryService) priceRecipe(ctx context.Context, id string, item *models.InventoryItem) error {
+	graph, err := s.repo.InventoryRepo.GetRecipeGraph(ctx)
+	if err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+
+	for _, line := range item.Recipe {
+		node, ok := graph[line.ComponentID]
+		if !ok {
+			return models.NewError(models.ErrNotFound, fmt.Errorf("inventory item %s not found", line.ComponentID))
+		}
+		if node.Archived {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is archived", node.Title))
+		}
+	}
+
+	graph[id] = &models.RecipeNode{
+		ItemRef:  id,
+		Title:    item.Title,
+		Prepared: true,
+		Yield:    *item.Yield,
+		Recipe:   item.Recipe,
+	}
+
+	g := recipeGraph(graph)
+	if err := g.checkCycle(id); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	cost, err := g.unitCost(id)
+	if err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	item.UnitCost = roundFloat(cost, 2)
+	return nil
+}
+
+func (s *inventoryService) applyMaxProducible(ctx context.Context, items []*models.InventoryItem) error {
+	var prepared []*models.InventoryItem
+	for _, item := range items {
+		if item.Prepared {
+			prepared = append(prepared, item)
+		}
+	}
+	if len(prepared) == 0 {
+		return nil
+	}
+
+	graph, err := s.repo.InventoryRepo.GetRecipeGraph(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range prepared {
+		if node, ok := graph[item.IngredientID]; ok {
+			max := roundFloat(recipeGraph(graph).maxProducible(node), 4)
+			item.MaxProducible = &max
+		}
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+	"frappuccino/internal/slog"
+)
+
+type KitchenService interface {
+	GetQueue(ctx context.Context) ([]*models.KitchenTicket, error)
+	SetOrderStatus(ctx context.Context, orderID string, update *models.KitchenUpdate) (*models.KitchenTicket, error)
+	SetLineStatus(ctx context.Context, orderID, lineID string, update *models.KitchenUpdate) (*models.KitchenTicket, error)
+	Subscribe() (<-chan *models.KitchenEvent, func())
+	Publish(ctx context.Context, orderID string)
+	RunReleases(ctx context.Context, interval time.Duration)
+}
+
+// kitchenSubscriberBuffer is how many events a display may fall behind
+// before it is dropped.
+const kitchenSubscriberBuffer = 64
+
+type kitchenService struct {
+	Repo   *repo.Container
+	events *OrderEventBus
+	// lead is how long before its pickup time an order placed ahead joins
+	// the queue.
+	lead time.Duration
+
+	mu          sync.Mutex
+	subscribers map[chan *models.KitchenEvent]struct{}
+}
+
+func NewKitchenService(r *repo.Container, events *OrderEventBus, lead time.Duration) KitchenService {
+	return &kitchenService{
+		Repo:        r,
+		events:      events,
+		lead:        lead,
+		subscribers: make(map[chan *models.KitchenEvent]struct{}),
+	}
+}
+
+func (s *kitchenService) GetQueue(ctx context.Context) ([]*models.KitchenTicket, error) {
+	tickets, err := s.Repo.KitchenRepo.GetQueue(ctx, s.lead)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return tickets, nil
+}
+
+// SetOrderStatus moves every line of an order to the given status.
+func (s *kitchenService) SetOrderStatus(ctx context.Context, orderID string, update *models.KitchenUpdate) (*models.KitchenTicket, error) {
+	if err := update.Validate(); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+	if _, err := s.getTicket(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.setStatus(ctx, orderID, "", update.Status)
+}
+
+func (s *kitchenService) SetLineStatus(ctx context.Context, orderID, lineID string, update *models.KitchenUpdate) (*models.KitchenTicket, error) {
+	if err := update.Validate(); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+	ticket, err := s.getTicket(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	for _, line := range ticket.Lines {
+		found = found || line.LineID == lineID
+	}
+	if !found {
+		return nil, models.NewError(models.ErrNotFound, fmt.Errorf("order %s has no line %s", orderID, lineID))
+	}
+	return s.setStatus(ctx, orderID, lineID, update.Status)
+}
+
+func (s *kitchenService) setStatus(ctx context.Context, orderID, lineID, status string) (*models.KitchenTicket, error) {
+	before, after, err := s.Repo.KitchenRepo.SetStatus(ctx, orderID, lineID, status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrNotFound, errors.New("order is not in the kitchen queue"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	ticket, err := s.getTicket(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(&models.KitchenEvent{Type: models.KitchenEventTicket, OrderID: orderID, Ticket: ticket})
+
+	if after != before {
+		s.events.Publish(&models.OrderEvent{
+			Type:       models.OrderStatusChanged,
+			OrderID:    orderID,
+			CustomerID: ticket.CustomerID,
+			Status:     after,
+		})
+	}
+	return ticket, nil
+}
+
+func (s *kitchenService) getTicket(ctx context.Context, orderID string) (*models.KitchenTicket, error) {
+	ticket, err := s.Repo.KitchenRepo.GetTicket(ctx, orderID, s.lead)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrNotFound, errors.New("order is not in the kitchen queue"))
+		}
+		if errors.Is(err, strconv.ErrSyntax) {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("invalid order id"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return ticket, nil
+}
+
+// Subscribe registers a kitchen display. Events arrive on the returned
+// channel until the returned cancel function is called, or until the display
+// falls too far behind and the channel is closed.
+func (s *kitchenService) Subscribe() (<-chan *models.KitchenEvent, func()) {
+	ch := make(chan *models.KitchenEvent, kitchenSubscriberBuffer)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.drop(ch)
+	}
+}
+
+// Publish tells the displays that an order changed outside the kitchen:
+// it was placed, edited, closed, canceled or deleted.
+func (s *kitchenService) Publish(ctx context.Context, orderID string) {
+	if !s.hasSubscribers() {
+		return
+	}
+
+	event := &models.KitchenEvent{Type: models.KitchenEventTicket, OrderID: orderID}
+	ticket, err := s.Repo.KitchenRepo.GetTicket(ctx, orderID, s.lead)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		event.Type = models.KitchenEventRemoved
+	case err != nil:
+		slog.Error("Failed to load kitchen ticket %s: %v", orderID, err)
+		return
+	default:
+		event.Ticket = ticket
+	}
+	s.broadcast(event)
+}
+
+// RunReleases puts orders placed ahead on the displays as they join the
+// queue, checking every interval until ctx is done. Displays that connect
+// later find them in the queue they start from.
+func (s *kitchenService) RunReleases(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !s.hasSubscribers() {
+			continue
+		}
+		// the window overlaps the previous run so a late tick misses
+		// nothing; a ticket sent twice just replaces itself on the display
+		released, err := s.Repo.KitchenRepo.GetReleased(ctx, s.lead, 2*interval)
+		if err != nil {
+			slog.Error("Failed to load released pre-orders: %v", err)
+			continue
+		}
+		for _, orderID := range released {
+			s.Publish(ctx, orderID)
+		}
+	}
+}
+
+func (s *kitchenService) hasSubscribers() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers) > 0
+}
+
+// broadcast hands the event to every display without waiting on any. A
+// display too far behind is dropped rather than left with a gap; it
+// reconnects and starts over from a fresh queue.
+func (s *kitchenService) broadcast(event *models.KitchenEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			s.drop(ch)
+		}
+	}
+}
+
+// drop unregisters a display and closes its channel; s.mu must be held.
+func (s *kitchenService) drop(ch chan *models.KitchenEvent) {
+	if _, ok := s.subscribers[ch]; ok {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"frappuccino/internal/models"
 	repo "frappuccino/internal/repo"
@@ -12,11 +15,16 @@ import (
 )
 
 type MenuService interface {
-	GetAllMenus(ctx context.Context) (listMenu []*models.Product, err error)
+	GetAllMenus(ctx context.Context, q *models.MenuQuery) (listMenu []*models.Product, total int, err error)
+	GetMenuByCategory(ctx context.Context, q *models.MenuQuery) (sections []*models.MenuSection, err error)
 	CreateMenu(ctx context.Context, item *models.Product) (err error)
-	GetMenuByID(ctx context.Context, id string) (item *models.Product, err error)
+	GetMenuByID(ctx context.Context, id string, q *models.MenuQuery) (item *models.Product, err error)
 	UpdateMenu(ctx context.Context, id string, item *models.Product) (err error)
-	DeleteMenu(ctx context.Context, id string) (err error)
+	ArchiveMenu(ctx context.Context, id string) (err error)
+	RestoreMenu(ctx context.Context, id string) (err error)
+	MarkSoldOut(ctx context.Context, id string, until *time.Time) (err error)
+	ClearSoldOut(ctx context.Context, id string) (err error)
+	GetPriceHistory(ctx context.Context, id string) (history []*models.PriceHistory, err error)
 }
 
 type menuService struct {
@@ -29,26 +37,92 @@ func NewMenuService(r *repo.Container) MenuService {
 	}
 }
 
-func (m *menuService) GetAllMenus(ctx context.Context) (listMenu []*models.Product, err error) {
-	listMenu, err = m.repo.MenuRepo.GetAllProducts(ctx)
+// GetAllMenus lists the menu items matching the query together with the
+// number of matches before paging. Availability and allergens are computed,
+// so when they are filtered on the page is cut after the filtering.
+func (m *menuService) GetAllMenus(ctx context.Context, q *models.MenuQuery) (listMenu []*models.Product, total int, err error) {
+	if q == nil {
+		q = &models.MenuQuery{}
+	}
+	if err = q.Validate(); err != nil {
+		return nil, 0, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	filter := q.MenuFilter
+	if q.FiltersComputed() {
+		filter.Page, filter.PageSize = 0, 0
+	}
+
+	listMenu, total, err = m.repo.MenuRepo.FindProducts(ctx, &filter)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return listMenu, nil
+		return nil, 0, models.NewError(models.ErrInternal, err)
+	}
+
+	if err = m.describe(ctx, listMenu, q.IncludeCost, q.Modifiers, time.Now()); err != nil {
+		return nil, 0, models.NewError(models.ErrInternal, err)
+	}
+
+	filtered := listMenu[:0]
+	for _, item := range listMenu {
+		if q.AvailableOnly && !item.Available {
+			continue
 		}
-		return nil, err
+		if containsAny(item.Allergens, q.ExcludeAllergens) {
+			continue
+		}
+		if q.IncludeCost {
+			applyRecipeCost(item)
+		}
+		filtered = append(filtered, item)
+	}
+	listMenu = filtered
+
+	if q.FiltersComputed() {
+		total = len(listMenu)
+		listMenu = paginate(listMenu, &q.MenuFilter)
 	}
 	return
 }
 
+// GetMenuByCategory returns the menu as a tree of categories in display
+// order. Inactive categories are left out together with their subcategories,
+// and so are categories with nothing to show.
+func (m *menuService) GetMenuByCategory(ctx context.Context, q *models.MenuQuery) (sections []*models.MenuSection, err error) {
+	if q.GroupBy != "category" {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("group_by must be 'category'"))
+	}
+
+	// sections are not paged: a page could split a category
+	whole := *q
+	whole.Page, whole.PageSize = 0, 0
+	listMenu, _, err := m.GetAllMenus(ctx, &whole)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := m.repo.CategoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	return groupByCategory(categories, listMenu), nil
+}
+
 func (m *menuService) CreateMenu(ctx context.Context, item *models.Product) (err error) {
 	if err = item.CheckRequiredFields(); err != nil {
 		return models.NewError(models.ErrInvalidInput, err)
 	}
 
-	for _, ingredient := range item.Components {
-		if _, err := m.repo.InventoryRepo.GetInventoryByID(ctx, ingredient.ComponentID); err != nil {
-			return models.NewError(models.ErrNotFound, err)
-		}
+	if err = m.resolveCategory(ctx, item); err != nil {
+		return err
+	}
+
+	if err = m.checkComponents(ctx, item); err != nil {
+		return err
+	}
+
+	if err = m.checkBundleOptions(ctx, item); err != nil {
+		return err
 	}
 
 	err = m.repo.MenuRepo.CreateProduct(ctx, item)
@@ -62,7 +136,7 @@ func (m *menuService) CreateMenu(ctx context.Context, item *models.Product) (err
 	return
 }
 
-func (m *menuService) GetMenuByID(ctx context.Context, id string) (item *models.Product, err error) {
+func (m *menuService) GetMenuByID(ctx context.Context, id string, q *models.MenuQuery) (item *models.Product, err error) {
 	item, err = m.repo.MenuRepo.GetProductByID(ctx, id)
 	if err != nil {
 		var pqErr *pq.Error
@@ -81,6 +155,22 @@ func (m *menuService) GetMenuByID(ctx context.Context, id string) (item *models.
 		}
 		return nil, err
 	}
+
+	if q == nil {
+		q = &models.MenuQuery{}
+	}
+	for _, name := range q.Modifiers {
+		if item.Modifier(name) == nil {
+			return nil, models.NewError(models.ErrInvalidInput, fmt.Errorf("%s has no modifier %s", item.Title, name))
+		}
+	}
+
+	if err = m.describe(ctx, []*models.Product{item}, q.IncludeCost, q.Modifiers, time.Now()); err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	if q.IncludeCost {
+		applyRecipeCost(item)
+	}
 	return
 }
 
@@ -89,15 +179,25 @@ func (m *menuService) UpdateMenu(ctx context.Context, id string, item *models.Pr
 		return models.NewError(models.ErrInvalidInput, err)
 	}
 
-	oldItem, err := m.GetMenuByID(ctx, id)
+	oldItem, err := m.GetMenuByID(ctx, id, nil)
 	if err != nil {
 		return err
 	}
 
-	for _, ingredient := range item.Components {
-		if _, err := m.repo.MenuRepo.GetProductByID(ctx, ingredient.ComponentID); err != nil {
-			return models.NewError(models.ErrNotFound, err)
-		}
+	if err = m.resolveCategory(ctx, item); err != nil {
+		return err
+	}
+
+	if err = m.checkComponents(ctx, item); err != nil {
+		return err
+	}
+
+	item.ProductID = id
+	if err = m.checkBundleOptions(ctx, item); err != nil {
+		return err
+	}
+	if err = checkSlotIDs(oldItem, item); err != nil {
+		return err
 	}
 
 	if oldItem.UnitPrice != item.UnitPrice {
@@ -124,14 +224,412 @@ func (m *menuService) UpdateMenu(ctx context.Context, id string, item *models.Pr
 	return
 }
 
-func (m *menuService) DeleteMenu(ctx context.Context, id string) (err error) {
-	if _, err = m.GetMenuByID(ctx, id); err != nil {
+// ArchiveMenu takes the item off the menu. Past orders and reports keep
+// resolving it.
+func (m *menuService) ArchiveMenu(ctx context.Context, id string) (err error) {
+	item, err := m.GetMenuByID(ctx, id, nil)
+	if err != nil {
 		return err
 	}
+	if item.ArchivedAt != nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("menu item is already archived"))
+	}
 
-	err = m.repo.MenuRepo.DeleteProduct(ctx, id)
+	now := time.Now()
+	if err = m.repo.MenuRepo.SetArchived(ctx, id, &now); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return
+}
+
+// RestoreMenu puts an archived item back on the menu, provided no item on the
+// menu has taken its name in the meantime.
+func (m *menuService) RestoreMenu(ctx context.Context, id string) (err error) {
+	item, err := m.GetMenuByID(ctx, id, nil)
 	if err != nil {
+		return err
+	}
+	if item.ArchivedAt == nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("menu item is not archived"))
+	}
+
+	if err = m.repo.MenuRepo.SetArchived(ctx, id, nil); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return models.NewError(models.ErrElemExist, errors.New("another menu item has the same name"))
+		}
 		return models.NewError(models.ErrInternal, err)
 	}
 	return
 }
+
+// MarkSoldOut takes the item off sale ("86" it). Without an explicit until
+// the mark resets automatically at the start of the next day.
+func (m *menuService) MarkSoldOut(ctx context.Context, id string, until *time.Time) (err error) {
+	if _, err = m.GetMenuByID(ctx, id, nil); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if until == nil {
+		midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		until = &midnight
+	}
+	if !until.After(now) {
+		return models.NewError(models.ErrInvalidInput, errors.New("sold out mark must end in the future"))
+	}
+
+	if err = m.repo.MenuRepo.SetSoldOut(ctx, id, until); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return
+}
+
+func (m *menuService) ClearSoldOut(ctx context.Context, id string) (err error) {
+	if _, err = m.GetMenuByID(ctx, id, nil); err != nil {
+		return err
+	}
+
+	if err = m.repo.MenuRepo.SetSoldOut(ctx, id, nil); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return
+}
+
+func (m *menuService) GetPriceHistory(ctx context.Context, id string) (history []*models.PriceHistory, err error) {
+	if _, err = m.GetMenuByID(ctx, id, nil); err != nil {
+		return nil, err
+	}
+
+	history, err = m.repo.MenuRepo.GetPriceHistory(ctx, id)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return
+}
+
+// resolveCategory points the item at its category. The category is taken by
+// id when given, otherwise looked up by the name in group.
+func (m *menuService) resolveCategory(ctx context.Context, item *models.Product) error {
+	var (
+		category *models.Category
+		err      error
+	)
+	if item.CategoryID != "" {
+		category, err = m.repo.CategoryRepo.GetByID(ctx, item.CategoryID)
+	} else {
+		category, err = m.repo.CategoryRepo.GetByName(ctx, item.Group)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("category not found"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+
+	item.CategoryID = category.CategoryID
+	item.Group = category.Name
+	return nil
+}
+
+// checkComponents makes sure every component and modifier is an inventory
+// item in use.
+func (m *menuService) checkComponents(ctx context.Context, item *models.Product) error {
+	ids := make([]string, 0, len(item.Components)+len(item.Modifiers))
+	for _, comp := range item.Components {
+		ids = append(ids, comp.ComponentID)
+	}
+	for _, mod := range item.Modifiers {
+		ids = append(ids, mod.ComponentID)
+	}
+
+	for _, id := range ids {
+		inv, err := m.repo.InventoryRepo.GetInventoryByID(ctx, id)
+		if err != nil {
+			return models.NewError(models.ErrNotFound, fmt.Errorf("inventory item %s not found", id))
+		}
+		if inv.ArchivedAt != nil {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is archived", inv.Title))
+		}
+	}
+	return nil
+}
+
+// checkBundleOptions makes sure every item offered by a bundle slot exists and
+// is not a bundle itself.
+func (m *menuService) checkBundleOptions(ctx context.Context, item *models.Product) error {
+	if !item.Bundle {
+		return nil
+	}
+
+	for _, slot := range item.Slots {
+		for _, opt := range slot.Options {
+			if opt.CategoryID != nil {
+				if _, err := m.repo.CategoryRepo.GetByID(ctx, *opt.CategoryID); err != nil {
+					return models.NewError(models.ErrNotFound, fmt.Errorf("category %s not found", *opt.CategoryID))
+				}
+				continue
+			}
+			option, err := m.repo.MenuRepo.GetProductByID(ctx, *opt.ItemID)
+			if err != nil {
+				return models.NewError(models.ErrNotFound, fmt.Errorf("menu item %s not found", *opt.ItemID))
+			}
+			if option.Bundle {
+				return models.NewError(models.ErrInvalidInput, errors.New("bundle cannot contain another bundle"))
+			}
+			if option.ArchivedAt != nil {
+				return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is archived", option.Title))
+			}
+		}
+	}
+	return nil
+}
+
+// checkSlotIDs makes sure an edited bundle names each of its slots at most
+// once and only slots it already has. Slots without an id are new.
+func checkSlotIDs(old, item *models.Product) error {
+	known := make(map[string]bool, len(old.Slots))
+	for _, slot := range old.Slots {
+		known[slot.SlotID] = true
+	}
+
+	seen := make(map[string]bool, len(item.Slots))
+	for _, slot := range item.Slots {
+		if slot.SlotID == "" {
+			continue
+		}
+		if !known[slot.SlotID] {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s has no slot %s", old.Title, slot.SlotID))
+		}
+		if seen[slot.SlotID] {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("slot %s appears twice", slot.SlotID))
+		}
+		seen[slot.SlotID] = true
+	}
+	return nil
+}
+
+// maxMakeable is the number of portions the free stock (on hand minus open
+// reservations) allows for. An item without recipe components cannot be
+// checked against stock and counts as unavailable.
+func maxMakeable(item *models.Product) int {
+	makeable := -1
+	for _, comp := range item.Components {
+		if comp.RequiredQty <= 0 {
+			continue
+		}
+		n := int(wholeUnits(comp.Available, comp.RequiredQty))
+		if n < 0 {
+			n = 0
+		}
+		if makeable < 0 || n < makeable {
+			makeable = n
+		}
+	}
+	if makeable < 0 {
+		return 0
+	}
+	return makeable
+}
+
+// describe works out what the menu shows beyond the stored items:
+// availability from live stock, allergens and nutrition from the recipe graph
+// and, when asked, recipe costs of prepared components. Bundles are judged by
+// their options, which need not be among the items, so the whole menu is
+// loaded when a bundle is present.
+func (m *menuService) describe(ctx context.Context, items []*models.Product, withCost bool, modifiers []string, now time.Time) error {
+	nodes, err := m.repo.InventoryRepo.GetRecipeGraph(ctx)
+	if err != nil {
+		return err
+	}
+	graph := recipeGraph(nodes)
+
+	if withCost {
+		if err := graph.costPreparedComponents(items); err != nil {
+			return err
+		}
+	}
+	if err := graph.applyDietary(items, modifiers); err != nil {
+		return err
+	}
+
+	hasBundle := false
+	for _, item := range items {
+		applyAvailability(item, now)
+		hasBundle = hasBundle || item.Bundle
+	}
+	if !hasBundle {
+		return nil
+	}
+
+	options, err := m.repo.MenuRepo.GetAllProducts(ctx)
+	if err != nil {
+		return err
+	}
+	if err := graph.applyDietary(options, nil); err != nil {
+		return err
+	}
+	for _, opt := range options {
+		applyAvailability(opt, now)
+	}
+	for _, item := range items {
+		if item.Bundle {
+			applyBundleAvailability(item, options, now)
+			applyBundleAllergens(item, options)
+		}
+	}
+	return nil
+}
+
+func applyAvailability(item *models.Product, now time.Time) {
+	item.MaxMakeable = maxMakeable(item)
+	item.Available = item.MaxMakeable > 0 && !item.IsSoldOut(now)
+}
+
+// applyBundleAvailability derives the availability of a bundle from its
+// options: each slot can be filled with its best stocked option. Availability
+// of the options must already be applied.
+func applyBundleAvailability(bundle *models.Product, products []*models.Product, now time.Time) {
+	makeable := -1
+	for _, slot := range bundle.Slots {
+		best := 0
+		for _, p := range products {
+			if p.Bundle || !p.Available || !slot.Offers(p) {
+				continue
+			}
+			if n := p.MaxMakeable / slot.Quantity; n > best {
+				best = n
+			}
+		}
+		if makeable < 0 || best < makeable {
+			makeable = best
+		}
+	}
+	if makeable < 0 {
+		makeable = 0
+	}
+
+	bundle.MaxMakeable = makeable
+	bundle.Available = makeable > 0 && !bundle.IsSoldOut(now)
+}
+
+// paginate cuts the requested page out of the items.
+func paginate(items []*models.Product, f *models.MenuFilter) []*models.Product {
+	if !f.Paged() {
+		return items
+	}
+	if f.Offset() >= len(items) {
+		return items[:0]
+	}
+	items = items[f.Offset():]
+	if f.PageSize < len(items) {
+		items = items[:f.PageSize]
+	}
+	return items
+}
+
+// applyBundleAllergens declares every allergen the bundle may contain,
+// whichever options end up selected.
+func applyBundleAllergens(bundle *models.Product, products []*models.Product) {
+	found := map[string]bool{}
+	for _, slot := range bundle.Slots {
+		for _, p := range products {
+			if p.Bundle || !slot.Offers(p) {
+				continue
+			}
+			for _, a := range p.Allergens {
+				found[a] = true
+			}
+		}
+	}
+	bundle.Allergens = sortedKeys(found)
+	bundle.Nutrition = nil
+}
+
+func containsAny(set, values []string) bool {
+	for _, v := range values {
+		if slices.Contains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// recipeCost prices one portion of a product at the current ingredient prices.
+func recipeCost(item *models.Product) float64 {
+	var cost float64
+	for _, comp := range item.Components {
+		cost += comp.RequiredQty * comp.UnitCost
+	}
+	return roundFloat(cost, 2)
+}
+
+func applyRecipeCost(item *models.Product) {
+	// a bundle has no recipe until its slots are filled on an order
+	if item.Bundle {
+		return
+	}
+
+	cost := recipeCost(item)
+	margin := roundFloat(item.UnitPrice-cost, 2)
+	item.Cost = &cost
+	item.Margin = &margin
+
+	if item.UnitPrice > 0 {
+		pct := roundFloat(margin/item.UnitPrice*100, 2)
+		item.MarginPct = &pct
+	}
+}
+
+// groupByCategory sorts the items into their categories. Categories come in
+// display order; a subcategory whose parent is missing is shown at the top
+// level.
+func groupByCategory(categories []*models.Category, items []*models.Product) []*models.MenuSection {
+	byID := make(map[string]*models.Category, len(categories))
+	sections := make(map[string]*models.MenuSection, len(categories))
+	for _, c := range categories {
+		byID[c.CategoryID] = c
+		sections[c.CategoryID] = &models.MenuSection{
+			CategoryID:   c.CategoryID,
+			Name:         c.Name,
+			DisplayOrder: c.DisplayOrder,
+			Items:        []*models.Product{},
+		}
+	}
+
+	for _, item := range items {
+		if section, ok := sections[item.CategoryID]; ok {
+			section.Items = append(section.Items, item)
+		}
+	}
+
+	var roots []*models.MenuSection
+	for _, c := range categories {
+		section := sections[c.CategoryID]
+		if c.ParentID != nil {
+			if parent, ok := sections[*c.ParentID]; ok {
+				parent.Sections = append(parent.Sections, section)
+				continue
+			}
+		}
+		roots = append(roots, section)
+	}
+
+	var visible func(sections []*models.MenuSection) []*models.MenuSection
+	visible = func(sections []*models.MenuSection) []*models.MenuSection {
+		shown := []*models.MenuSection{}
+		for _, section := range sections {
+			if !byID[section.CategoryID].IsActive() {
+				continue
+			}
+			section.Sections = visible(section.Sections)
+			if len(section.Items) == 0 && len(section.Sections) == 0 {
+				continue
+			}
+			shown = append(shown, section)
+		}
+		return shown
+	}
+
+	return visible(roots)
+}
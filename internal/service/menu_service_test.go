@@ -0,0 +1,90 @@
+package service
+
+import (
+	"testing"
+
+	"frappuccino/internal/models"
+)
+
+func TestMaxMakeable(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []*models.ProductComponent
+		want       int
+	}{
+		{
+			name: "float quotient just under a whole portion",
+			components: []*models.ProductComponent{
+				{RequiredQty: 0.2, Available: 0.6},
+			},
+			want: 3,
+		},
+		{
+			name: "exact last portion",
+			components: []*models.ProductComponent{
+				{RequiredQty: 0.1, Available: 0.3},
+				{RequiredQty: 18, Available: 54},
+			},
+			want: 3,
+		},
+		{
+			name: "short of the next portion",
+			components: []*models.ProductComponent{
+				{RequiredQty: 0.2, Available: 0.59},
+			},
+			want: 2,
+		},
+		{
+			name: "scarcest component decides",
+			components: []*models.ProductComponent{
+				{RequiredQty: 1, Available: 10},
+				{RequiredQty: 0.25, Available: 0.5},
+			},
+			want: 2,
+		},
+		{
+			name: "stock overcommitted by reservations",
+			components: []*models.ProductComponent{
+				{RequiredQty: 1, Available: -2},
+			},
+			want: 0,
+		},
+		{
+			name:       "no components",
+			components: nil,
+			want:       0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := maxMakeable(&models.Product{Components: tt.components})
+			if got != tt.want {
+				t.Errorf("maxMakeable() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSlotIDs(t *testing.T) {
+	old := &models.Product{Title: "Coffee + croissant", Slots: []*models.BundleSlot{{SlotID: "1"}, {SlotID: "2"}}}
+
+	tests := []struct {
+		name    string
+		slots   []*models.BundleSlot
+		wantErr bool
+	}{
+		{name: "kept, new and removed slots", slots: []*models.BundleSlot{{SlotID: "2"}, {}}},
+		{name: "slot of another bundle", slots: []*models.BundleSlot{{SlotID: "1"}, {SlotID: "3"}}, wantErr: true},
+		{name: "slot named twice", slots: []*models.BundleSlot{{SlotID: "1"}, {SlotID: "1"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSlotIDs(old, &models.Product{Bundle: true, Slots: tt.slots})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkSlotIDs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
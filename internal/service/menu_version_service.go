@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+
+	"github.com/lib/pq"
+)
+
+type MenuVersionService interface {
+	CreateVersion(ctx context.Context, version *models.MenuVersion) error
+	GetAllVersions(ctx context.Context) ([]*models.MenuVersion, error)
+	GetVersionByID(ctx context.Context, id string) (*models.MenuVersion, error)
+	DeleteVersion(ctx context.Context, id string) error
+	SaveEntry(ctx context.Context, versionID string, entry *models.MenuVersionEntry) error
+	DeleteEntry(ctx context.Context, versionID, entryID string) error
+	DiffVersion(ctx context.Context, id string) ([]*models.MenuDiffLine, error)
+	PublishVersion(ctx context.Context, id string) (*models.MenuVersion, error)
+}
+
+type menuVersionService struct {
+	Repo *repo.Container
+	menu *menuService
+}
+
+func NewMenuVersionService(r *repo.Container) MenuVersionService {
+	return &menuVersionService{
+		Repo: r,
+		menu: &menuService{repo: r},
+	}
+}
+
+func (s *menuVersionService) CreateVersion(ctx context.Context, version *models.MenuVersion) error {
+	if err := version.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	version.Entries = nil
+	if err := s.Repo.MenuVersionRepo.Create(ctx, version); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *menuVersionService) GetAllVersions(ctx context.Context) ([]*models.MenuVersion, error) {
+	versions, err := s.Repo.MenuVersionRepo.GetAll(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return versions, nil
+}
+
+func (s *menuVersionService) GetVersionByID(ctx context.Context, id string) (*models.MenuVersion, error) {
+	version, err := s.Repo.MenuVersionRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, strconv.ErrSyntax) {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("invalid menu version id"))
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrNotFound, errors.New("menu version not found"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return version, nil
+}
+
+func (s *menuVersionService) DeleteVersion(ctx context.Context, id string) error {
+	if _, err := s.getDraft(ctx, id); err != nil {
+		return err
+	}
+	if err := s.Repo.MenuVersionRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrInvalidInput, errors.New("menu version is no longer a draft"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+// SaveEntry records a change of a draft, replacing an earlier change of the
+// same item. Bundles are edited on the live menu only.
+func (s *menuVersionService) SaveEntry(ctx context.Context, versionID string, entry *models.MenuVersionEntry) error {
+	if err := entry.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	if _, err := s.getDraft(ctx, versionID); err != nil {
+		return err
+	}
+	if err := s.checkEntry(ctx, entry); err != nil {
+		return err
+	}
+
+	if err := s.Repo.MenuVersionRepo.SaveEntry(ctx, versionID, entry); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return models.NewError(models.ErrInvalidInput, errors.New("component listed more than once"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *menuVersionService) DeleteEntry(ctx context.Context, versionID, entryID string) error {
+	if _, err := s.getDraft(ctx, versionID); err != nil {
+		return err
+	}
+	if err := s.Repo.MenuVersionRepo.DeleteEntry(ctx, versionID, entryID); err != nil {
+		var pqErr *pq.Error
+		if errors.Is(err, sql.ErrNoRows) || errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+			return models.NewError(models.ErrNotFound, errors.New("entry not found"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+// DiffVersion compares a draft with the live menu, one line per item the
+// draft adds, changes or removes. Changes that match the live menu are left
+// out.
+func (s *menuVersionService) DiffVersion(ctx context.Context, id string) ([]*models.MenuDiffLine, error) {
+	version, err := s.getDraft(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	live, err := s.liveItems(ctx, version.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := []*models.MenuDiffLine{}
+	for _, entry := range version.Entries {
+		current := live[entry.ProductID]
+		switch {
+		case entry.Remove:
+			diff = append(diff, &models.MenuDiffLine{
+				ProductID: entry.ProductID,
+				Title:     current.Title,
+				Change:    "removed",
+			})
+		case current == nil:
+			diff = append(diff, &models.MenuDiffLine{
+				Title:  entry.Item.Title,
+				Change: "added",
+				Fields: diffProducts(nil, entry.Item),
+			})
+		default:
+			fields := diffProducts(current, entry.Item)
+			if len(fields) == 0 {
+				continue
+			}
+			diff = append(diff, &models.MenuDiffLine{
+				ProductID: entry.ProductID,
+				Title:     current.Title,
+				Change:    "changed",
+				Fields:    fields,
+			})
+		}
+	}
+	return diff, nil
+}
+
+// PublishVersion applies a draft to the menu and makes it live. The entries
+// are checked again since items and ingredients may have been archived after
+// the draft was written.
+func (s *menuVersionService) PublishVersion(ctx context.Context, id string) (*models.MenuVersion, error) {
+	version, err := s.getDraft(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range version.Entries {
+		if err := s.checkEntry(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.Repo.MenuVersionRepo.Publish(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("menu version is no longer a draft"))
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return nil, models.NewError(models.ErrElemExist, errors.New("another menu item has the same name"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	return s.GetVersionByID(ctx, id)
+}
+
+func (s *menuVersionService) getDraft(ctx context.Context, id string) (*models.MenuVersion, error) {
+	version, err := s.GetVersionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status != models.MenuVersionDraft {
+		return nil, models.NewError(models.ErrInvalidInput, fmt.Errorf("menu version is %s, not a draft", version.Status))
+	}
+	return version, nil
+}
+
+// checkEntry makes sure the item an entry changes is on the menu and not a
+// bundle, and that its category and components exist.
+func (s *menuVersionService) checkEntry(ctx context.Context, entry *models.MenuVersionEntry) error {
+	if entry.ProductID != "" {
+		current, err := s.Repo.MenuRepo.GetProductByID(ctx, entry.ProductID)
+		if err != nil {
+			return models.NewError(models.ErrNotFound, fmt.Errorf("menu item %s not found", entry.ProductID))
+		}
+		if current.ArchivedAt != nil {
+			return models.NewError(models.ErrInvalidInput, fmt.Errorf("%s is archived", current.Title))
+		}
+		if current.Bundle {
+			return models.NewError(models.ErrInvalidInput, errors.New("bundles cannot be changed in a menu version"))
+		}
+	}
+	if entry.Item == nil {
+		return nil
+	}
+
+	entry.Item.ProductID = entry.ProductID
+	if err := s.menu.resolveCategory(ctx, entry.Item); err != nil {
+		return err
+	}
+	return s.menu.checkComponents(ctx, entry.Item)
+}
+
+// liveItems loads the menu items the entries change, by id.
+func (s *menuVersionService) liveItems(ctx context.Context, entries []*models.MenuVersionEntry) (map[string]*models.Product, error) {
+	var ids []string
+	for _, entry := range entries {
+		if entry.ProductID != "" {
+			ids = append(ids, entry.ProductID)
+		}
+	}
+
+	items, err := s.Repo.MenuRepo.FetchProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	live := make(map[string]*models.Product, len(items))
+	for _, item := range items {
+		live[item.ProductID] = item
+	}
+	for _, id := range ids {
+		if live[id] == nil {
+			return nil, models.NewError(models.ErrNotFound, fmt.Errorf("menu item %s not found", id))
+		}
+	}
+	return live, nil
+}
+
+// diffProducts lists the fields of the draft item that differ from the live
+// one. Without a live item every field the draft sets is listed.
+func diffProducts(live, draft *models.Product) map[string]models.FieldChange {
+	added := live == nil
+	if added {
+		live = &models.Product{}
+	}
+
+	fields := make(map[string]models.FieldChange)
+	compare := func(name string, from, to any, equal bool) {
+		if equal {
+			return
+		}
+		if added {
+			from = nil
+		}
+		fields[name] = models.FieldChange{From: from, To: to}
+	}
+
+	compare("title", live.Title, draft.Title, live.Title == draft.Title)
+	compare("details", live.Details, draft.Details, live.Details == draft.Details)
+	compare("unit_price", live.UnitPrice, draft.UnitPrice, live.UnitPrice == draft.UnitPrice)
+	compare("size_label", live.SizeLabel, draft.SizeLabel, live.SizeLabel == draft.SizeLabel)
+	compare("group", live.Group, draft.Group, live.CategoryID == draft.CategoryID)
+	compare("labels", live.Labels, draft.Labels, slices.Equal(live.Labels, draft.Labels))
+	compare("extras", live.Extras, draft.Extras, len(live.Extras) == 0 && len(draft.Extras) == 0 || reflect.DeepEqual(live.Extras, draft.Extras))
+	compare("components", live.Components, draft.Components, sameComponents(live.Components, draft.Components))
+
+	return fields
+}
+
+func sameComponents(a, b []*models.ProductComponent) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	qty := make(map[string]float64, len(a))
+	for _, comp := range a {
+		qty[comp.ComponentID] = comp.RequiredQty
+	}
+	for _, comp := range b {
+		if q, ok := qty[comp.ComponentID]; !ok || q != comp.RequiredQty {
+			return false
+		}
+	}
+	return true
+}
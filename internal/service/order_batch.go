@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"frappuccino/internal/models"
+	order_repo "frappuccino/internal/repo/order"
+	"frappuccino/internal/slog"
+)
+
+// BatchProcessOrders creates and closes the orders of a batch. In best_effort
+// mode every order is kept or undone on its own; in all_or_nothing mode the
+// first order refused undoes the whole batch.
+func (s *orderService) BatchProcessOrders(ctx context.Context, batch *models.PurchaseBatch) (*models.PurchaseResult, error) {
+	if err := validateBatch(batch); err != nil {
+		return nil, err
+	}
+	return s.processBatch(ctx, batch.Purchases, batch.Mode, func(int) {}), nil
+}
+
+func validateBatch(batch *models.PurchaseBatch) error {
+	if err := models.ValidateBatchMode(batch.Mode); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	if batch.Mode == "" {
+		batch.Mode = models.BatchBestEffort
+	}
+	if len(batch.Purchases) == 0 {
+		return models.NewError(models.ErrInvalidInput, errors.New("batch has no orders"))
+	}
+	for _, order := range batch.Purchases {
+		if order == nil {
+			return models.NewError(models.ErrInvalidInput, errors.New("batch has an empty order"))
+		}
+	}
+	return nil
+}
+
+// batchPlacer places one order of a batch with tx, the service bound to the
+// transaction the order is placed in.
+type batchPlacer func(tx *orderService, ctx context.Context, order *models.Purchase) error
+
+// processBatch places the orders of a batch, reporting after each order how
+// many have been handled.
+func (s *orderService) processBatch(ctx context.Context, orders []*models.Purchase, mode string, progress func(processed int)) *models.PurchaseResult {
+	return s.runBatch(ctx, orders, mode, progress, (*orderService).placeBatchOrder)
+}
+
+// runBatch is processBatch with the placing of each order left to place.
+func (s *orderService) runBatch(ctx context.Context, orders []*models.Purchase, mode string, progress func(processed int), place batchPlacer) *models.PurchaseResult {
+	result := &models.PurchaseResult{
+		Handled: orders,
+		Report: models.ResultReport{
+			Mode:  mode,
+			Total: len(orders),
+		},
+	}
+
+	reject := func(order *models.Purchase, err error) {
+		str := err.Error()
+		if svcError, ok := err.(models.Error); ok {
+			str = svcError.AppError().Error()
+		}
+		order.Note = &str
+		order.Status = "rejected"
+		result.Report.Declined++
+	}
+	var accepted []string
+	accept := func(order *models.Purchase) {
+		// a batch order is created and closed at once; subscribers hear
+		// both, as they would have from CreateOrder and CloseOrder, before
+		// the status is replaced by the outcome of the batch
+		s.notify(ctx, models.OrderCreated, order)
+		s.notify(ctx, models.OrderCompleted, order)
+
+		order.Status = "accepted"
+		order.Note = nil
+		result.Report.Confirmed++
+		result.Report.Revenue += *order.Amount
+		accepted = append(accepted, order.PurchaseID)
+	}
+
+	if mode == models.BatchAllOrNothing {
+		var events []*models.StockEvent
+		failed := -1
+		err := s.inTx(ctx, func(tx *orderService) error {
+			ids := make([]string, 0, len(orders))
+			for i, order := range orders {
+				if err := place(tx, ctx, order); err != nil {
+					failed = i
+					return err
+				}
+				ids = append(ids, order.PurchaseID)
+				progress(i + 1)
+			}
+
+			// read before the commit, while the batch still holds the
+			// stock rows it deducted from
+			var err error
+			events, err = tx.Repo.OrderRepo.GetStockEvents(ctx, ids)
+			if err != nil {
+				return fmt.Errorf("stock events: %w", err)
+			}
+			return nil
+		})
+
+		if err != nil {
+			result.Report.RolledBack = true
+			for i, order := range orders {
+				// the ids handed out inside the transaction were never kept
+				order.PurchaseID = ""
+				switch {
+				case i == failed:
+					reject(order, err)
+				case failed >= 0:
+					reject(order, fmt.Errorf("batch rolled back: order %d was refused", failed+1))
+				default:
+					reject(order, fmt.Errorf("batch rolled back: %w", err))
+				}
+			}
+			progress(len(orders))
+			return result
+		}
+
+		for _, order := range orders {
+			accept(order)
+		}
+		result.Report.Revenue = roundFloat(result.Report.Revenue, 2)
+		result.Report.StockEvents = events
+		return result
+	}
+
+	// each order commits on its own while other orders may move the same
+	// stock, so the opening stock is read before the first one
+	opening, stockErr := s.Repo.OrderRepo.GetStockLevels(ctx)
+	if stockErr != nil {
+		stockErr = fmt.Errorf("opening stock: %w", stockErr)
+	}
+
+	for i, order := range orders {
+		err := s.inTx(ctx, func(tx *orderService) error {
+			return place(tx, ctx, order)
+		})
+		if err != nil {
+			order.PurchaseID = ""
+			reject(order, err)
+		} else {
+			accept(order)
+		}
+		progress(i + 1)
+	}
+	result.Report.Revenue = roundFloat(result.Report.Revenue, 2)
+
+	result.Report.StockEvents = []*models.StockEvent{}
+	if len(accepted) == 0 {
+		return result
+	}
+	if stockErr == nil {
+		events, err := s.Repo.OrderRepo.GetStockEvents(ctx, accepted)
+		if err != nil {
+			stockErr = fmt.Errorf("stock events: %w", err)
+		} else {
+			result.Report.StockEvents = batchStockEvents(events, opening)
+		}
+	}
+	if stockErr != nil {
+		slog.Error("Failed to read stock events of batch: %v", stockErr)
+		result.Report.StockEventsError = stockErr.Error()
+	}
+	return result
+}
+
+// batchStockEvents restates the events of a best_effort batch from the stock
+// on hand before the batch: the closing stock is the opening stock less what
+// the batch consumed, leaving out whatever other orders moved meanwhile.
+func batchStockEvents(events []*models.StockEvent, opening map[string]float64) []*models.StockEvent {
+	for i, e := range events {
+		if qty, ok := opening[e.InventoryID]; ok {
+			events[i] = models.NewStockEvent(e.InventoryID, e.Title, e.Measure, qty-e.Consumed, e.Consumed, e.ReorderLevel)
+		}
+	}
+	return events
+}
+
+// placeBatchOrder creates an order and closes it straight away.
+func (s *orderService) placeBatchOrder(ctx context.Context, order *models.Purchase) error {
+	if err := s.validateOrderInput(ctx, order); err != nil {
+		return err
+	}
+	if err := s.calculateOrderPrices(ctx, order); err != nil {
+		return err
+	}
+	if err := s.checkPickupSlot(ctx, order, ""); err != nil {
+		return err
+	}
+	if err := s.checkInventoryAvailability(ctx, order, ""); err != nil {
+		return err
+	}
+
+	if err := s.Repo.OrderRepo.CreateOrder(ctx, order); err != nil {
+		return err
+	}
+
+	if err := s.Repo.OrderRepo.CloseOrder(ctx, order); err != nil {
+		return err
+	}
+	return nil
+}
+
+// inTx runs fn on a copy of the service whose order queries share one
+// transaction.
+func (s *orderService) inTx(ctx context.Context, fn func(tx *orderService) error) error {
+	return s.Repo.OrderRepo.InTx(ctx, func(orders order_repo.OrderRepo) error {
+		repos := *s.Repo
+		repos.OrderRepo = orders
+		tx := *s
+		tx.Repo = &repos
+		return fn(&tx)
+	})
+}
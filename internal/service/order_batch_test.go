@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+	order_repo "frappuccino/internal/repo/order"
+	"frappuccino/internal/slog"
+)
+
+func TestMain(m *testing.M) {
+	slog.Init()
+	os.Exit(m.Run())
+}
+
+// fakeOrderRepo answers the queries a batch makes around its orders; any
+// other method panics.
+type fakeOrderRepo struct {
+	order_repo.OrderRepo
+
+	levels    map[string]float64
+	levelsErr error
+	events    []*models.StockEvent
+	eventsErr error
+
+	// eventsFor records the orders stock events were asked for.
+	eventsFor []string
+}
+
+func (f *fakeOrderRepo) InTx(ctx context.Context, fn func(tx order_repo.OrderRepo) error) error {
+	return fn(f)
+}
+
+func (f *fakeOrderRepo) GetStockLevels(ctx context.Context) (map[string]float64, error) {
+	return f.levels, f.levelsErr
+}
+
+func (f *fakeOrderRepo) GetStockEvents(ctx context.Context, orderIDs []string) ([]*models.StockEvent, error) {
+	f.eventsFor = orderIDs
+	return f.events, f.eventsErr
+}
+
+type fakeKitchen struct {
+	KitchenService
+}
+
+func (fakeKitchen) Publish(ctx context.Context, orderID string) {}
+
+func newBatchTestService(orders *fakeOrderRepo) *orderService {
+	return &orderService{
+		Repo:    &repo.Container{OrderRepo: orders},
+		kitchen: fakeKitchen{},
+		events:  NewOrderEventBus(0),
+	}
+}
+
+// placeRefusing places every order at 10.00 under the next id, except the
+// orders at the given positions, which are refused.
+func placeRefusing(refused ...int) batchPlacer {
+	n := 0
+	return func(tx *orderService, ctx context.Context, order *models.Purchase) error {
+		n++
+		for _, i := range refused {
+			if i == n {
+				return fmt.Errorf("order %d refused", n)
+			}
+		}
+		amount := 10.0
+		order.PurchaseID = strconv.Itoa(100 + n)
+		order.Amount = &amount
+		order.Status = "completed"
+		return nil
+	}
+}
+
+func newBatch(n int) []*models.Purchase {
+	orders := make([]*models.Purchase, n)
+	for i := range orders {
+		orders[i] = &models.Purchase{}
+	}
+	return orders
+}
+
+func milkEvent(closing, consumed float64) *models.StockEvent {
+	return models.NewStockEvent("1", "Milk", "l", closing, consumed, nil)
+}
+
+func TestRunBatchBestEffort(t *testing.T) {
+	fake := &fakeOrderRepo{
+		levels: map[string]float64{"1": 10},
+		// another order took 1 l while the batch ran
+		events: []*models.StockEvent{milkEvent(6, 3)},
+	}
+	s := newBatchTestService(fake)
+
+	var progress []int
+	orders := newBatch(3)
+	result := s.runBatch(context.Background(), orders, models.BatchBestEffort,
+		func(n int) { progress = append(progress, n) }, placeRefusing(2))
+
+	report := result.Report
+	if report.Total != 3 || report.Confirmed != 2 || report.Declined != 1 || report.Revenue != 20 {
+		t.Errorf("report = total %d, confirmed %d, declined %d, revenue %g; want 3, 2, 1, 20",
+			report.Total, report.Confirmed, report.Declined, report.Revenue)
+	}
+	if report.RolledBack {
+		t.Error("best_effort batch reported as rolled back")
+	}
+	if fmt.Sprint(progress) != "[1 2 3]" {
+		t.Errorf("progress = %v, want [1 2 3]", progress)
+	}
+
+	wantStatus := []string{"accepted", "rejected", "accepted"}
+	wantID := []string{"101", "", "103"}
+	for i, order := range orders {
+		if order.Status != wantStatus[i] || order.PurchaseID != wantID[i] {
+			t.Errorf("order %d = %s %q, want %s %q", i+1, order.Status, order.PurchaseID, wantStatus[i], wantID[i])
+		}
+	}
+	if note := orders[1].Note; note == nil || *note != "order 2 refused" {
+		t.Errorf("note of refused order = %v, want %q", note, "order 2 refused")
+	}
+
+	if fmt.Sprint(fake.eventsFor) != "[101 103]" {
+		t.Errorf("stock events read for %v, want [101 103]", fake.eventsFor)
+	}
+	if len(report.StockEvents) != 1 {
+		t.Fatalf("got %d stock events, want 1", len(report.StockEvents))
+	}
+	e := report.StockEvents[0]
+	if e.Opening != 10 || e.Consumed != 3 || e.Closing != 7 {
+		t.Errorf("stock event = opening %g, consumed %g, closing %g; want 10, 3, 7", e.Opening, e.Consumed, e.Closing)
+	}
+	if report.StockEventsError != "" {
+		t.Errorf("unexpected stock events error %q", report.StockEventsError)
+	}
+}
+
+func TestRunBatchBestEffortStockErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		repo *fakeOrderRepo
+	}{
+		{
+			name: "opening stock",
+			repo: &fakeOrderRepo{levelsErr: errors.New("connection lost")},
+		},
+		{
+			name: "stock events",
+			repo: &fakeOrderRepo{levels: map[string]float64{}, eventsErr: errors.New("connection lost")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newBatchTestService(tt.repo)
+			result := s.runBatch(context.Background(), newBatch(2), models.BatchBestEffort, func(int) {}, placeRefusing())
+
+			report := result.Report
+			if report.Confirmed != 2 {
+				t.Errorf("confirmed = %d, want 2: a failed report must not undo orders", report.Confirmed)
+			}
+			if report.StockEventsError == "" {
+				t.Error("stock events error not reported")
+			}
+			if report.StockEvents == nil || len(report.StockEvents) != 0 {
+				t.Errorf("stock events = %v, want empty", report.StockEvents)
+			}
+		})
+	}
+}
+
+func TestRunBatchBestEffortNothingAccepted(t *testing.T) {
+	fake := &fakeOrderRepo{levelsErr: errors.New("connection lost")}
+	s := newBatchTestService(fake)
+
+	result := s.runBatch(context.Background(), newBatch(2), models.BatchBestEffort, func(int) {}, placeRefusing(1, 2))
+
+	if result.Report.Declined != 2 {
+		t.Errorf("declined = %d, want 2", result.Report.Declined)
+	}
+	if fake.eventsFor != nil {
+		t.Errorf("stock events read for %v, want no read", fake.eventsFor)
+	}
+	if result.Report.StockEventsError != "" {
+		t.Errorf("stock events error %q reported without any order kept", result.Report.StockEventsError)
+	}
+}
+
+func TestRunBatchPublishesEvents(t *testing.T) {
+	for _, mode := range []string{models.BatchBestEffort, models.BatchAllOrNothing} {
+		t.Run(mode, func(t *testing.T) {
+			s := newBatchTestService(&fakeOrderRepo{levels: map[string]float64{}})
+			_, _, events, cancel := s.events.Subscribe(models.OrderEventFilter{}, nil)
+			defer cancel()
+
+			s.runBatch(context.Background(), newBatch(2), mode, func(int) {}, placeRefusing())
+
+			var got []string
+			for len(events) > 0 {
+				e := <-events
+				got = append(got, fmt.Sprintf("%s %s %s", e.Type, e.OrderID, e.Status))
+			}
+			want := "[order.created 101 completed order.completed 101 completed order.created 102 completed order.completed 102 completed]"
+			if fmt.Sprint(got) != want {
+				t.Errorf("events = %v, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRunBatchAllOrNothing(t *testing.T) {
+	events := []*models.StockEvent{milkEvent(6, 3)}
+	fake := &fakeOrderRepo{events: events}
+	s := newBatchTestService(fake)
+
+	var progress []int
+	orders := newBatch(3)
+	result := s.runBatch(context.Background(), orders, models.BatchAllOrNothing,
+		func(n int) { progress = append(progress, n) }, placeRefusing())
+
+	report := result.Report
+	if report.RolledBack || report.Confirmed != 3 || report.Declined != 0 || report.Revenue != 30 {
+		t.Errorf("report = rolled back %v, confirmed %d, declined %d, revenue %g; want false, 3, 0, 30",
+			report.RolledBack, report.Confirmed, report.Declined, report.Revenue)
+	}
+	if fmt.Sprint(progress) != "[1 2 3]" {
+		t.Errorf("progress = %v, want [1 2 3]", progress)
+	}
+	if fmt.Sprint(fake.eventsFor) != "[101 102 103]" {
+		t.Errorf("stock events read for %v, want [101 102 103]", fake.eventsFor)
+	}
+	// read inside the transaction, the events stand as they are
+	if len(report.StockEvents) != 1 || report.StockEvents[0] != events[0] {
+		t.Errorf("stock events = %v, want %v", report.StockEvents, events)
+	}
+	for i, order := range orders {
+		if order.Status != "accepted" {
+			t.Errorf("order %d is %s, want accepted", i+1, order.Status)
+		}
+	}
+}
+
+func TestRunBatchAllOrNothingRollsBack(t *testing.T) {
+	fake := &fakeOrderRepo{}
+	s := newBatchTestService(fake)
+
+	var progress []int
+	orders := newBatch(3)
+	result := s.runBatch(context.Background(), orders, models.BatchAllOrNothing,
+		func(n int) { progress = append(progress, n) }, placeRefusing(2))
+
+	report := result.Report
+	if !report.RolledBack || report.Confirmed != 0 || report.Declined != 3 || report.Revenue != 0 {
+		t.Errorf("report = rolled back %v, confirmed %d, declined %d, revenue %g; want true, 0, 3, 0",
+			report.RolledBack, report.Confirmed, report.Declined, report.Revenue)
+	}
+	if fmt.Sprint(progress) != "[1 3]" {
+		t.Errorf("progress = %v, want [1 3]", progress)
+	}
+	if fake.eventsFor != nil {
+		t.Errorf("stock events read for %v, want no read", fake.eventsFor)
+	}
+
+	wantNote := []string{
+		"batch rolled back: order 2 was refused",
+		"order 2 refused",
+		"batch rolled back: order 2 was refused",
+	}
+	for i, order := range orders {
+		if order.Status != "rejected" || order.PurchaseID != "" {
+			t.Errorf("order %d = %s %q, want rejected without id", i+1, order.Status, order.PurchaseID)
+		}
+		if order.Note == nil || *order.Note != wantNote[i] {
+			t.Errorf("note of order %d = %v, want %q", i+1, order.Note, wantNote[i])
+		}
+	}
+}
+
+func TestRunBatchAllOrNothingStockEventsError(t *testing.T) {
+	fake := &fakeOrderRepo{eventsErr: errors.New("connection lost")}
+	s := newBatchTestService(fake)
+
+	orders := newBatch(2)
+	result := s.runBatch(context.Background(), orders, models.BatchAllOrNothing, func(int) {}, placeRefusing())
+
+	if !result.Report.RolledBack || result.Report.Declined != 2 {
+		t.Errorf("report = rolled back %v, declined %d; want true, 2", result.Report.RolledBack, result.Report.Declined)
+	}
+	for i, order := range orders {
+		if order.Note == nil || *order.Note != "batch rolled back: stock events: connection lost" {
+			t.Errorf("note of order %d = %v", i+1, order.Note)
+		}
+	}
+}
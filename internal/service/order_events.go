@@ -0,0 +1,103 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+// orderEventSubscriberBuffer is how many events a subscriber may fall behind
+// before it is dropped.
+const orderEventSubscriberBuffer = 64
+
+// OrderEventBus fans order events out to subscribers within the process and
+// keeps the latest of them so a subscriber can resume after a reconnect.
+type OrderEventBus struct {
+	mu          sync.Mutex
+	nextID      int64
+	replay      []*models.OrderEvent
+	replaySize  int
+	subscribers map[chan *models.OrderEvent]models.OrderEventFilter
+}
+
+// NewOrderEventBus keeps the last replaySize events for resuming. IDs start
+// from the clock so they keep growing across restarts, and an ID handed out
+// before a restart is recognized as too old.
+func NewOrderEventBus(replaySize int) *OrderEventBus {
+	return &OrderEventBus{
+		nextID:      time.Now().UnixMicro(),
+		replaySize:  replaySize,
+		subscribers: make(map[chan *models.OrderEvent]models.OrderEventFilter),
+	}
+}
+
+// Publish numbers the event, keeps it for replay and hands it to every
+// subscriber whose filter it matches, without waiting on any.
+func (b *OrderEventBus) Publish(event *models.OrderEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event.ID = b.nextID
+	b.nextID++
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			b.replay = append(b.replay[:0], b.replay[1:]...)
+		}
+		b.replay = append(b.replay, event)
+	}
+
+	for ch, filter := range b.subscribers {
+		if !filter.Match(event) {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+			b.drop(ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber. With lastID set, the kept events after
+// it that match the filter are returned for replay; complete is false when
+// some events after lastID are no longer kept (or lastID is unknown), so the
+// subscriber should reload what it shows. Events arrive on the channel until
+// cancel is called or the subscriber falls too far behind and the channel is
+// closed.
+func (b *OrderEventBus) Subscribe(filter models.OrderEventFilter, lastID *int64) (replay []*models.OrderEvent, complete bool, events <-chan *models.OrderEvent, cancel func()) {
+	ch := make(chan *models.OrderEvent, orderEventSubscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastID != nil {
+		oldest := b.nextID - int64(len(b.replay))
+		complete = *lastID+1 >= oldest && *lastID < b.nextID
+		for _, event := range b.replay {
+			if event.ID > *lastID && filter.Match(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	b.subscribers[ch] = filter
+
+	return replay, complete, ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.drop(ch)
+	}
+}
+
+// drop unregisters a subscriber and closes its channel; b.mu must be held.
+func (b *OrderEventBus) drop(ch chan *models.OrderEvent) {
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
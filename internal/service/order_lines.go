@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+
+	"frappuccino/internal/models"
+
+	"github.com/lib/pq"
+)
+
+// AddLine adds a line to an open order. With merge, a line the same as one
+// already on the order adds to its count instead.
+func (s *orderService) AddLine(ctx context.Context, orderID string, line *models.LineItem, merge bool) (*models.Purchase, error) {
+	if err := line.Validate(); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+	line.LineID = ""
+
+	return s.editLines(ctx, orderID, func(tx *orderService, order *models.Purchase) (func() error, error) {
+		if merge {
+			if i := slices.IndexFunc(order.Positions, line.SameAs); i >= 0 {
+				same := order.Positions[i]
+				same.Count += line.Count
+				if err := tx.priceEdit(ctx, order, same); err != nil {
+					return nil, err
+				}
+				return func() error { return tx.Repo.OrderRepo.UpdateLine(ctx, orderID, same) }, nil
+			}
+		}
+
+		if err := tx.priceEdit(ctx, order, line); err != nil {
+			return nil, err
+		}
+		order.Positions = append(order.Positions, line)
+		return func() error { return tx.Repo.OrderRepo.AddLine(ctx, orderID, line) }, nil
+	})
+}
+
+// UpdateLine changes the count, adjustments or bundle items of a line of an
+// open order.
+func (s *orderService) UpdateLine(ctx context.Context, orderID, lineID string, patch *models.LinePatch) (*models.Purchase, error) {
+	return s.editLines(ctx, orderID, func(tx *orderService, order *models.Purchase) (func() error, error) {
+		line, err := findLine(order, lineID)
+		if err != nil {
+			return nil, err
+		}
+
+		patch.Apply(line)
+		if err := line.Validate(); err != nil {
+			return nil, models.NewError(models.ErrInvalidInput, err)
+		}
+		if err := tx.priceEdit(ctx, order, line); err != nil {
+			return nil, err
+		}
+		return func() error { return tx.Repo.OrderRepo.UpdateLine(ctx, orderID, line) }, nil
+	})
+}
+
+// DeleteLine takes a line off an open order. The last line cannot go; the
+// order is deleted instead.
+func (s *orderService) DeleteLine(ctx context.Context, orderID, lineID string) (*models.Purchase, error) {
+	return s.editLines(ctx, orderID, func(tx *orderService, order *models.Purchase) (func() error, error) {
+		line, err := findLine(order, lineID)
+		if err != nil {
+			return nil, err
+		}
+		if len(order.Positions) == 1 {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("an order needs at least one line, delete the order instead"))
+		}
+
+		order.Positions = slices.DeleteFunc(order.Positions, func(l *models.LineItem) bool { return l == line })
+		return func() error { return tx.Repo.OrderRepo.DeleteLine(ctx, orderID, lineID) }, nil
+	})
+}
+
+// editLines changes the lines of an open order in one transaction. edit
+// changes the order in memory, pricing the lines it touches, and returns how
+// to store the change; the other lines keep their price. The order is then
+// checked against its pickup slot and the free stock, and saved with its new
+// totals and reservations.
+func (s *orderService) editLines(ctx context.Context, orderID string, edit func(tx *orderService, order *models.Purchase) (func() error, error)) (*models.Purchase, error) {
+	var order *models.Purchase
+	err := s.inTx(ctx, func(tx *orderService) error {
+		err := tx.Repo.OrderRepo.LockOrder(ctx, orderID)
+		if err == nil {
+			order, err = tx.Repo.OrderRepo.GetOrderByID(ctx, orderID)
+		}
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+				return models.NewError(models.ErrNotFound, errors.New("order not found"))
+			}
+			return models.NewError(models.ErrInternal, err)
+		}
+		if order.Status != "open" {
+			return models.NewError(models.ErrInvalidInput, errors.New("order is not open"))
+		}
+
+		save, err := edit(tx, order)
+		if err != nil {
+			return err
+		}
+		tx.setTotals(order)
+
+		if err := tx.checkPickupSlot(ctx, order, orderID); err != nil {
+			return err
+		}
+		if err := tx.checkInventoryAvailability(ctx, order, orderID); err != nil {
+			return err
+		}
+
+		if err := save(); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.NewError(models.ErrNotFound, errors.New("line not found"))
+			}
+			return models.NewError(models.ErrInternal, err)
+		}
+		if err := tx.Repo.OrderRepo.UpdateTotals(ctx, order); err != nil {
+			return models.NewError(models.ErrInternal, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notify(ctx, models.OrderUpdated, order)
+	return order, nil
+}
+
+// priceEdit prices a line added to or changed on order. The line must be
+// priced with the menu version the rest of the order was priced with; an
+// order placed on an older menu moves to the live one only when it is
+// updated as a whole.
+func (s *orderService) priceEdit(ctx context.Context, order *models.Purchase, line *models.LineItem) error {
+	versionID, err := s.priceLines(ctx, []*models.LineItem{line})
+	if err != nil {
+		return err
+	}
+	if order.MenuVersionID != nil && *order.MenuVersionID != versionID {
+		return models.NewError(models.ErrInvalidInput, errors.New("the menu changed since the order was priced, update the whole order instead"))
+	}
+	order.MenuVersionID = &versionID
+	return nil
+}
+
+func findLine(order *models.Purchase, lineID string) (*models.LineItem, error) {
+	for _, line := range order.Positions {
+		if line.LineID == lineID {
+			return line, nil
+		}
+	}
+	return nil, models.NewError(models.ErrNotFound, errors.New("line not found"))
+}
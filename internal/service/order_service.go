@@ -8,33 +8,62 @@ import (
 
 	"frappuccino/internal/models"
 	repo "frappuccino/internal/repo"
+	"frappuccino/internal/slog"
 
 	"github.com/lib/pq"
 )
 
 type OrderService interface {
-	GetAllOrders(ctx context.Context) (listOrders []*models.Purchase, err error)
+	GetAllOrders(ctx context.Context, filter models.OrderFilter) (listOrders []*models.Purchase, err error)
 	CreateOrder(ctx context.Context, order *models.Purchase) error
 	GetOrderById(ctx context.Context, id string) (order *models.Purchase, err error)
 	UpdateOrder(ctx context.Context, id string, order *models.Purchase) error
 	DeleteOrder(ctx context.Context, id string) error
 	CloseOrder(ctx context.Context, id string) error
-	BatchProcessOrders(ctx context.Context, listOrders []*models.Purchase) (*models.PurchaseResult, error)
+	AddLine(ctx context.Context, orderID string, line *models.LineItem, merge bool) (*models.Purchase, error)
+	UpdateLine(ctx context.Context, orderID, lineID string, patch *models.LinePatch) (*models.Purchase, error)
+	DeleteLine(ctx context.Context, orderID, lineID string) (*models.Purchase, error)
+	BatchProcessOrders(ctx context.Context, batch *models.PurchaseBatch) (*models.PurchaseResult, error)
+	SubmitBatch(batch *models.PurchaseBatch) (*models.BatchJob, error)
+	GetBatchJob(id string) (*models.BatchJob, error)
+	RunBatchWorker(ctx context.Context)
 	GetNumberOfOrderedItems(ctx context.Context, startDate, endDate *time.Time) (map[string]int, error)
+	GetReservations(ctx context.Context, olderThan time.Duration) ([]*models.OrderReservation, error)
+	CancelStaleOrders(ctx context.Context) ([]*models.Purchase, error)
+	RunExpiry(ctx context.Context, interval time.Duration)
 }
 
 type orderService struct {
 	Repo *repo.Container
+	// ttl is how long an open order may go unchanged before it is canceled
+	// and its reservations freed; 0 keeps open orders forever.
+	ttl time.Duration
+	// kitchen and events are told about every order change.
+	kitchen KitchenService
+	events  *OrderEventBus
+	// channels prices tax and delivery by order channel.
+	channels models.ChannelPricing
+	// jobs are the batches processed in the background.
+	jobs *batchJobs
 }
 
-func NewOrderService(r *repo.Container) OrderService {
+func NewOrderService(r *repo.Container, ttl time.Duration, kitchen KitchenService, events *OrderEventBus, channels models.ChannelPricing) OrderService {
 	return &orderService{
-		Repo: r,
+		Repo:     r,
+		ttl:      ttl,
+		kitchen:  kitchen,
+		events:   events,
+		channels: channels,
+		jobs:     newBatchJobs(),
 	}
 }
 
-func (s *orderService) GetAllOrders(ctx context.Context) (listOrders []*models.Purchase, err error) {
-	listOrders, err = s.Repo.OrderRepo.GetAllOrders(ctx)
+func (s *orderService) GetAllOrders(ctx context.Context, filter models.OrderFilter) (listOrders []*models.Purchase, err error) {
+	if err := models.ValidateChannel(filter.Channel); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	listOrders, err = s.Repo.OrderRepo.GetAllOrders(ctx, filter)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return listOrders, nil
@@ -49,17 +78,27 @@ func (s *orderService) CreateOrder(ctx context.Context, order *models.Purchase)
 		return err
 	}
 
-	if err := s.checkInventoryAvailability(ctx, order); err != nil {
+	// prices first: they resolve bundle selections into the component
+	// lines the stock check works on
+	if err := s.calculateOrderPrices(ctx, order); err != nil {
 		return err
 	}
 
-	if err := s.calculateOrderPrices(ctx, order); err != nil {
+	if err := s.checkPickupSlot(ctx, order, ""); err != nil {
+		return err
+	}
+
+	if err := s.checkInventoryAvailability(ctx, order, ""); err != nil {
 		return err
 	}
 
 	order.Status = "open"
 
-	return s.Repo.OrderRepo.CreateOrder(ctx, order)
+	if err := s.Repo.OrderRepo.CreateOrder(ctx, order); err != nil {
+		return err
+	}
+	s.notify(ctx, models.OrderCreated, order)
+	return nil
 }
 
 func (s *orderService) GetOrderById(ctx context.Context, id string) (order *models.Purchase, err error) {
@@ -92,11 +131,6 @@ func (s *orderService) UpdateOrder(ctx context.Context, id string, order *models
 		return err
 	}
 
-	err = s.checkInventoryAvailability(ctx, order)
-	if err != nil {
-		return err
-	}
-
 	oldOrder, err := s.Repo.OrderRepo.GetOrderByID(ctx, id)
 	if err != nil {
 		return err
@@ -111,19 +145,42 @@ func (s *orderService) UpdateOrder(ctx context.Context, id string, order *models
 		return err
 	}
 
-	return s.Repo.OrderRepo.UpdateOrder(ctx, id, order)
+	err = s.checkPickupSlot(ctx, order, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.checkInventoryAvailability(ctx, order, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Repo.OrderRepo.UpdateOrder(ctx, id, order); err != nil {
+		return err
+	}
+	order.PurchaseID = id
+	order.Status = oldOrder.Status
+	s.notify(ctx, models.OrderUpdated, order)
+	return nil
 }
 
 func (s *orderService) DeleteOrder(ctx context.Context, id string) error {
+	// loaded for the event only; deleting a missing order is not an error
+	order, _ := s.Repo.OrderRepo.GetOrderByID(ctx, id)
+
 	err := s.Repo.OrderRepo.DeleteOrder(ctx, id)
 	if err != nil {
 		var pqErr *pq.Error
 		if errors.As(err, &pqErr) && pqErr.Code == "22P02" {
 			return models.NewError(models.ErrInvalidInput, errors.New("invalid UUID format"))
 		}
+		return err
 	}
 
-	return err
+	if order != nil {
+		s.notify(ctx, models.OrderDeleted, order)
+	}
+	return nil
 }
 
 func (s *orderService) CloseOrder(ctx context.Context, id string) error {
@@ -132,7 +189,9 @@ func (s *orderService) CloseOrder(ctx context.Context, id string) error {
 		return err
 	}
 
-	if order.Status != "open" {
+	switch order.Status {
+	case "open", "in_progress", "ready":
+	default:
 		return models.NewError(models.ErrInvalidInput, errors.New("order is not open"))
 	}
 
@@ -141,118 +200,103 @@ func (s *orderService) CloseOrder(ctx context.Context, id string) error {
 		return err
 	}
 
+	s.notify(ctx, models.OrderCompleted, order)
 	return nil
 }
 
-func (s *orderService) BatchProcessOrders(ctx context.Context, listOrders []*models.Purchase) (*models.PurchaseResult, error) {
-	var processedOrders []*models.Purchase
-	var totalRevenue float64
-	var rejectedCount, acceptedCount int
-
-	inventoryChanges := make(map[string]*models.ProductComponent)
-
-	for _, order := range listOrders {
-		setRejected := func(err error) {
-			rejectedCount++
-			var str string
-			if svcError, ok := err.(models.Error); ok {
-				str = svcError.AppError().Error()
-			} else {
-				str = err.Error()
-			}
-			order.Note = &str
-			order.Status = "rejected"
-			processedOrders = append(processedOrders, order)
-		}
+func (s *orderService) GetNumberOfOrderedItems(ctx context.Context, startDate, endDate *time.Time) (map[string]int, error) {
+	results, err := s.Repo.OrderRepo.GetNumberOfOrderedItems(ctx, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
 
-		// Validate input
-		if err := s.validateOrderInput(ctx, order); err != nil {
-			setRejected(err)
-			continue
-		}
+	return results, nil
+}
 
-		if err := s.checkInventoryAvailability(ctx, order); err != nil {
-			setRejected(err)
-			continue
-		}
+// GetReservations lists the stock held by orders whose reservation is at
+// least olderThan old, oldest first.
+func (s *orderService) GetReservations(ctx context.Context, olderThan time.Duration) ([]*models.OrderReservation, error) {
+	if olderThan < 0 {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("older_than must not be negative"))
+	}
 
-		if err := s.calculateOrderPrices(ctx, order); err != nil {
-			setRejected(err)
-			continue
-		}
+	reservations, err := s.Repo.OrderRepo.GetReservations(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
 
-		if err := s.Repo.OrderRepo.CreateOrder(ctx, order); err != nil {
-			setRejected(err)
+	filtered := make([]*models.OrderReservation, 0, len(reservations))
+	for _, res := range reservations {
+		if time.Duration(res.AgeMinutes)*time.Minute < olderThan.Truncate(time.Minute) {
 			continue
 		}
-
-		for _, item := range order.Positions {
-			menu, err := s.Repo.MenuRepo.GetProductByID(ctx, item.ItemID)
-			if err != nil {
-				setRejected(err)
-				continue
-			}
-
-			for _, ingredient := range menu.Components {
-				ingredientID := ingredient.ComponentID
-				requiredQty := roundFloat(ingredient.RequiredQty*float64(item.Count), 2)
-
-				if change, exists := inventoryChanges[ingredientID]; !exists {
-					inventoryChanges[ingredientID] = &models.ProductComponent{
-						ComponentID: ingredientID,
-						RequiredQty: requiredQty,
-					}
-				} else {
-					change.RequiredQty = roundFloat(change.RequiredQty+requiredQty, 2)
-				}
+		if s.ttl > 0 && res.Status == "open" {
+			expiresAt := res.LastActivity.Add(s.ttl)
+			if res.PickupAt != nil && res.PickupAt.Add(s.ttl).After(expiresAt) {
+				expiresAt = res.PickupAt.Add(s.ttl)
 			}
+			res.ExpiresAt = &expiresAt
 		}
+		filtered = append(filtered, res)
+	}
+	return filtered, nil
+}
 
-		if err := s.Repo.OrderRepo.CloseOrder(ctx, order); err != nil {
-			setRejected(err)
-			continue
-		}
+// CancelStaleOrders cancels the open orders unchanged for longer than the TTL
+// and frees their reservations. It does nothing when the TTL is 0.
+func (s *orderService) CancelStaleOrders(ctx context.Context) ([]*models.Purchase, error) {
+	if s.ttl <= 0 {
+		return nil, nil
+	}
+	canceled, err := s.Repo.OrderRepo.CancelStaleOrders(ctx, s.ttl)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return canceled, nil
+}
 
-		order.Status = "accepted"
-		order.Note = nil
-		totalRevenue += *order.Amount
-		acceptedCount++
-		processedOrders = append(processedOrders, order)
+// RunExpiry cancels stale open orders every interval until ctx is done.
+func (s *orderService) RunExpiry(ctx context.Context, interval time.Duration) {
+	if s.ttl <= 0 {
+		return
 	}
 
-	for id, change := range inventoryChanges {
-		invtItem, err := s.Repo.InventoryRepo.GetInventoryByID(ctx, id)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		canceled, err := s.CancelStaleOrders(ctx)
 		if err != nil {
-			continue
+			slog.Error("Failed to cancel stale orders: %v", err)
+		}
+		for _, order := range canceled {
+			slog.Info("Stale order canceled, reservations freed: order=%s", order.PurchaseID)
+			if full, err := s.Repo.OrderRepo.GetOrderByID(ctx, order.PurchaseID); err == nil {
+				order = full
+			}
+			s.notify(ctx, models.OrderCanceled, order)
 		}
-		inventoryChanges[id].ComponentName = invtItem.Title
-		inventoryChanges[id].RequiredQty = change.RequiredQty
-		remaining := roundFloat(invtItem.UnitCost-change.RequiredQty, 2)
-		change.InStock = &remaining
-	}
 
-	invtList := make([]*models.ProductComponent, 0, len(inventoryChanges))
-	for _, v := range inventoryChanges {
-		invtList = append(invtList, v)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-
-	return &models.PurchaseResult{
-		Handled: processedOrders,
-		Report: models.ResultReport{
-			Total:       len(listOrders),
-			Confirmed:   acceptedCount,
-			Declined:    rejectedCount,
-			Revenue:     totalRevenue,
-			StockEvents: invtList,
-		},
-	}, nil
 }
 
-func (s *orderService) GetNumberOfOrderedItems(ctx context.Context, startDate, endDate *time.Time) (map[string]int, error) {
-	results, err := s.Repo.OrderRepo.GetNumberOfOrderedItems(ctx, startDate, endDate)
-	if err != nil {
-		return nil, err
-	}
-
-	return results, nil
+// notify tells the kitchen displays and the order event subscribers that an
+// order changed.
+func (s *orderService) notify(ctx context.Context, eventType string, order *models.Purchase) {
+	s.kitchen.Publish(ctx, order.PurchaseID)
+
+	// subscribers encode the order later; keep them off the caller's copy
+	snapshot := *order
+	s.events.Publish(&models.OrderEvent{
+		Type:       eventType,
+		OrderID:    order.PurchaseID,
+		CustomerID: order.CustomerID,
+		Status:     order.Status,
+		Order:      &snapshot,
+	})
 }
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+)
+
+type PickupService interface {
+	GetHours(ctx context.Context) ([]*models.PickupHours, error)
+	SaveHours(ctx context.Context, day string, hours *models.PickupHours) error
+	DeleteHours(ctx context.Context, day string) error
+	GetSlots(ctx context.Context, date time.Time) ([]*models.PickupSlot, error)
+}
+
+type pickupService struct {
+	Repo *repo.Container
+}
+
+func NewPickupService(r *repo.Container) PickupService {
+	return &pickupService{
+		Repo: r,
+	}
+}
+
+func (s *pickupService) GetHours(ctx context.Context) ([]*models.PickupHours, error) {
+	hours, err := s.Repo.PickupRepo.GetHours(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return hours, nil
+}
+
+// SaveHours sets the pickup hours of an ISO weekday. Orders already placed
+// keep their pickup time even when it no longer fits.
+func (s *pickupService) SaveHours(ctx context.Context, day string, hours *models.PickupHours) error {
+	n, err := strconv.Atoi(day)
+	if err != nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("invalid day"))
+	}
+	hours.Day = n
+	if err := hours.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	if err := s.Repo.PickupRepo.SaveHours(ctx, hours); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *pickupService) DeleteHours(ctx context.Context, day string) error {
+	n, err := strconv.Atoi(day)
+	if err != nil {
+		return models.NewError(models.ErrInvalidInput, errors.New("invalid day"))
+	}
+
+	if err := s.Repo.PickupRepo.DeleteHours(ctx, n); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("no pickup hours on this day"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+// GetSlots lists the pickup slots of a day still to come, with the room
+// left in each.
+func (s *pickupService) GetSlots(ctx context.Context, date time.Time) ([]*models.PickupSlot, error) {
+	slots, err := pickupSlots(ctx, s.Repo, date, "")
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	now := time.Now()
+	upcoming := []*models.PickupSlot{}
+	for _, slot := range slots {
+		if slot.Start.After(now) {
+			upcoming = append(upcoming, slot)
+		}
+	}
+	return upcoming, nil
+}
+
+// pickupSlots lays out the pickup slots of the day of date in local time,
+// with what is booked into them apart from the given order. A day without
+// pickup hours has no slots.
+func pickupSlots(ctx context.Context, r *repo.Container, date time.Time, excludeOrderID string) ([]*models.PickupSlot, error) {
+	date = date.In(time.Local)
+	day := int(date.Weekday())
+	if day == 0 {
+		day = 7
+	}
+
+	hours, err := r.PickupRepo.GetHoursByDay(ctx, day)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	slots := hours.Slots(date)
+	if len(slots) == 0 {
+		return nil, nil
+	}
+	bookings, err := r.PickupRepo.GetBookings(ctx, slots[0].Start, slots[len(slots)-1].End, excludeOrderID)
+	if err != nil {
+		return nil, err
+	}
+	models.Book(slots, bookings)
+	return slots, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+	"frappuccino/internal/slog"
+)
+
+type PricingService interface {
+	SchedulePriceChange(ctx context.Context, menuItemID string, change *models.ScheduledPriceChange) error
+	GetScheduledChanges(ctx context.Context, menuItemID string) ([]*models.ScheduledPriceChange, error)
+	CancelScheduledChange(ctx context.Context, menuItemID, changeID string) error
+	CreateWindow(ctx context.Context, window *models.PriceWindow) error
+	GetWindows(ctx context.Context) ([]*models.PriceWindow, error)
+	DeleteWindow(ctx context.Context, id string) error
+	ApplyDueChanges(ctx context.Context) ([]*models.PriceHistory, error)
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+type pricingService struct {
+	Repo *repo.Container
+}
+
+func NewPricingService(r *repo.Container) PricingService {
+	return &pricingService{
+		Repo: r,
+	}
+}
+
+func (s *pricingService) SchedulePriceChange(ctx context.Context, menuItemID string, change *models.ScheduledPriceChange) error {
+	if err := change.Validate(time.Now()); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	if _, err := s.Repo.MenuRepo.GetProductByID(ctx, menuItemID); err != nil {
+		return models.NewError(models.ErrNotFound, errors.New("menu item not found"))
+	}
+
+	change.MenuItemID = menuItemID
+	if err := s.Repo.PricingRepo.CreateScheduledChange(ctx, change); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *pricingService) GetScheduledChanges(ctx context.Context, menuItemID string) ([]*models.ScheduledPriceChange, error) {
+	if _, err := s.Repo.MenuRepo.GetProductByID(ctx, menuItemID); err != nil {
+		return nil, models.NewError(models.ErrNotFound, errors.New("menu item not found"))
+	}
+
+	changes, err := s.Repo.PricingRepo.GetScheduledChanges(ctx, menuItemID)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return changes, nil
+}
+
+func (s *pricingService) CancelScheduledChange(ctx context.Context, menuItemID, changeID string) error {
+	if err := s.Repo.PricingRepo.CancelScheduledChange(ctx, menuItemID, changeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("pending price change not found"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *pricingService) CreateWindow(ctx context.Context, window *models.PriceWindow) error {
+	if err := window.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	if window.ItemID != nil {
+		if _, err := s.Repo.MenuRepo.GetProductByID(ctx, *window.ItemID); err != nil {
+			return models.NewError(models.ErrNotFound, errors.New("menu item not found"))
+		}
+	}
+	if window.CategoryID != nil {
+		if _, err := s.Repo.CategoryRepo.GetByID(ctx, *window.CategoryID); err != nil {
+			return models.NewError(models.ErrNotFound, errors.New("category not found"))
+		}
+	}
+
+	if err := s.Repo.PricingRepo.CreateWindow(ctx, window); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *pricingService) GetWindows(ctx context.Context) ([]*models.PriceWindow, error) {
+	windows, err := s.Repo.PricingRepo.GetWindows(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return windows, nil
+}
+
+func (s *pricingService) DeleteWindow(ctx context.Context, id string) error {
+	if err := s.Repo.PricingRepo.DeleteWindow(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrNotFound, errors.New("price window not found"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *pricingService) ApplyDueChanges(ctx context.Context) ([]*models.PriceHistory, error) {
+	applied, err := s.Repo.PricingRepo.ApplyDueChanges(ctx, time.Now())
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return applied, nil
+}
+
+// RunScheduler applies due price changes every interval until ctx is done.
+// Changes that fell due while the server was down are applied on start.
+func (s *pricingService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		applied, err := s.ApplyDueChanges(ctx)
+		if err != nil {
+			slog.Error("Failed to apply scheduled price changes: %v", err)
+		}
+		for _, ph := range applied {
+			slog.Info("Scheduled price change applied: product=%s, %.2f -> %.2f", ph.MenuItemID, ph.OldPrice, ph.NewPrice)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// effectivePrice resolves the price of a product at the given moment. A window
+// naming the item beats a window on its group; among equally specific windows
+// the lowest price wins.
+func effectivePrice(p *models.Product, windows []*models.PriceWindow, now time.Time) float64 {
+	price := p.UnitPrice
+	found, itemLevel := false, false
+
+	for _, w := range windows {
+		if !w.Targets(p) || !w.Covers(now) {
+			continue
+		}
+		candidate := roundFloat(w.PriceFor(p.UnitPrice), 2)
+		isItem := w.ItemID != nil
+
+		switch {
+		case !found, isItem && !itemLevel:
+			price = candidate
+		case isItem == itemLevel && candidate < price:
+			price = candidate
+		default:
+			continue
+		}
+		found = true
+		itemLevel = itemLevel || isItem
+	}
+
+	return price
+}
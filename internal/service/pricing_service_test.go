@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+func TestEffectivePrice(t *testing.T) {
+	ref := func(s string) *string { return &s }
+	amount := func(f float64) *float64 { return &f }
+	window := func(item, category *string, price, discount *float64) *models.PriceWindow {
+		return &models.PriceWindow{
+			ItemID: item, CategoryID: category, Price: price, DiscountPct: discount,
+			Days: []int64{1, 2, 3, 4, 5, 6, 7}, Start: "00:00", End: "23:59",
+		}
+	}
+
+	product := &models.Product{ProductID: "1", CategoryID: "4", UnitPrice: 5}
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+
+	categoryDiscount := window(nil, ref("4"), nil, amount(20))
+	itemPrice := window(ref("1"), nil, amount(4.5), nil)
+	lowerItemPrice := window(ref("1"), nil, amount(4.2), nil)
+	otherItem := window(ref("2"), nil, amount(1), nil)
+	closed := &models.PriceWindow{ItemID: ref("1"), Price: amount(1), Days: []int64{1}, Start: "15:00", End: "17:00"}
+
+	tests := []struct {
+		name    string
+		product *models.Product
+		windows []*models.PriceWindow
+		want    float64
+	}{
+		{"list price without windows", product, nil, 5},
+		{"category discount", product, []*models.PriceWindow{categoryDiscount}, 4},
+		{"item window beats a lower category price", product, []*models.PriceWindow{categoryDiscount, itemPrice}, 4.5},
+		{"item window beats category in any order", product, []*models.PriceWindow{itemPrice, categoryDiscount}, 4.5},
+		{"lowest of the item windows", product, []*models.PriceWindow{itemPrice, lowerItemPrice}, 4.2},
+		{"window outside its hours", product, []*models.PriceWindow{closed}, 5},
+		{"window on another item", product, []*models.PriceWindow{otherItem}, 5},
+		{
+			name:    "discount rounded to the cent",
+			product: &models.Product{ProductID: "3", CategoryID: "4", UnitPrice: 4.99},
+			windows: []*models.PriceWindow{window(nil, ref("4"), nil, amount(10))},
+			want:    4.49,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := effectivePrice(tt.product, tt.windows, now); got != tt.want {
+				t.Errorf("effectivePrice() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
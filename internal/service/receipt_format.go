@@ -0,0 +1,338 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"frappuccino/internal/models"
+)
+
+const (
+	FormatText   = "text"
+	FormatHTML   = "html"
+	FormatESCPOS = "escpos"
+)
+
+// receiptRow is one line of a printed receipt: text on the left, an amount
+// on the right, or a rule across the paper.
+type receiptRow struct {
+	Left   string
+	Right  string
+	Center bool
+	Bold   bool
+	Large  bool
+	Rule   bool
+}
+
+var channelLabels = map[string]string{
+	models.ChannelDineIn:   "Dine-in",
+	models.ChannelTakeaway: "Takeaway",
+	models.ChannelDelivery: "Delivery",
+}
+
+// receiptColumns is how many characters of the standard printer font fit on
+// a line of the paper.
+func receiptColumns(paper int) int {
+	if paper == models.Paper58mm {
+		return 32
+	}
+	return 48
+}
+
+// layoutReceipt lays the receipt out as rows, the same for every format.
+func layoutReceipt(r *models.Receipt) []receiptRow {
+	var rows []receiptRow
+	add := func(row receiptRow) { rows = append(rows, row) }
+	rule := receiptRow{Rule: true}
+
+	if r.Kind == models.ReceiptKitchen {
+		add(receiptRow{Left: "#" + r.OrderID, Center: true, Bold: true, Large: true})
+		add(receiptRow{Left: fulfilment(r), Center: true, Bold: true})
+	} else {
+		add(receiptRow{Left: r.Shop.Name, Center: true, Bold: true, Large: true})
+		for _, info := range []string{r.Shop.Address, r.Shop.Phone} {
+			if info != "" {
+				add(receiptRow{Left: info, Center: true})
+			}
+		}
+		if r.Shop.TaxID != "" {
+			add(receiptRow{Left: "Tax ID " + r.Shop.TaxID, Center: true})
+		}
+		add(rule)
+		add(receiptRow{Left: "Order #" + r.OrderID, Right: channelLabels[r.Channel], Bold: true})
+		if details := fulfilmentDetails(r); details != "" {
+			add(receiptRow{Left: details})
+		}
+	}
+	add(receiptRow{Left: r.PlacedAt.Format("2006-01-02 15:04")})
+	if r.Customer != "" {
+		add(receiptRow{Left: "Customer: " + r.Customer})
+	}
+	add(rule)
+
+	for _, line := range r.Lines {
+		row := receiptRow{Left: fmt.Sprintf("%d x %s", line.Count, line.Title)}
+		if r.Kind == models.ReceiptKitchen {
+			row.Bold = true
+		} else {
+			row.Right = money(line.Total)
+		}
+		add(row)
+		for _, adj := range line.Adjustments {
+			add(receiptRow{Left: "   " + adj})
+		}
+		for _, component := range line.Components {
+			add(receiptRow{Left: "   - " + component})
+		}
+	}
+	add(rule)
+
+	if r.Kind == models.ReceiptKitchen {
+		return rows
+	}
+
+	add(receiptRow{Left: "Subtotal", Right: money(r.Subtotal)})
+	if r.Tax > 0 {
+		add(receiptRow{Left: fmt.Sprintf("Tax %g%%", r.TaxPct), Right: money(r.Tax)})
+	}
+	if r.DeliveryFee > 0 {
+		add(receiptRow{Left: "Delivery", Right: money(r.DeliveryFee)})
+	}
+	add(receiptRow{Left: "TOTAL", Right: money(r.Total), Bold: true})
+	add(rule)
+	if r.Paid {
+		add(receiptRow{Left: "Paid", Right: money(r.Total)})
+	} else {
+		add(receiptRow{Left: "Amount due", Right: money(r.Total), Bold: true})
+	}
+	add(receiptRow{})
+	add(receiptRow{Left: "Thank you!", Center: true})
+	return rows
+}
+
+// fulfilment is the channel with where or when the order goes, for the
+// kitchen ticket.
+func fulfilment(r *models.Receipt) string {
+	label := channelLabels[r.Channel]
+	if details := fulfilmentDetails(r); details != "" {
+		label += " - " + details
+	}
+	return label
+}
+
+func fulfilmentDetails(r *models.Receipt) string {
+	switch {
+	case r.TableNumber != nil:
+		return fmt.Sprintf("Table %d", *r.TableNumber)
+	case r.PickupAt != nil:
+		return "Pickup " + r.PickupAt.In(time.Local).Format("15:04")
+	case r.DeliveryAddress != nil:
+		return "Deliver to " + *r.DeliveryAddress
+	}
+	return ""
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// textLines lays a row out on lines of the given width, wrapping long text
+// and keeping the amount on the first line.
+func (row receiptRow) textLines(columns int) []string {
+	if row.Rule {
+		return []string{strings.Repeat("-", columns)}
+	}
+	if row.Left == "" && row.Right == "" {
+		return []string{""}
+	}
+
+	width := columns
+	if row.Right != "" {
+		width -= utf8.RuneCountInString(row.Right) + 1
+	}
+	var lines []string
+	for i, text := range wrap(row.Left, width) {
+		switch {
+		case row.Center:
+			pad := (columns - utf8.RuneCountInString(text)) / 2
+			text = strings.Repeat(" ", max(pad, 0)) + text
+		case i == 0 && row.Right != "":
+			pad := columns - utf8.RuneCountInString(text) - utf8.RuneCountInString(row.Right)
+			text += strings.Repeat(" ", max(pad, 1)) + row.Right
+		}
+		lines = append(lines, strings.TrimRight(text, " "))
+	}
+	return lines
+}
+
+// wrap breaks text into lines of at most width characters, between words
+// where it can. Leading spaces indent every line.
+func wrap(text string, width int) []string {
+	trimmed := strings.TrimLeft(text, " ")
+	indent := text[:len(text)-len(trimmed)]
+	width -= len(indent)
+	if width < 1 {
+		width = 1
+	}
+
+	var (
+		lines []string
+		line  []rune
+	)
+	flush := func() {
+		lines = append(lines, indent+string(line))
+		line = line[:0]
+	}
+	for _, word := range strings.Fields(trimmed) {
+		runes := []rune(word)
+		if len(line) > 0 && len(line)+1+len(runes) > width {
+			flush()
+		}
+		for len(runes) > width {
+			if len(line) > 0 {
+				flush()
+			}
+			line = append(line, runes[:width]...)
+			flush()
+			runes = runes[width:]
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, runes...)
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		flush()
+	}
+	return lines
+}
+
+func renderReceiptText(r *models.Receipt, paper int) []byte {
+	var buf bytes.Buffer
+	for _, row := range layoutReceipt(r) {
+		for _, line := range row.textLines(receiptColumns(paper)) {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+// ESC/POS commands understood by common thermal printers.
+var (
+	escInit        = []byte{0x1b, '@'}
+	escAlignLeft   = []byte{0x1b, 'a', 0}
+	escAlignCenter = []byte{0x1b, 'a', 1}
+	escBoldOn      = []byte{0x1b, 'E', 1}
+	escBoldOff     = []byte{0x1b, 'E', 0}
+	escSizeDouble  = []byte{0x1d, '!', 0x11}
+	escSizeNormal  = []byte{0x1d, '!', 0}
+	escFeedAndCut  = []byte{0x1d, 'V', 'A', 3}
+)
+
+// renderReceiptESCPOS writes the receipt as a byte stream for an ESC/POS
+// printer, ending with a paper cut. Text is folded to ASCII, which every
+// code page prints the same.
+func renderReceiptESCPOS(r *models.Receipt, paper int) []byte {
+	var buf bytes.Buffer
+	buf.Write(escInit)
+
+	for _, row := range layoutReceipt(r) {
+		columns := receiptColumns(paper)
+		if row.Large {
+			// double width halves the characters per line
+			columns /= 2
+			buf.Write(escSizeDouble)
+		}
+		if row.Bold {
+			buf.Write(escBoldOn)
+		}
+		if row.Center {
+			// the printer centers the text itself
+			buf.Write(escAlignCenter)
+			row.Center = false
+		}
+
+		for _, line := range row.textLines(columns) {
+			buf.WriteString(toASCII(line))
+			buf.WriteByte('\n')
+		}
+
+		buf.Write(escAlignLeft)
+		buf.Write(escBoldOff)
+		buf.Write(escSizeNormal)
+	}
+
+	buf.Write(escFeedAndCut)
+	return buf.Bytes()
+}
+
+var asciiFolder = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a",
+	"è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ß", "ss",
+	"À", "A", "Á", "A", "Â", "A", "Ã", "A", "Ä", "A", "Å", "A",
+	"È", "E", "É", "E", "Ê", "E", "Ë", "E",
+	"Ì", "I", "Í", "I", "Î", "I", "Ï", "I",
+	"Ò", "O", "Ó", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ù", "U", "Ú", "U", "Û", "U", "Ü", "U",
+	"Ñ", "N", "Ç", "C",
+)
+
+// toASCII folds accented letters to their base letter and replaces any other
+// character outside ASCII with "?".
+func toASCII(s string) string {
+	s = asciiFolder.Replace(s)
+	return strings.Map(func(r rune) rune {
+		if r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+var receiptTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Order #{{.OrderID}}</title>
+<style>
+body { margin: 0; }
+.receipt { width: {{.Width}}mm; padding: 2mm; font: 12px/1.4 monospace; }
+.row { display: flex; justify-content: space-between; gap: 1em; white-space: pre-wrap; }
+.center { justify-content: center; text-align: center; }
+.bold { font-weight: bold; }
+.large { font-size: 200%; }
+hr { border: none; border-top: 1px dashed #000; }
+</style>
+</head>
+<body>
+<div class="receipt">
+{{range .Rows}}{{if .Rule}}<hr>
+{{else}}<div class="row{{if .Center}} center{{end}}{{if .Bold}} bold{{end}}{{if .Large}} large{{end}}"><span>{{.Left}}</span>{{if .Right}}<span>{{.Right}}</span>{{end}}</div>
+{{end}}{{end}}</div>
+</body>
+</html>
+`))
+
+// renderReceiptHTML renders the receipt as a page sized to the paper, for
+// printing from a browser.
+func renderReceiptHTML(r *models.Receipt, paper int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := receiptTemplate.Execute(&buf, struct {
+		OrderID string
+		Width   int
+		Rows    []receiptRow
+	}{r.OrderID, paper, layoutReceipt(r)})
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return buf.Bytes(), nil
+}
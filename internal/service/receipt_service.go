@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+
+	"github.com/lib/pq"
+)
+
+// ReceiptService renders orders for the thermal printers: the customer
+// receipt and the kitchen ticket, as plain text, HTML, an ESC/POS byte stream
+// or JSON.
+type ReceiptService interface {
+	GetReceipt(ctx context.Context, orderID, kind, format string, paper int) ([]byte, error)
+}
+
+type receiptService struct {
+	Repo *repo.Container
+	shop models.ShopInfo
+}
+
+func NewReceiptService(r *repo.Container, shop models.ShopInfo) ReceiptService {
+	return &receiptService{
+		Repo: r,
+		shop: shop,
+	}
+}
+
+func (s *receiptService) GetReceipt(ctx context.Context, orderID, kind, format string, paper int) ([]byte, error) {
+	if kind != models.ReceiptCustomer && kind != models.ReceiptKitchen {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("kind must be receipt or kitchen"))
+	}
+	if paper != models.Paper58mm && paper != models.Paper80mm {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("paper must be 58 or 80"))
+	}
+	if !slices.Contains([]string{FormatText, FormatHTML, FormatESCPOS, FormatJSON}, format) {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("format must be text, html, escpos or json"))
+	}
+
+	receipt, err := s.buildReceipt(ctx, orderID, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatText:
+		return renderReceiptText(receipt, paper), nil
+	case FormatHTML:
+		return renderReceiptHTML(receipt, paper)
+	case FormatESCPOS:
+		return renderReceiptESCPOS(receipt, paper), nil
+	default:
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return nil, models.NewError(models.ErrInternal, err)
+		}
+		return data, nil
+	}
+}
+
+func (s *receiptService) buildReceipt(ctx context.Context, orderID, kind string) (*models.Receipt, error) {
+	order, err := s.Repo.OrderRepo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, strconv.ErrSyntax) || errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+			return nil, models.NewError(models.ErrNotFound, errors.New("order not found"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	titles, err := s.itemTitles(ctx, order)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	receipt := &models.Receipt{
+		Kind:            kind,
+		Shop:            s.shop,
+		OrderID:         order.PurchaseID,
+		Status:          order.Status,
+		Channel:         order.Channel,
+		TableNumber:     order.TableNumber,
+		DeliveryAddress: order.DeliveryAddress,
+		PickupAt:        order.PickupAt,
+		Paid:            order.Status == "completed",
+	}
+	if order.Created != nil {
+		receipt.PlacedAt = *order.Created
+	}
+	if customer, err := s.Repo.CustomerRepo.GetCustomerByID(ctx, order.CustomerID); err == nil {
+		receipt.Customer = customer.Name
+	}
+
+	for _, pos := range order.Positions {
+		line := &models.ReceiptLine{
+			Title:       titles[pos.ItemID],
+			Count:       pos.Count,
+			UnitPrice:   pos.UnitPrice,
+			Total:       roundFloat(pos.UnitPrice*float64(pos.Count), 2),
+			Adjustments: describeAdjustments(pos.Adjustments),
+		}
+		for _, sel := range pos.Selections {
+			component := titles[sel.ItemID]
+			if sel.Count > 1 {
+				component = fmt.Sprintf("%d x %s", sel.Count, component)
+			}
+			line.Components = append(line.Components, component)
+		}
+		receipt.Lines = append(receipt.Lines, line)
+	}
+
+	if order.Amount != nil {
+		receipt.Total = *order.Amount
+	}
+	if order.Subtotal != nil {
+		receipt.Subtotal = *order.Subtotal
+	}
+	if order.Tax != nil {
+		receipt.Tax = *order.Tax
+	}
+	if order.DeliveryFee != nil {
+		receipt.DeliveryFee = *order.DeliveryFee
+	}
+	if receipt.Subtotal > 0 {
+		receipt.TaxPct = math.Round(receipt.Tax/receipt.Subtotal*1000) / 10
+	}
+	return receipt, nil
+}
+
+// itemTitles names the items of an order and of its bundles. Items since
+// removed from the menu keep their id.
+func (s *receiptService) itemTitles(ctx context.Context, order *models.Purchase) (map[string]string, error) {
+	var ids []string
+	for _, pos := range order.Positions {
+		ids = append(ids, pos.ItemID)
+		for _, sel := range pos.Selections {
+			ids = append(ids, sel.ItemID)
+		}
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	titles := make(map[string]string, len(ids))
+	for _, id := range ids {
+		titles[id] = "Item #" + id
+	}
+	if len(ids) == 0 {
+		return titles, nil
+	}
+
+	products, err := s.Repo.MenuRepo.FetchProductsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		titles[p.ProductID] = p.Title
+	}
+	return titles, nil
+}
+
+// describeAdjustments lists the adjustments of a line in a stable order:
+// a flag by its name ("extra_shot" as "extra shot"), anything else as
+// "name: value". Flags turned off are left out.
+func describeAdjustments(adjustments models.ConfigMap) []string {
+	keys := make([]string, 0, len(adjustments))
+	for key := range adjustments {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var lines []string
+	for _, key := range keys {
+		name := strings.ReplaceAll(key, "_", " ")
+		switch v := adjustments[key].(type) {
+		case bool:
+			if v {
+				lines = append(lines, name)
+			}
+		case nil:
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %v", name, v))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"frappuccino/internal/models"
+)
+
+var errRecipeCycle = errors.New("recipe graph contains a cycle")
+
+// recipeGraph indexes every inventory item by id. Prepared items point to the
+// items of their batch recipe, which may in turn be prepared.
+type recipeGraph map[string]*models.RecipeNode
+
+// unitCost prices one unit of an item by walking the recipe graph down to
+// raw ingredients: a prepared item costs its recipe divided by its yield.
+func (g recipeGraph) unitCost(id string) (float64, error) {
+	return g.walkCost(id, map[string]float64{}, map[string]bool{})
+}
+
+func (g recipeGraph) walkCost(id string, memo map[string]float64, visiting map[string]bool) (float64, error) {
+	if cost, ok := memo[id]; ok {
+		return cost, nil
+	}
+	node, ok := g[id]
+	if !ok {
+		return 0, fmt.Errorf("inventory item %s not found", id)
+	}
+	if !node.Prepared || node.Yield <= 0 {
+		return node.UnitCost, nil
+	}
+	if visiting[id] {
+		return 0, errRecipeCycle
+	}
+
+	visiting[id] = true
+	var batch float64
+	for _, line := range node.Recipe {
+		cost, err := g.walkCost(line.ComponentID, memo, visiting)
+		if err != nil {
+			return 0, err
+		}
+		batch += line.RequiredQty * cost
+	}
+	delete(visiting, id)
+
+	memo[id] = batch / node.Yield
+	return memo[id], nil
+}
+
+// batchCost prices one unit of a batch produced now. Production consumes the
+// ingredients on hand, so each is taken at its current stock price.
+func (g recipeGraph) batchCost(node *models.RecipeNode) float64 {
+	var batch float64
+	for _, line := range node.Recipe {
+		if child, ok := g[line.ComponentID]; ok {
+			batch += line.RequiredQty * child.UnitCost
+		}
+	}
+	return batch / node.Yield
+}
+
+// maxProducible is the quantity of a prepared item the free stock of its
+// direct ingredients allows to produce in whole batches.
+func (g recipeGraph) maxProducible(node *models.RecipeNode) float64 {
+	batches := -1.0
+	for _, line := range node.Recipe {
+		child, ok := g[line.ComponentID]
+		if !ok || line.RequiredQty <= 0 {
+			continue
+		}
+		n := wholeUnits(child.Available, line.RequiredQty)
+		if n < 0 {
+			n = 0
+		}
+		if batches < 0 || n < batches {
+			batches = n
+		}
+	}
+	if batches < 0 {
+		return 0
+	}
+	return batches * node.Yield
+}
+
+// checkCycle reports whether any item reachable from id leads back to it.
+func (g recipeGraph) checkCycle(id string) error {
+	state := map[string]int{}
+
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case 1:
+			return errRecipeCycle
+		case 2:
+			return nil
+		}
+		state[id] = 1
+		if node, ok := g[id]; ok && node.Prepared {
+			for _, line := range node.Recipe {
+				if err := visit(line.ComponentID); err != nil {
+					return err
+				}
+			}
+		}
+		state[id] = 2
+		return nil
+	}
+
+	return visit(id)
+}
+
+// allergens collects the allergen flags of an item, including everything its
+// recipe contains when it is prepared.
+func (g recipeGraph) allergens(id string) ([]string, error) {
+	found := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var walk func(id string) error
+	walk = func(id string) error {
+		node, ok := g[id]
+		if !ok {
+			return fmt.Errorf("inventory item %s not found", id)
+		}
+		for _, a := range node.Allergens {
+			found[a] = true
+		}
+		if !node.Prepared {
+			return nil
+		}
+		if visiting[id] {
+			return errRecipeCycle
+		}
+		visiting[id] = true
+		for _, line := range node.Recipe {
+			if err := walk(line.ComponentID); err != nil {
+				return err
+			}
+		}
+		delete(visiting, id)
+		return nil
+	}
+
+	if err := walk(id); err != nil {
+		return nil, err
+	}
+	return sortedKeys(found), nil
+}
+
+// nutrition returns the nutrition of one unit of an item. A prepared item
+// without values of its own is worked out from its recipe divided by its
+// yield. Nil means some ingredient on the way has no nutrition recorded.
+func (g recipeGraph) nutrition(id string) (*models.Nutrition, error) {
+	return g.walkNutrition(id, map[string]bool{})
+}
+
+func (g recipeGraph) walkNutrition(id string, visiting map[string]bool) (*models.Nutrition, error) {
+	node, ok := g[id]
+	if !ok {
+		return nil, fmt.Errorf("inventory item %s not found", id)
+	}
+	if node.Nutrition != nil || !node.Prepared || node.Yield <= 0 {
+		return node.Nutrition, nil
+	}
+	if visiting[id] {
+		return nil, errRecipeCycle
+	}
+
+	visiting[id] = true
+	batch := &models.Nutrition{}
+	for _, line := range node.Recipe {
+		n, err := g.walkNutrition(line.ComponentID, visiting)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		batch.Add(n, line.RequiredQty)
+	}
+	delete(visiting, id)
+
+	unit := &models.Nutrition{}
+	unit.Add(batch, 1/node.Yield)
+	return unit, nil
+}
+
+// applyDietary sets the allergens and the nutrition per portion of the
+// products from their components and the modifiers named in selected, and
+// those of each modifier from what it adds. A selected modifier replacing a
+// component takes its place. Bundles are left to applyBundleAllergens.
+func (g recipeGraph) applyDietary(products []*models.Product, selected []string) error {
+	for _, p := range products {
+		if p.Bundle {
+			continue
+		}
+
+		var (
+			portion  []*models.ProductComponent
+			replaced = map[string]bool{}
+		)
+		for _, mod := range p.Modifiers {
+			added := []*models.ProductComponent{{ComponentID: mod.ComponentID, RequiredQty: mod.Quantity}}
+			var err error
+			if mod.Allergens, mod.Nutrition, err = g.dietary(added); err != nil {
+				return err
+			}
+
+			mod.Selected = slices.ContainsFunc(selected, func(name string) bool {
+				return strings.EqualFold(strings.TrimSpace(name), mod.Name)
+			})
+			if mod.Selected {
+				portion = append(portion, added...)
+				if mod.ReplacesID != nil {
+					replaced[*mod.ReplacesID] = true
+				}
+			}
+		}
+		for _, comp := range p.Components {
+			if !replaced[comp.ComponentID] {
+				portion = append(portion, comp)
+			}
+		}
+
+		var err error
+		if p.Allergens, p.Nutrition, err = g.dietary(portion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dietary adds up the allergens and nutrition of the given quantities of
+// inventory items. The nutrition is nil when any of them has none recorded.
+func (g recipeGraph) dietary(lines []*models.ProductComponent) ([]string, *models.Nutrition, error) {
+	found := map[string]bool{}
+	total := &models.Nutrition{}
+	for _, line := range lines {
+		allergens, err := g.allergens(line.ComponentID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, a := range allergens {
+			found[a] = true
+		}
+
+		n, err := g.nutrition(line.ComponentID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n == nil {
+			total = nil
+		} else if total != nil {
+			total.Add(n, line.RequiredQty)
+		}
+	}
+
+	if total != nil {
+		roundNutrition(total)
+	}
+	return sortedKeys(found), total, nil
+}
+
+func roundNutrition(n *models.Nutrition) {
+	n.Calories = roundFloat(n.Calories, 0)
+	n.Protein = roundFloat(n.Protein, 1)
+	n.Fat = roundFloat(n.Fat, 1)
+	n.Carbs = roundFloat(n.Carbs, 1)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// costPreparedComponents replaces the stock price of prepared components
+// with their recipe cost, so product costing follows the graph down to raw
+// ingredients.
+func (g recipeGraph) costPreparedComponents(products []*models.Product) error {
+	for _, p := range products {
+		for _, comp := range p.Components {
+			if node, ok := g[comp.ComponentID]; !ok || !node.Prepared {
+				continue
+			}
+			cost, err := g.unitCost(comp.ComponentID)
+			if err != nil {
+				return err
+			}
+			comp.UnitCost = cost
+		}
+	}
+	return nil
+}
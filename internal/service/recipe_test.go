@@ -0,0 +1,134 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"frappuccino/internal/models"
+)
+
+func TestMaxProducible(t *testing.T) {
+	graph := recipeGraph{
+		"1": {ItemRef: "1", Available: 0.6},
+		"2": {ItemRef: "2", Available: 0.3},
+		"3": {ItemRef: "3", Available: 5},
+		"4": {ItemRef: "4", Available: -1},
+	}
+
+	tests := []struct {
+		name   string
+		recipe []*models.ProductComponent
+		yield  float64
+		want   float64
+	}{
+		{
+			name:   "float quotient just under a whole batch",
+			recipe: []*models.ProductComponent{{ComponentID: "1", RequiredQty: 0.2}},
+			yield:  1,
+			want:   3,
+		},
+		{
+			name: "exact last batch",
+			recipe: []*models.ProductComponent{
+				{ComponentID: "2", RequiredQty: 0.1},
+				{ComponentID: "3", RequiredQty: 1},
+			},
+			yield: 2.5,
+			want:  7.5,
+		},
+		{
+			name:   "partial batch is not counted",
+			recipe: []*models.ProductComponent{{ComponentID: "3", RequiredQty: 2}},
+			yield:  1,
+			want:   2,
+		},
+		{
+			name:   "overcommitted ingredient",
+			recipe: []*models.ProductComponent{{ComponentID: "4", RequiredQty: 1}},
+			yield:  1,
+			want:   0,
+		},
+		{
+			name:   "no recipe",
+			recipe: nil,
+			yield:  1,
+			want:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &models.RecipeNode{Prepared: true, Yield: tt.yield, Recipe: tt.recipe}
+			if got := graph.maxProducible(node); got != tt.want {
+				t.Errorf("maxProducible() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDietaryModifiers(t *testing.T) {
+	graph := recipeGraph{
+		"1": {ItemRef: "1", Nutrition: &models.Nutrition{Calories: 100}},
+		"3": {ItemRef: "3", Allergens: []string{"dairy"}, Nutrition: &models.Nutrition{Calories: 600}},
+		"9": {ItemRef: "9", Allergens: []string{"gluten"}, Nutrition: &models.Nutrition{Calories: 450}},
+		"5": {ItemRef: "5"},
+	}
+	milk := "3"
+	latte := func() *models.Product {
+		return &models.Product{
+			Components: []*models.ProductComponent{
+				{ComponentID: "1", RequiredQty: 0.018},
+				{ComponentID: "3", RequiredQty: 0.2},
+			},
+			Modifiers: []*models.Modifier{
+				{Name: "Oat milk", ComponentID: "9", Quantity: 0.2, ReplacesID: &milk},
+				{Name: "Extra shot", ComponentID: "1", Quantity: 0.009},
+				{Name: "Syrup", ComponentID: "5", Quantity: 0.02},
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		selected      []string
+		wantAllergens []string
+		wantCalories  *float64
+	}{
+		{name: "recipe only", wantAllergens: []string{"dairy"}, wantCalories: ptr(122)},
+		{name: "replacing a component", selected: []string{"OAT MILK"}, wantAllergens: []string{"gluten"}, wantCalories: ptr(92)},
+		{name: "replacing and adding", selected: []string{"oat milk", "extra shot"}, wantAllergens: []string{"gluten"}, wantCalories: ptr(93)},
+		{name: "modifier without nutrition", selected: []string{"syrup"}, wantAllergens: []string{"dairy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := latte()
+			if err := graph.applyDietary([]*models.Product{p}, tt.selected); err != nil {
+				t.Fatalf("applyDietary() error = %v", err)
+			}
+			if !slices.Equal(p.Allergens, tt.wantAllergens) {
+				t.Errorf("allergens = %v, want %v", p.Allergens, tt.wantAllergens)
+			}
+			switch {
+			case tt.wantCalories == nil && p.Nutrition != nil:
+				t.Errorf("nutrition = %+v, want none", p.Nutrition)
+			case tt.wantCalories != nil && (p.Nutrition == nil || p.Nutrition.Calories != *tt.wantCalories):
+				t.Errorf("nutrition = %+v, want %v calories", p.Nutrition, *tt.wantCalories)
+			}
+		})
+	}
+
+	p := latte()
+	if err := graph.applyDietary([]*models.Product{p}, []string{"oat milk"}); err != nil {
+		t.Fatalf("applyDietary() error = %v", err)
+	}
+	oat := p.Modifiers[0]
+	if !oat.Selected || !slices.Equal(oat.Allergens, []string{"gluten"}) || oat.Nutrition == nil || oat.Nutrition.Calories != 90 {
+		t.Errorf("oat milk modifier = %+v, want selected with gluten and 90 calories", oat)
+	}
+	if p.Modifiers[1].Selected {
+		t.Error("extra shot marked as selected")
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
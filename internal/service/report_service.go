@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+)
+
+type ReportService interface {
+	GetInventoryValuation(ctx context.Context, asOf time.Time, method string) (*models.InventoryValuation, error)
+	GetMenuEngineering(ctx context.Context, from, to time.Time) (*models.MenuEngineeringReport, error)
+	GetProfitReport(ctx context.Context, from, to time.Time, groupBy string) (*models.ProfitReport, error)
+	GetPriceImpact(ctx context.Context, from, to time.Time, windowDays int) (*models.PriceImpactReport, error)
+}
+
+type reportService struct {
+	Repo *repo.Container
+}
+
+func NewReportService(r *repo.Container) ReportService {
+	return &reportService{
+		Repo: r,
+	}
+}
+
+func (s *reportService) GetInventoryValuation(ctx context.Context, asOf time.Time, method string) (*models.InventoryValuation, error) {
+	if method == "" {
+		method = models.CostingWeightedAverage
+	}
+	if method != models.CostingFIFO && method != models.CostingWeightedAverage {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("method must be 'fifo' or 'weighted_average'"))
+	}
+
+	inputs, err := s.Repo.ReportRepo.GetValuationInputs(ctx, asOf)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	valuation := &models.InventoryValuation{
+		AsOf:   asOf,
+		Method: method,
+		Lines:  make([]*models.ValuationLine, 0, len(inputs)),
+	}
+
+	for _, in := range inputs {
+		var line *models.ValuationLine
+		if method == models.CostingFIFO {
+			line = valueFIFO(in)
+		} else {
+			line = valueWeightedAverage(in)
+		}
+		valuation.TotalValue += line.Value
+		valuation.Lines = append(valuation.Lines, line)
+	}
+
+	valuation.ItemCount = len(valuation.Lines)
+	valuation.TotalValue = roundFloat(valuation.TotalValue, 2)
+	return valuation, nil
+}
+
+// GetMenuEngineering classifies menu items on the popularity/profitability
+// matrix. An item is popular when its share of units sold reaches 70% of the
+// even share (1/N), and profitable when its unit margin is at least the
+// sales-weighted average margin of the menu.
+func (s *reportService) GetMenuEngineering(ctx context.Context, from, to time.Time) (*models.MenuEngineeringReport, error) {
+	if to.Before(from) {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("end date is before start date"))
+	}
+
+	sales, err := s.Repo.ReportRepo.GetMenuSales(ctx, from, to)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	products, err := s.Repo.MenuRepo.GetAllProducts(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	graph, err := s.Repo.InventoryRepo.GetRecipeGraph(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	if err := recipeGraph(graph).costPreparedComponents(products); err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	costs := make(map[string]float64, len(products))
+	for _, p := range products {
+		costs[p.ProductID] = recipeCost(p)
+	}
+
+	report := &models.MenuEngineeringReport{
+		From:  from,
+		To:    to,
+		Items: make([]*models.MenuEngineeringLine, 0, len(sales)),
+	}
+
+	for _, item := range sales {
+		// price at which the item actually sold, list price if it did not
+		price := item.Price
+		if item.Sold > 0 {
+			price = item.Revenue / item.Sold
+		}
+
+		line := &models.MenuEngineeringLine{
+			ItemRef:  item.ItemRef,
+			Title:    item.Title,
+			Group:    item.Group,
+			Sold:     item.Sold,
+			Revenue:  roundFloat(item.Revenue, 2),
+			UnitCost: costs[item.ItemRef],
+		}
+		line.UnitMargin = roundFloat(price-line.UnitCost, 2)
+		line.TotalMargin = roundFloat(line.UnitMargin*item.Sold, 2)
+		report.Items = append(report.Items, line)
+	}
+
+	classifyMenuItems(report)
+	return report, nil
+}
+
+// classifyMenuItems sets the thresholds of the report and the class of each
+// of its items from their units sold and margins.
+func classifyMenuItems(report *models.MenuEngineeringReport) {
+	var totalMargin float64
+	report.TotalSold = 0
+	for _, line := range report.Items {
+		report.TotalSold += line.Sold
+		totalMargin += line.TotalMargin
+	}
+
+	if n := len(report.Items); n > 0 {
+		report.PopularityThreshold = roundFloat(0.7/float64(n)*100, 2)
+	}
+	if report.TotalSold > 0 {
+		report.MarginThreshold = roundFloat(totalMargin/report.TotalSold, 2)
+	}
+
+	for _, line := range report.Items {
+		if report.TotalSold > 0 {
+			line.Popularity = roundFloat(line.Sold/report.TotalSold*100, 2)
+		}
+
+		popular := report.TotalSold > 0 && line.Popularity >= report.PopularityThreshold
+		profitable := line.UnitMargin >= report.MarginThreshold
+
+		switch {
+		case popular && profitable:
+			line.Class = models.ClassStar
+		case popular:
+			line.Class = models.ClassPlowhorse
+		case profitable:
+			line.Class = models.ClassPuzzle
+		default:
+			line.Class = models.ClassDog
+		}
+	}
+}
+
+func (s *reportService) GetProfitReport(ctx context.Context, from, to time.Time, groupBy string) (*models.ProfitReport, error) {
+	if groupBy == "" {
+		groupBy = "day"
+	}
+	if groupBy != "day" && groupBy != "week" && groupBy != "month" {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("group_by must be 'day', 'week' or 'month'"))
+	}
+	if to.Before(from) {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("end date is before start date"))
+	}
+
+	periods, err := s.Repo.ReportRepo.GetProfitByPeriod(ctx, from, to, groupBy)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	categories, err := s.Repo.ReportRepo.GetProfitByCategory(ctx, from, to)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	report := &models.ProfitReport{
+		From:       from,
+		To:         to,
+		GroupBy:    groupBy,
+		Periods:    make([]*models.ProfitLine, 0, len(periods)),
+		Categories: make([]*models.ProfitLine, 0, len(categories)),
+	}
+
+	for _, line := range periods {
+		report.Total.Orders += line.Orders
+		report.Total.Revenue += line.Revenue
+		report.Total.COGS += line.COGS
+		report.Total.WasteCost += line.WasteCost
+		line.Finalize()
+		report.Periods = append(report.Periods, line)
+	}
+
+	for _, line := range categories {
+		line.Finalize()
+		report.Categories = append(report.Categories, line)
+	}
+
+	report.Total.Finalize()
+	return report, nil
+}
+
+// GetPriceImpact compares the daily sales of an item over windowDays before
+// and after each of its price changes.
+func (s *reportService) GetPriceImpact(ctx context.Context, from, to time.Time, windowDays int) (*models.PriceImpactReport, error) {
+	if windowDays == 0 {
+		windowDays = 14
+	}
+	if windowDays < 1 || windowDays > 90 {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("window_days must be between 1 and 90"))
+	}
+	if to.Before(from) {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("end date is before start date"))
+	}
+
+	lines, err := s.Repo.ReportRepo.GetPriceChangeSales(ctx, from, to, windowDays)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	report := &models.PriceImpactReport{
+		From:       from,
+		To:         to,
+		WindowDays: windowDays,
+		Changes:    make([]*models.PriceImpactLine, 0, len(lines)),
+	}
+
+	window := time.Duration(windowDays) * 24 * time.Hour
+	now := time.Now()
+
+	for i, line := range lines {
+		line.Before.Days = float64(windowDays)
+		line.After.Days = float64(windowDays)
+		if elapsed := now.Sub(line.ChangedAt); elapsed < window {
+			line.After.Days = roundFloat(elapsed.Hours()/24, 2)
+		}
+		finalizeImpactPeriod(&line.Before)
+		finalizeImpactPeriod(&line.After)
+
+		if line.OldPrice > 0 {
+			line.PriceChangePct = roundFloat((line.NewPrice-line.OldPrice)/line.OldPrice*100, 2)
+		}
+		line.UnitsChangePct = changePct(line.Before.UnitsPerDay, line.After.UnitsPerDay)
+		line.RevenueChangePct = changePct(line.Before.RevenuePerDay, line.After.RevenuePerDay)
+		line.Elasticity = arcElasticity(line.OldPrice, line.NewPrice, line.Before.UnitsPerDay, line.After.UnitsPerDay)
+
+		for j, other := range lines {
+			if j == i || other.ItemRef != line.ItemRef {
+				continue
+			}
+			if d := other.ChangedAt.Sub(line.ChangedAt); d > -window && d < window {
+				line.OverlappingChange = true
+				break
+			}
+		}
+
+		report.Changes = append(report.Changes, line)
+	}
+
+	return report, nil
+}
+
+func finalizeImpactPeriod(p *models.PriceImpactPeriod) {
+	p.Revenue = roundFloat(p.Revenue, 2)
+	if p.Days > 0 {
+		p.UnitsPerDay = roundFloat(p.Units/p.Days, 2)
+		p.RevenuePerDay = roundFloat(p.Revenue/p.Days, 2)
+	}
+}
+
+func changePct(before, after float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	pct := roundFloat((after-before)/before*100, 2)
+	return &pct
+}
+
+// arcElasticity uses midpoints so that a rise and the matching cut give the
+// same figure. It is undefined when nothing sold on either side.
+func arcElasticity(oldPrice, newPrice, oldQty, newQty float64) *float64 {
+	if oldPrice+newPrice == 0 || oldQty+newQty == 0 || oldPrice == newPrice {
+		return nil
+	}
+	dq := (newQty - oldQty) / ((newQty + oldQty) / 2)
+	dp := (newPrice - oldPrice) / ((newPrice + oldPrice) / 2)
+	e := roundFloat(dq/dp, 2)
+	return &e
+}
+
+// valueWeightedAverage prices the stock on hand at a running average cost.
+// Each receipt blends its cost with the stock already on hand; stock going
+// out leaves the average as it is.
+func valueWeightedAverage(in *models.ValuationInput) *models.ValuationLine {
+	var onHand float64
+	unitCost := in.CurrentCost
+	for _, m := range in.Movements {
+		if m.Quantity > 0 {
+			if onHand > 0 {
+				unitCost = (onHand*unitCost + m.Quantity*m.UnitCost) / (onHand + m.Quantity)
+			} else {
+				unitCost = m.UnitCost
+			}
+		}
+		onHand += m.Quantity
+	}
+
+	return &models.ValuationLine{
+		ItemRef:  in.ItemRef,
+		Title:    in.Title,
+		Measure:  in.Measure,
+		Quantity: roundFloat(in.Quantity, 4),
+		UnitCost: roundFloat(unitCost, 4),
+		Value:    roundFloat(in.Quantity*unitCost, 2),
+	}
+}
+
+// valueFIFO assumes the oldest stock is consumed first, so whatever is still
+// on hand comes from the most recent receipts. Stock that cannot be matched
+// to a receipt is priced at the oldest known cost.
+func valueFIFO(in *models.ValuationInput) *models.ValuationLine {
+	line := &models.ValuationLine{
+		ItemRef:  in.ItemRef,
+		Title:    in.Title,
+		Measure:  in.Measure,
+		Quantity: roundFloat(in.Quantity, 4),
+		Layers:   []*models.CostLayer{},
+	}
+
+	if in.Quantity <= 0 {
+		line.UnitCost = in.CurrentCost
+		line.Value = roundFloat(in.Quantity*in.CurrentCost, 2)
+		return line
+	}
+
+	var receipts []*models.CostLayer
+	for _, m := range in.Movements {
+		if m.Quantity > 0 {
+			receipts = append(receipts, m)
+		}
+	}
+
+	remaining := in.Quantity
+	for i := len(receipts) - 1; i >= 0 && remaining > 0; i-- {
+		r := receipts[i]
+		qty := r.Quantity
+		if qty > remaining {
+			qty = remaining
+		}
+		line.Layers = append([]*models.CostLayer{{
+			Quantity: roundFloat(qty, 4),
+			UnitCost: r.UnitCost,
+			Received: r.Received,
+		}}, line.Layers...)
+		line.Value += qty * r.UnitCost
+		remaining -= qty
+	}
+
+	if remaining > 0 {
+		cost := in.CurrentCost
+		if len(receipts) > 0 {
+			cost = receipts[0].UnitCost
+		}
+		line.Value += remaining * cost
+	}
+
+	line.UnitCost = roundFloat(line.Value/in.Quantity, 4)
+	line.Value = roundFloat(line.Value, 2)
+	return line
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"frappuccino/internal/models"
+)
+
+func TestValueWeightedAverage(t *testing.T) {
+	tests := []struct {
+		name      string
+		in        *models.ValuationInput
+		wantCost  float64
+		wantValue float64
+	}{
+		{
+			name:      "no movements uses the current cost",
+			in:        &models.ValuationInput{Quantity: 4, CurrentCost: 2.5},
+			wantCost:  2.5,
+			wantValue: 10,
+		},
+		{
+			name: "receipts blend by quantity",
+			in: &models.ValuationInput{Quantity: 20, Movements: []*models.CostLayer{
+				{Quantity: 10, UnitCost: 2},
+				{Quantity: 10, UnitCost: 4},
+			}},
+			wantCost:  3,
+			wantValue: 60,
+		},
+		{
+			name: "consumption leaves the average and weighs the next receipt against what is left",
+			in: &models.ValuationInput{Quantity: 10, Movements: []*models.CostLayer{
+				{Quantity: 10, UnitCost: 2},
+				{Quantity: -5},
+				{Quantity: 5, UnitCost: 4},
+			}},
+			wantCost:  3,
+			wantValue: 30,
+		},
+		{
+			name: "receipt after running out sets the cost",
+			in: &models.ValuationInput{Quantity: 5, Movements: []*models.CostLayer{
+				{Quantity: 10, UnitCost: 2},
+				{Quantity: -10},
+				{Quantity: 5, UnitCost: 3},
+			}},
+			wantCost:  3,
+			wantValue: 15,
+		},
+		{
+			name: "receipt after overdrawn stock sets the cost",
+			in: &models.ValuationInput{Quantity: 2, CurrentCost: 1, Movements: []*models.CostLayer{
+				{Quantity: -3},
+				{Quantity: 5, UnitCost: 4},
+			}},
+			wantCost:  4,
+			wantValue: 8,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := valueWeightedAverage(tt.in)
+			if got.UnitCost != tt.wantCost || got.Value != tt.wantValue {
+				t.Errorf("valueWeightedAverage() = cost %v value %v, want cost %v value %v", got.UnitCost, got.Value, tt.wantCost, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestValueFIFO(t *testing.T) {
+	day1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name       string
+		in         *models.ValuationInput
+		wantCost   float64
+		wantValue  float64
+		wantLayers []float64
+	}{
+		{
+			name: "on hand stock comes from the newest receipts",
+			in: &models.ValuationInput{Quantity: 15, Movements: []*models.CostLayer{
+				{Quantity: 10, UnitCost: 2, Received: day1},
+				{Quantity: 10, UnitCost: 3, Received: day2},
+				{Quantity: -5},
+			}},
+			wantCost:   2.6667,
+			wantValue:  40,
+			wantLayers: []float64{5, 10},
+		},
+		{
+			name: "stock beyond the receipts is priced at the oldest cost",
+			in: &models.ValuationInput{Quantity: 25, Movements: []*models.CostLayer{
+				{Quantity: 10, UnitCost: 2, Received: day1},
+				{Quantity: 10, UnitCost: 3, Received: day2},
+			}},
+			wantCost:   2.4,
+			wantValue:  60,
+			wantLayers: []float64{10, 10},
+		},
+		{
+			name:       "no receipts uses the current cost",
+			in:         &models.ValuationInput{Quantity: 4, CurrentCost: 1.5},
+			wantCost:   1.5,
+			wantValue:  6,
+			wantLayers: nil,
+		},
+		{
+			name: "nothing on hand",
+			in: &models.ValuationInput{Quantity: 0, CurrentCost: 2, Movements: []*models.CostLayer{
+				{Quantity: 10, UnitCost: 2, Received: day1},
+				{Quantity: -10},
+			}},
+			wantCost:   2,
+			wantValue:  0,
+			wantLayers: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := valueFIFO(tt.in)
+			if got.UnitCost != tt.wantCost || got.Value != tt.wantValue {
+				t.Errorf("valueFIFO() = cost %v value %v, want cost %v value %v", got.UnitCost, got.Value, tt.wantCost, tt.wantValue)
+			}
+			if len(got.Layers) != len(tt.wantLayers) {
+				t.Fatalf("valueFIFO() has %d layers, want %d", len(got.Layers), len(tt.wantLayers))
+			}
+			for i, layer := range got.Layers {
+				if layer.Quantity != tt.wantLayers[i] {
+					t.Errorf("layer %d quantity = %v, want %v", i, layer.Quantity, tt.wantLayers[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyMenuItems(t *testing.T) {
+	// Four items: popularity threshold is 17.5% of 100 units sold, margin
+	// threshold is 500/100 = 5 per unit.
+	report := &models.MenuEngineeringReport{Items: []*models.MenuEngineeringLine{
+		{ItemRef: "star", Sold: 40, UnitMargin: 6, TotalMargin: 240},
+		{ItemRef: "plowhorse", Sold: 40, UnitMargin: 4, TotalMargin: 160},
+		{ItemRef: "puzzle", Sold: 10, UnitMargin: 6, TotalMargin: 60},
+		{ItemRef: "dog", Sold: 10, UnitMargin: 4, TotalMargin: 40},
+	}}
+
+	classifyMenuItems(report)
+
+	if report.TotalSold != 100 || report.PopularityThreshold != 17.5 || report.MarginThreshold != 5 {
+		t.Fatalf("thresholds = sold %v popularity %v margin %v, want 100, 17.5, 5", report.TotalSold, report.PopularityThreshold, report.MarginThreshold)
+	}
+	for _, line := range report.Items {
+		if line.Class != line.ItemRef {
+			t.Errorf("item %s classified as %s", line.ItemRef, line.Class)
+		}
+	}
+}
+
+func TestClassifyMenuItemsAtThresholds(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []*models.MenuEngineeringLine
+		want  []string
+	}{
+		{
+			name: "reaching both thresholds is a star",
+			items: []*models.MenuEngineeringLine{
+				{Sold: 17.5, UnitMargin: 5, TotalMargin: 87.5},
+				{Sold: 32.5, UnitMargin: 5, TotalMargin: 162.5},
+				{Sold: 25, UnitMargin: 5, TotalMargin: 125},
+				{Sold: 25, UnitMargin: 5, TotalMargin: 125},
+			},
+			want: []string{models.ClassStar, models.ClassStar, models.ClassStar, models.ClassStar},
+		},
+		{
+			name: "nothing sold is never popular",
+			items: []*models.MenuEngineeringLine{
+				{Sold: 0, UnitMargin: 2},
+				{Sold: 0, UnitMargin: -1},
+			},
+			want: []string{models.ClassPuzzle, models.ClassDog},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classifyMenuItems(&models.MenuEngineeringReport{Items: tt.items})
+			for i, line := range tt.items {
+				if line.Class != tt.want[i] {
+					t.Errorf("item %d classified as %s, want %s", i, line.Class, tt.want[i])
+				}
+			}
+		})
+	}
+}
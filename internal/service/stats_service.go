@@ -11,10 +11,10 @@ import (
 )
 
 type StatsService interface {
-	GetPopularItem(ctx context.Context) ([]models.TopProduct, error)
-	GetTotalSum(ctx context.Context) (*models.RevenueSummary, error)
-	GetSearch(ctx context.Context, query, filter string, minPrice *float64, maxPrice *float64) (models.LookupResult, error)
-	GetItemByPeriod(ctx context.Context, period string, month string, year int) (map[string]interface{}, error)
+	GetPopularItem(ctx context.Context, channel string) ([]models.TopProduct, error)
+	GetTotalSum(ctx context.Context, channel string) (*models.RevenueSummary, error)
+	GetSearch(ctx context.Context, query, filter string, minPrice *float64, maxPrice *float64, channel string) (models.LookupResult, error)
+	GetItemByPeriod(ctx context.Context, period string, month string, year int, channel string) (map[string]interface{}, error)
 }
 
 type statsService struct {
@@ -27,25 +27,37 @@ func NewStatsService(r *repo.Container) StatsService {
 	}
 }
 
-func (m *statsService) GetPopularItem(ctx context.Context) ([]models.TopProduct, error) {
-	list, err := m.Repo.StatsRepo.GetPopularItem(ctx)
+func (m *statsService) GetPopularItem(ctx context.Context, channel string) ([]models.TopProduct, error) {
+	if err := models.ValidateChannel(channel); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	list, err := m.Repo.StatsRepo.GetPopularItem(ctx, channel)
 	if err != nil {
 		return nil, models.NewError(nil, err)
 	}
 	return list, nil
 }
 
-func (m *statsService) GetTotalSum(ctx context.Context) (*models.RevenueSummary, error) {
-	list, err := m.Repo.StatsRepo.GetTotalSum(ctx)
+func (m *statsService) GetTotalSum(ctx context.Context, channel string) (*models.RevenueSummary, error) {
+	if err := models.ValidateChannel(channel); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	list, err := m.Repo.StatsRepo.GetTotalSum(ctx, channel)
 	if err != nil {
 		return nil, models.NewError(nil, err)
 	}
 	return list, nil
 }
 
-func (m *statsService) GetSearch(ctx context.Context, query, filter string, minPrice *float64, maxPrice *float64) (models.LookupResult, error) {
+func (m *statsService) GetSearch(ctx context.Context, query, filter string, minPrice *float64, maxPrice *float64, channel string) (models.LookupResult, error) {
 	var response models.LookupResult
 
+	if err := models.ValidateChannel(channel); err != nil {
+		return response, models.NewError(models.ErrInvalidInput, err)
+	}
+
 	filters := strings.Split(filter, ",")
 	searchMenu := true
 	searchOrders := true
@@ -73,7 +85,7 @@ func (m *statsService) GetSearch(ctx context.Context, query, filter string, minP
 	}
 
 	if searchOrders {
-		orders, err := m.Repo.StatsRepo.SearchOrders(ctx, query, minPrice, maxPrice)
+		orders, err := m.Repo.StatsRepo.SearchOrders(ctx, query, minPrice, maxPrice, channel)
 		if err != nil {
 			return response, err
 		}
@@ -84,7 +96,7 @@ func (m *statsService) GetSearch(ctx context.Context, query, filter string, minP
 	return response, nil
 }
 
-func (m *statsService) GetItemByPeriod(ctx context.Context, period string, month string, year int) (map[string]interface{}, error) {
+func (m *statsService) GetItemByPeriod(ctx context.Context, period string, month string, year int, channel string) (map[string]interface{}, error) {
 	if period != "day" && period != "month" {
 		return nil, errors.New("invalid period parameter")
 	}
@@ -93,11 +105,15 @@ func (m *statsService) GetItemByPeriod(ctx context.Context, period string, month
 		return nil, errors.New("month is required when period is 'day'")
 	}
 
+	if err := models.ValidateChannel(channel); err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
 	if year == 0 {
 		year = time.Now().Year()
 	}
 
-	data, err := m.Repo.StatsRepo.GetItemByPeriod(ctx, period, month, year)
+	data, err := m.Repo.StatsRepo.GetItemByPeriod(ctx, period, month, year, channel)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +125,9 @@ func (m *statsService) GetItemByPeriod(ctx context.Context, period string, month
 	if period == "day" {
 		response["month"] = month
 	}
+	if channel != "" {
+		response["channel"] = channel
+	}
 
 	orderedItems := []map[string]int{}
 	for _, item := range data {
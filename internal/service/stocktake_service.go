@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+
+	"github.com/lib/pq"
+)
+
+type StockTakeService interface {
+	StartStockTake(ctx context.Context, st *models.StockTake) error
+	GetStockTakeByID(ctx context.Context, id string) (*models.StockTake, error)
+	SubmitCounts(ctx context.Context, id string, submission *models.CountSubmission) error
+	CommitStockTake(ctx context.Context, id string) (*models.VarianceReport, error)
+	CancelStockTake(ctx context.Context, id string) error
+	GetVarianceReport(ctx context.Context, id string) (*models.VarianceReport, error)
+}
+
+type stockTakeService struct {
+	repo *repo.Container
+}
+
+func NewStockTakeService(r *repo.Container) StockTakeService {
+	return &stockTakeService{
+		repo: r,
+	}
+}
+
+func (s *stockTakeService) StartStockTake(ctx context.Context, st *models.StockTake) error {
+	if err := s.repo.StockTakeRepo.CreateStockTake(ctx, st); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return models.NewError(models.ErrElemExist, errors.New("another stock take is already in progress"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *stockTakeService) GetStockTakeByID(ctx context.Context, id string) (*models.StockTake, error) {
+	st, err := s.repo.StockTakeRepo.GetStockTakeByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrNotFound, errors.New("stock take not found"))
+		}
+		if errors.As(err, new(*strconv.NumError)) {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("invalid stock take id"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return st, nil
+}
+
+func (s *stockTakeService) SubmitCounts(ctx context.Context, id string, submission *models.CountSubmission) error {
+	if err := submission.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+
+	st, err := s.GetStockTakeByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if st.Status != "open" {
+		return models.NewError(models.ErrInvalidInput, errors.New("stock take is not open"))
+	}
+
+	for _, count := range submission.Counts {
+		if _, err := s.repo.InventoryRepo.GetInventoryByID(ctx, count.ItemRef); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.NewError(models.ErrNotFound, errors.New("inventory item not found"))
+			}
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+				return models.NewError(models.ErrInvalidInput, errors.New("invalid inventory item id"))
+			}
+			return models.NewError(models.ErrInternal, err)
+		}
+	}
+
+	if err := s.repo.StockTakeRepo.SaveCounts(ctx, id, submission); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *stockTakeService) CommitStockTake(ctx context.Context, id string) (*models.VarianceReport, error) {
+	st, err := s.GetStockTakeByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Status != "open" {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("stock take is not open"))
+	}
+
+	if len(st.Counts) == 0 {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("no counts were submitted"))
+	}
+
+	report, err := s.repo.StockTakeRepo.CommitStockTake(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("stock take is not open"))
+		}
+		if errors.Is(err, models.ErrCountsSpanMovement) {
+			return nil, models.NewError(models.ErrInvalidInput, err)
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	report.TotalValue = roundFloat(report.TotalValue, 2)
+	return report, nil
+}
+
+func (s *stockTakeService) CancelStockTake(ctx context.Context, id string) error {
+	if _, err := s.GetStockTakeByID(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.StockTakeRepo.CancelStockTake(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.NewError(models.ErrInvalidInput, errors.New("stock take is not open"))
+		}
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *stockTakeService) GetVarianceReport(ctx context.Context, id string) (*models.VarianceReport, error) {
+	report, err := s.repo.StockTakeRepo.GetVarianceReport(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.NewError(models.ErrNotFound, errors.New("no committed stock take with this id"))
+		}
+		if errors.As(err, new(*strconv.NumError)) {
+			return nil, models.NewError(models.ErrInvalidInput, errors.New("invalid stock take id"))
+		}
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	report.TotalValue = roundFloat(report.TotalValue, 2)
+	return report, nil
+}
@@ -0,0 +1,564 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+)
+
+// TransferService imports and exports inventory and menu items as CSV or
+// JSON. Imports match existing items by name, ignoring case, and update them;
+// other rows create new items. Every row is checked before anything is
+// written, and a file with any invalid row is not written at all. The rows
+// are then written in one transaction, so a row the database refuses leaves
+// nothing written either. A dry run writes the rows the same way and rolls
+// them back.
+type TransferService interface {
+	ImportInventory(ctx context.Context, format string, data []byte, dryRun bool) (*models.ImportReport, error)
+	ExportInventory(ctx context.Context, format string) ([]byte, error)
+	ImportMenu(ctx context.Context, format string, data []byte, dryRun bool) (*models.ImportReport, error)
+	ExportMenu(ctx context.Context, format string) ([]byte, error)
+}
+
+type transferService struct {
+	Repo *repo.Container
+}
+
+func NewTransferService(r *repo.Container) TransferService {
+	return &transferService{
+		Repo: r,
+	}
+}
+
+var (
+	// errRowFailed rolls an import back when one of its rows cannot be written.
+	errRowFailed = errors.New("import row failed")
+	// errDryRun rolls a dry run back once every row is written.
+	errDryRun = errors.New("dry run")
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+func (s *transferService) ImportInventory(ctx context.Context, format string, data []byte, dryRun bool) (*models.ImportReport, error) {
+	records, rowErrs, err := decodeRecords(format, data, models.InventoryCSVHeader, models.ParseInventoryCSV)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	existing, err := s.Repo.InventoryRepo.GetAllInventory(ctx, false)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	names := newNameIndex(existing, func(item *models.InventoryItem) string { return item.Title })
+
+	report := &models.ImportReport{DryRun: dryRun, Total: len(records)}
+	items := make([]*models.InventoryItem, len(records))
+	inFile := make(map[string]int)
+
+	for i, rec := range records {
+		row := &models.ImportRow{Row: rowNumber(format, i)}
+		report.Rows = append(report.Rows, row)
+		if rowErrs[i] != nil {
+			row.Error = rowErrs[i].Error()
+			continue
+		}
+		row.Title = rec.Title
+
+		item, err := inventoryFromRecord(rec, names, inFile)
+		if err == nil {
+			err = item.Validate()
+		}
+		if err != nil {
+			row.Error = err.Error()
+			continue
+		}
+
+		row.Action = "create"
+		if current := names.get(rec.Title); current != nil {
+			row.Action = "update"
+			item.IngredientID = current.IngredientID
+			// files carry no reorder level; keep the one set on the item
+			item.ReorderLevel = current.ReorderLevel
+		}
+		inFile[nameKey(rec.Title)] = row.Row
+		items[i] = item
+	}
+
+	if tally(report); report.Failed > 0 {
+		return report, nil
+	}
+
+	err = s.write(ctx, report, dryRun, func(tx *repo.Container) error {
+		svc := &inventoryService{repo: tx}
+		for i, item := range items {
+			row := report.Rows[i]
+			// recipes may use items created by earlier rows
+			var err error
+			for _, line := range item.Recipe {
+				ref := names.get(line.ComponentName)
+				if ref == nil {
+					err = fmt.Errorf("inventory item %s was not imported", line.ComponentName)
+					break
+				}
+				line.ComponentID = ref.IngredientID
+			}
+
+			switch {
+			case err != nil:
+			case row.Action == "update":
+				err = svc.UpdateInventoryByID(ctx, item.IngredientID, item)
+			default:
+				err = svc.CreateInventory(ctx, item)
+			}
+			if err != nil {
+				row.Error = importError(err)
+				return errRowFailed
+			}
+			names.set(item.Title, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *transferService) ExportInventory(ctx context.Context, format string) ([]byte, error) {
+	items, err := s.Repo.InventoryRepo.GetAllInventory(ctx, false)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	records := make([]*models.InventoryRecord, 0, len(items))
+	for _, item := range items {
+		rec := &models.InventoryRecord{
+			Title:          item.Title,
+			Stock:          item.Stock,
+			Measure:        item.Measure,
+			UnitCost:       item.UnitCost,
+			Allergens:      item.Allergens,
+			Nutrition:      item.Nutrition,
+			Prepared:       item.Prepared,
+			Yield:          item.Yield,
+			ShelfLifeHours: item.ShelfLifeHours,
+		}
+		for _, line := range item.Recipe {
+			rec.Recipe = append(rec.Recipe, &models.ComponentRecord{Name: line.ComponentName, Quantity: line.RequiredQty})
+		}
+		records = append(records, rec)
+	}
+
+	return encodeRecords(format, records, models.InventoryCSVHeader)
+}
+
+func (s *transferService) ImportMenu(ctx context.Context, format string, data []byte, dryRun bool) (*models.ImportReport, error) {
+	records, rowErrs, err := decodeRecords(format, data, models.MenuCSVHeader, models.ParseMenuCSV)
+	if err != nil {
+		return nil, models.NewError(models.ErrInvalidInput, err)
+	}
+
+	products, err := s.Repo.MenuRepo.GetAllProducts(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	inventory, err := s.Repo.InventoryRepo.GetAllInventory(ctx, false)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	categories, err := s.Repo.CategoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	menu := newNameIndex(products, func(p *models.Product) string { return p.Title })
+	ingredients := newNameIndex(inventory, func(item *models.InventoryItem) string { return item.Title })
+	groups := newNameIndex(categories, func(c *models.Category) string { return c.Name })
+
+	report := &models.ImportReport{DryRun: dryRun, Total: len(records)}
+	items := make([]*models.Product, len(records))
+	inFile := make(map[string]int)
+
+	for i, rec := range records {
+		row := &models.ImportRow{Row: rowNumber(format, i)}
+		report.Rows = append(report.Rows, row)
+		if rowErrs[i] != nil {
+			row.Error = rowErrs[i].Error()
+			continue
+		}
+		row.Title = rec.Title
+
+		item, err := productFromRecord(rec, ingredients, groups)
+		current := menu.get(rec.Title)
+		if err == nil && current != nil {
+			// files carry no modifiers; keep the ones set on the item
+			item.Modifiers = current.Modifiers
+		}
+		if err == nil {
+			err = item.CheckRequiredFields()
+		}
+		if err == nil {
+			if first, dup := inFile[nameKey(rec.Title)]; dup && nameKey(rec.Title) != "" {
+				err = fmt.Errorf("%s already appears in row %d", rec.Title, first)
+			}
+		}
+		if err == nil && current != nil && current.Bundle {
+			err = fmt.Errorf("%s is a bundle and cannot be replaced by an import", current.Title)
+		}
+		if err != nil {
+			row.Error = err.Error()
+			continue
+		}
+
+		row.Action = "create"
+		if current != nil {
+			row.Action = "update"
+			item.ProductID = current.ProductID
+		}
+		inFile[nameKey(rec.Title)] = row.Row
+		items[i] = item
+	}
+
+	if tally(report); report.Failed > 0 {
+		return report, nil
+	}
+
+	err = s.write(ctx, report, dryRun, func(tx *repo.Container) error {
+		svc := &menuService{repo: tx}
+		for i, item := range items {
+			row := report.Rows[i]
+			var err error
+			if row.Action == "update" {
+				err = svc.UpdateMenu(ctx, item.ProductID, item)
+			} else {
+				err = svc.CreateMenu(ctx, item)
+			}
+			if err != nil {
+				row.Error = importError(err)
+				return errRowFailed
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// ExportMenu writes every menu item except bundles, whose slots refer to
+// other items and are set up by hand.
+func (s *transferService) ExportMenu(ctx context.Context, format string) ([]byte, error) {
+	products, err := s.Repo.MenuRepo.GetAllProducts(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+
+	records := make([]*models.MenuRecord, 0, len(products))
+	for _, p := range products {
+		if p.Bundle {
+			continue
+		}
+		rec := &models.MenuRecord{
+			Title:      p.Title,
+			Details:    p.Details,
+			UnitPrice:  p.UnitPrice,
+			SizeLabel:  p.SizeLabel,
+			Category:   p.Group,
+			Labels:     p.Labels,
+			Extras:     p.Extras,
+			Components: []*models.ComponentRecord{},
+		}
+		for _, comp := range p.Components {
+			rec.Components = append(rec.Components, &models.ComponentRecord{Name: comp.ComponentName, Quantity: comp.RequiredQty})
+		}
+		records = append(records, rec)
+	}
+
+	return encodeRecords(format, records, models.MenuCSVHeader)
+}
+
+// inventoryFromRecord turns a record into an inventory item. Recipe lines
+// carry the ingredient name until the item is written, since they may name
+// items created by earlier rows of the same file.
+func inventoryFromRecord(rec *models.InventoryRecord, names *nameIndex[*models.InventoryItem], inFile map[string]int) (*models.InventoryItem, error) {
+	if first, dup := inFile[nameKey(rec.Title)]; dup && nameKey(rec.Title) != "" {
+		return nil, fmt.Errorf("%s already appears in row %d", rec.Title, first)
+	}
+	if names.ambiguous(rec.Title) {
+		return nil, fmt.Errorf("several inventory items are named %s", rec.Title)
+	}
+
+	item := &models.InventoryItem{
+		Title:          strings.TrimSpace(rec.Title),
+		Stock:          rec.Stock,
+		Measure:        rec.Measure,
+		UnitCost:       rec.UnitCost,
+		Allergens:      rec.Allergens,
+		Nutrition:      rec.Nutrition,
+		Prepared:       rec.Prepared,
+		Yield:          rec.Yield,
+		ShelfLifeHours: rec.ShelfLifeHours,
+	}
+	for _, line := range rec.Recipe {
+		_, earlier := inFile[nameKey(line.Name)]
+		if !earlier {
+			if _, err := names.lookup("inventory item", line.Name); err != nil {
+				return nil, err
+			}
+		}
+		item.Recipe = append(item.Recipe, &models.ProductComponent{
+			ComponentID:   line.Name,
+			ComponentName: line.Name,
+			RequiredQty:   line.Quantity,
+		})
+	}
+	return item, nil
+}
+
+func productFromRecord(rec *models.MenuRecord, ingredients *nameIndex[*models.InventoryItem], groups *nameIndex[*models.Category]) (*models.Product, error) {
+	item := &models.Product{
+		Title:      strings.TrimSpace(rec.Title),
+		Details:    rec.Details,
+		UnitPrice:  rec.UnitPrice,
+		SizeLabel:  rec.SizeLabel,
+		Labels:     rec.Labels,
+		Extras:     rec.Extras,
+		Components: []*models.ProductComponent{},
+	}
+	if item.Extras == nil {
+		item.Extras = models.ExtrasMap{}
+	}
+
+	if strings.TrimSpace(rec.Category) != "" {
+		category, err := groups.lookup("category", rec.Category)
+		if err != nil {
+			return nil, err
+		}
+		item.CategoryID, item.Group = category.CategoryID, category.Name
+	}
+
+	for _, comp := range rec.Components {
+		inv, err := ingredients.lookup("inventory item", comp.Name)
+		if err != nil {
+			return nil, err
+		}
+		item.Components = append(item.Components, &models.ProductComponent{
+			ComponentID:   inv.IngredientID,
+			ComponentName: inv.Title,
+			RequiredQty:   comp.Quantity,
+		})
+	}
+	return item, nil
+}
+
+// decodeRecords reads the rows of a CSV or JSON file. A CSV row that cannot
+// be parsed comes back as nil along with its error, so the remaining rows can
+// still be checked.
+func decodeRecords[T any](format string, data []byte, header []string, parse func(map[string]string) (*T, error)) ([]*T, []error, error) {
+	switch format {
+	case FormatJSON:
+		var records []*T
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if slices.Contains(records, nil) {
+			return nil, nil, errors.New("invalid JSON: null row")
+		}
+		return records, make([]error, len(records)), nil
+	case FormatCSV:
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q (expected csv or json)", format)
+	}
+
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	columns, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("read CSV header: %w", err)
+	}
+	for i, col := range columns {
+		columns[i] = strings.ToLower(strings.TrimSpace(col))
+		if !slices.Contains(header, columns[i]) {
+			return nil, nil, fmt.Errorf("unknown column %q (expected %s)", col, strings.Join(header, ", "))
+		}
+	}
+	if !slices.Contains(columns, "title") {
+		return nil, nil, errors.New("missing column title")
+	}
+
+	var (
+		records []*T
+		errs    []error
+	)
+	for {
+		fields, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("read CSV: %w", err)
+		}
+		if len(fields) != len(columns) {
+			records = append(records, nil)
+			errs = append(errs, fmt.Errorf("expected %d fields, got %d", len(columns), len(fields)))
+			continue
+		}
+
+		row := make(map[string]string, len(columns))
+		for i, col := range columns {
+			row[col] = strings.TrimSpace(fields[i])
+		}
+		rec, err := parse(row)
+		records = append(records, rec)
+		errs = append(errs, err)
+	}
+	return records, errs, nil
+}
+
+// encodeRecords writes records in the format ImportInventory and ImportMenu
+// read back.
+func encodeRecords[T interface{ CSV() []string }](format string, records []T, header []string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(records); err != nil {
+			return nil, models.NewError(models.ErrInternal, err)
+		}
+	case FormatCSV:
+		w := csv.NewWriter(&buf)
+		w.Write(header)
+		for _, rec := range records {
+			w.Write(rec.CSV())
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, models.NewError(models.ErrInternal, err)
+		}
+	default:
+		return nil, models.NewError(models.ErrInvalidInput, fmt.Errorf("unknown format %q (expected csv or json)", format))
+	}
+	return buf.Bytes(), nil
+}
+
+// write runs fn, which writes the checked rows of report, in one
+// transaction. When a row fails the transaction is rolled back and the report
+// shows the failed row and nothing written; a dry run is rolled back too.
+func (s *transferService) write(ctx context.Context, report *models.ImportReport, dryRun bool, fn func(tx *repo.Container) error) error {
+	err := s.Repo.InTx(ctx, func(tx *repo.Container) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	switch {
+	case err == nil, errors.Is(err, errDryRun):
+	case errors.Is(err, errRowFailed):
+		for _, row := range report.Rows {
+			if row.Error == "" {
+				row.Action = ""
+			}
+		}
+	default:
+		return models.NewError(models.ErrInternal, err)
+	}
+	tally(report)
+	return nil
+}
+
+// rowNumber numbers rows the way an editor shows them: a CSV file has its
+// header on the first line.
+func rowNumber(format string, i int) int {
+	if format == FormatCSV {
+		return i + 2
+	}
+	return i + 1
+}
+
+func tally(report *models.ImportReport) {
+	report.Created, report.Updated, report.Failed = 0, 0, 0
+	for _, row := range report.Rows {
+		switch {
+		case row.Error != "":
+			report.Failed++
+		case row.Action == "create":
+			report.Created++
+		case row.Action == "update":
+			report.Updated++
+		}
+	}
+}
+
+func importError(err error) string {
+	var svcErr models.Error
+	if errors.As(err, &svcErr) && svcErr.AppError() != nil {
+		return svcErr.AppError().Error()
+	}
+	return err.Error()
+}
+
+func nameKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// nameIndex finds items by name, ignoring case and surrounding spaces, and
+// remembers names shared by several items.
+type nameIndex[T any] struct {
+	items  map[string]T
+	shared map[string]bool
+}
+
+func newNameIndex[T any](items []T, name func(T) string) *nameIndex[T] {
+	idx := &nameIndex[T]{items: make(map[string]T), shared: make(map[string]bool)}
+	for _, item := range items {
+		key := nameKey(name(item))
+		if _, ok := idx.items[key]; ok {
+			idx.shared[key] = true
+		}
+		idx.items[key] = item
+	}
+	return idx
+}
+
+func (idx *nameIndex[T]) get(name string) T {
+	return idx.items[nameKey(name)]
+}
+
+func (idx *nameIndex[T]) set(name string, item T) {
+	idx.items[nameKey(name)] = item
+	delete(idx.shared, nameKey(name))
+}
+
+func (idx *nameIndex[T]) ambiguous(name string) bool {
+	return idx.shared[nameKey(name)]
+}
+
+func (idx *nameIndex[T]) lookup(kind, name string) (T, error) {
+	item, ok := idx.items[nameKey(name)]
+	if !ok {
+		return item, fmt.Errorf("%s %s not found", kind, name)
+	}
+	if idx.shared[nameKey(name)] {
+		return item, fmt.Errorf("several %ss are named %s", kind, name)
+	}
+	return item, nil
+}
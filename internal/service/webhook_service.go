@@ -0,0 +1,279 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"frappuccino/internal/models"
+	repo "frappuccino/internal/repo"
+	"frappuccino/internal/slog"
+
+	"github.com/lib/pq"
+)
+
+// Dispatcher tuning: events handed to webhooks per statement, deliveries
+// sent per batch, how long a claimed delivery is left to its sender and how
+// long a receiver has to answer.
+const (
+	webhookOutboxBatch   = 100
+	webhookDeliveryBatch = 20
+	webhookLease         = 2 * time.Minute
+	webhookTimeout       = 10 * time.Second
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetAllWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	GetWebhookByID(ctx context.Context, id string) (*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id string, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, id string) error
+	GetDeliveries(ctx context.Context, webhookID, status string, limit int) ([]*models.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, webhookID, deliveryID string) error
+	RunDispatcher(ctx context.Context, interval time.Duration)
+}
+
+type webhookService struct {
+	Repo   *repo.Container
+	client *http.Client
+	// maxAttempts is how many times a delivery is tried before it is
+	// dead-lettered.
+	maxAttempts int
+}
+
+func NewWebhookService(r *repo.Container, maxAttempts int) WebhookService {
+	return &webhookService{
+		Repo:        r,
+		client:      &http.Client{Timeout: webhookTimeout},
+		maxAttempts: maxAttempts,
+	}
+}
+
+// CreateWebhook registers a webhook, active unless told otherwise. Without a
+// secret one is generated; either way it is returned this once.
+func (s *webhookService) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	if webhook.Active == nil {
+		active := true
+		webhook.Active = &active
+	}
+	if webhook.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return models.NewError(models.ErrInternal, err)
+		}
+		webhook.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := s.Repo.WebhookRepo.Create(ctx, webhook); err != nil {
+		return models.NewError(models.ErrInternal, err)
+	}
+	return nil
+}
+
+func (s *webhookService) GetAllWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	webhooks, err := s.Repo.WebhookRepo.GetAll(ctx)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) GetWebhookByID(ctx context.Context, id string) (*models.Webhook, error) {
+	webhook, err := s.Repo.WebhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, webhookError(err, "webhook not found")
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook replaces a webhook. The secret is kept unless a new one is
+// given, and so is the active flag.
+func (s *webhookService) UpdateWebhook(ctx context.Context, id string, webhook *models.Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return models.NewError(models.ErrInvalidInput, err)
+	}
+	current, err := s.GetWebhookByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	if webhook.Active == nil {
+		webhook.Active = current.Active
+	}
+
+	if err := s.Repo.WebhookRepo.Update(ctx, id, webhook); err != nil {
+		return webhookError(err, "webhook not found")
+	}
+	webhook.WebhookID = id
+	webhook.Secret = ""
+	webhook.CreatedAt = current.CreatedAt
+	return nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, id string) error {
+	if err := s.Repo.WebhookRepo.Delete(ctx, id); err != nil {
+		return webhookError(err, "webhook not found")
+	}
+	return nil
+}
+
+// GetDeliveries is the delivery log of a webhook, newest first.
+func (s *webhookService) GetDeliveries(ctx context.Context, webhookID, status string, limit int) ([]*models.WebhookDelivery, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryDead:
+	default:
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("status must be pending, delivered or dead"))
+	}
+	if limit <= 0 || limit > 500 {
+		return nil, models.NewError(models.ErrInvalidInput, errors.New("limit must be between 1 and 500"))
+	}
+	if _, err := s.GetWebhookByID(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.Repo.WebhookRepo.GetDeliveries(ctx, webhookID, status, limit)
+	if err != nil {
+		return nil, models.NewError(models.ErrInternal, err)
+	}
+	return deliveries, nil
+}
+
+// RetryDelivery gives a dead-lettered delivery another round of attempts.
+func (s *webhookService) RetryDelivery(ctx context.Context, webhookID, deliveryID string) error {
+	if err := s.Repo.WebhookRepo.Retry(ctx, webhookID, deliveryID); err != nil {
+		return webhookError(err, "no dead delivery with this id")
+	}
+	return nil
+}
+
+func webhookError(err error, notFound string) error {
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || errors.As(err, &pqErr) && pqErr.Code == "22P02" {
+		return models.NewError(models.ErrNotFound, errors.New(notFound))
+	}
+	return models.NewError(models.ErrInternal, err)
+}
+
+// RunDispatcher hands outbox events to the webhooks subscribed to them and
+// sends due deliveries every interval until ctx is done. Events written while
+// the server was down are sent on start.
+func (s *webhookService) RunDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.dispatchOutbox(ctx); err != nil {
+			slog.Error("Failed to dispatch outbox events: %v", err)
+		}
+		if err := s.sendDue(ctx); err != nil {
+			slog.Error("Failed to send webhook deliveries: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *webhookService) dispatchOutbox(ctx context.Context) error {
+	for {
+		n, err := s.Repo.WebhookRepo.DispatchOutbox(ctx, webhookOutboxBatch)
+		if err != nil || n < webhookOutboxBatch {
+			return err
+		}
+	}
+}
+
+func (s *webhookService) sendDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		deliveries, err := s.Repo.WebhookRepo.ClaimDue(ctx, webhookDeliveryBatch, webhookLease)
+		if err != nil {
+			return err
+		}
+		for _, d := range deliveries {
+			if err := s.send(ctx, d); err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < webhookDeliveryBatch {
+			return nil
+		}
+	}
+	return nil
+}
+
+// send makes one attempt at a delivery and records it. A failed delivery is
+// retried with exponential backoff until it runs out of attempts.
+func (s *webhookService) send(ctx context.Context, d *models.WebhookDelivery) error {
+	attempt := s.post(ctx, d)
+	attempts := d.Attempts + 1
+
+	status, retryIn := models.DeliveryPending, models.WebhookBackoff(attempts)
+	switch {
+	case attempt.Succeeded():
+		status, retryIn = models.DeliveryDelivered, 0
+	case attempts >= s.maxAttempts:
+		status, retryIn = models.DeliveryDead, 0
+		slog.Error("Webhook delivery dead-lettered: webhook=%s, delivery=%s, event=%s after %d attempts: %s",
+			d.WebhookID, d.DeliveryID, d.Event.Type, attempts, attempt.Error)
+	}
+
+	if err := s.Repo.WebhookRepo.RecordAttempt(ctx, d.DeliveryID, attempt, status, retryIn); err != nil {
+		return fmt.Errorf("record attempt of delivery %s: %w", d.DeliveryID, err)
+	}
+	return nil
+}
+
+// post sends the event, signed with the webhook secret.
+func (s *webhookService) post(ctx context.Context, d *models.WebhookDelivery) *models.WebhookAttempt {
+	attempt := &models.WebhookAttempt{AttemptedAt: time.Now()}
+
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := strconv.FormatInt(attempt.AttemptedAt.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", d.Event.Type)
+	req.Header.Set("X-Webhook-Delivery", d.DeliveryID)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+models.SignWebhook(d.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.Error = "receiver answered " + resp.Status
+	}
+	return attempt
+}